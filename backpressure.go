@@ -0,0 +1,120 @@
+package boomer
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// Queues between tasks, the stats goroutine, the runner and the transport are bounded.
+// When one of them is full, instead of blocking forever:
+//   - requests and custom metrics recorded by tasks follow --request-overflow
+//   - stats keep accumulating and are coalesced into the next report
+//   - messages to the master are dropped after --master-send-timeout
+// What's dropped, coalesced or delayed is counted and reported as custom metrics.
+
+const (
+	overflowBlock   = "block"
+	overflowDrop    = "drop"
+	overflowTimeout = "timeout"
+)
+
+type overflowPolicy string
+
+func (p *overflowPolicy) String() string {
+	return string(*p)
+}
+
+func (p *overflowPolicy) Set(value string) error {
+	switch value {
+	case overflowBlock, overflowDrop, overflowTimeout:
+		*p = overflowPolicy(value)
+		return nil
+	}
+	return fmt.Errorf("unknown overflow policy %q, should be block, drop or timeout", value)
+}
+
+var requestOverflow = overflowPolicy(overflowBlock)
+var requestOverflowTimeout *time.Duration
+var masterSendTimeout *time.Duration
+
+// Counters since the last report, they are updated atomically.
+var (
+	droppedRequests  int64
+	droppedMessages  int64
+	delayedReports   int64
+	coalescedReports int64
+)
+
+// requestOverflowTimer returns a channel that fires when a task should stop waiting for
+// the full request queue, nil means waiting forever. stop must be called to release the timer.
+func requestOverflowTimer() (timeout <-chan time.Time, stop func()) {
+	switch requestOverflow {
+	case overflowDrop:
+		expired := make(chan time.Time)
+		close(expired)
+		return expired, func() {}
+	case overflowTimeout:
+		timer := time.NewTimer(*requestOverflowTimeout)
+		return timer.C, func() { timer.Stop() }
+	}
+	return nil, func() {}
+}
+
+// sendToMaster gives up after --master-send-timeout if the transport stalls, the message is dropped.
+func sendToMaster(msg *message) bool {
+	select {
+	case toMaster <- msg:
+		return true
+	default:
+	}
+
+	timer := time.NewTimer(*masterSendTimeout)
+	defer timer.Stop()
+	select {
+	case toMaster <- msg:
+		return true
+	case <-timer.C:
+		atomic.AddInt64(&droppedMessages, 1)
+		log.Printf("Transport to master is stalled, %s message is dropped\n", msg.Type)
+		return false
+	}
+}
+
+// reportStats sends a report to the runner, it must be called in the stats goroutine.
+// If the runner is still busy with previous reports, stats keep accumulating until the next one.
+func reportStats() {
+	if len(messageToRunner) == cap(messageToRunner) {
+		atomic.AddInt64(&coalescedReports, 1)
+		return
+	}
+	messageToRunner <- collectReportData()
+}
+
+// logBackpressure adds the counters to custom metrics and resets them, it must be called in the stats goroutine.
+func logBackpressure() {
+	for _, counter := range []struct {
+		name  string
+		value *int64
+	}{
+		{"boomer_dropped_requests", &droppedRequests},
+		{"boomer_dropped_messages", &droppedMessages},
+		{"boomer_delayed_reports", &delayedReports},
+		{"boomer_coalesced_reports", &coalescedReports},
+	} {
+		n := atomic.SwapInt64(counter.value, 0)
+		if n == 0 {
+			continue
+		}
+		logMetric(&metricSample{metricCounter, counter.name, float64(n)})
+		log.Printf("%s: %d since the last report\n", counter.name, n)
+	}
+}
+
+func init() {
+	flag.Var(&requestOverflow, "request-overflow", "What tasks do when the queue of requests is full, block, drop or timeout. Dropped requests are counted as boomer_dropped_requests.")
+	requestOverflowTimeout = flag.Duration("request-overflow-timeout", 10*time.Millisecond, "How long tasks wait for the full queue of requests before dropping, with --request-overflow=timeout.")
+	masterSendTimeout = flag.Duration("master-send-timeout", 10*time.Second, "How long to wait for a stalled transport before dropping messages to the master, like hatch_complete.")
+}
@@ -0,0 +1,167 @@
+package boomer
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stallStats blocks the stats goroutine until the returned function is called.
+func stallStats() (resume func()) {
+	c := make(chan *testSummary)
+	summaryRequestChannel <- c
+	// let it drain the queues before it blocks
+	time.Sleep(10 * time.Millisecond)
+	return func() {
+		<-c
+	}
+}
+
+func fillRequestQueue() {
+	for len(requestSuccessChannel) < cap(requestSuccessChannel) {
+		RecordSuccess("backpressure", "fill", 1, 1, nil)
+	}
+}
+
+func TestRequestOverflowDrop(t *testing.T) {
+	defer func() {
+		requestOverflow = overflowBlock
+		atomic.StoreInt64(&droppedRequests, 0)
+		clearStatsChannel <- true
+	}()
+
+	requestOverflow = overflowDrop
+	resume := stallStats()
+	fillRequestQueue()
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		RecordSuccess("backpressure", "drop", 1, 1, nil)
+		RecordFailure("backpressure", "drop", 1, "error", nil)
+	}
+	NewCounter("backpressure").Inc()
+	resume()
+
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Error("tasks should not be blocked by the full queue, blocked for", elapsed)
+	}
+	// the failure queue is not full
+	if n := atomic.LoadInt64(&droppedRequests); n != 10 {
+		t.Error("dropped requests should be counted, got", n)
+	}
+}
+
+func TestRequestOverflowTimeout(t *testing.T) {
+	defer func() {
+		requestOverflow = overflowBlock
+		atomic.StoreInt64(&droppedRequests, 0)
+		clearStatsChannel <- true
+	}()
+
+	requestOverflow = overflowTimeout
+	*requestOverflowTimeout = 50 * time.Millisecond
+	resume := stallStats()
+	fillRequestQueue()
+
+	start := time.Now()
+	RecordSuccess("backpressure", "timeout", 1, 1, nil)
+	elapsed := time.Since(start)
+	resume()
+
+	if elapsed < 50*time.Millisecond || elapsed > time.Second {
+		t.Error("tasks should wait for the full queue until timeout, waited for", elapsed)
+	}
+	if n := atomic.LoadInt64(&droppedRequests); n != 1 {
+		t.Error("dropped requests should be counted, got", n)
+	}
+
+	// it's not dropped once the queue is consumed
+	RecordSuccess("backpressure", "timeout", 1, 1, nil)
+	if n := atomic.LoadInt64(&droppedRequests); n != 1 {
+		t.Error("requests should not be dropped if the queue is not full, got", n)
+	}
+}
+
+func TestOverflowPolicyFlag(t *testing.T) {
+	var p overflowPolicy
+	if err := p.Set("coalesce"); err == nil {
+		t.Error("unknown policy should be an error")
+	}
+	if err := p.Set(overflowDrop); err != nil || p.String() != overflowDrop {
+		t.Error("policy should be set, got", p, err)
+	}
+}
+
+func TestSendToMasterTimeout(t *testing.T) {
+	defer func(timeout time.Duration) {
+		*masterSendTimeout = timeout
+		atomic.StoreInt64(&droppedMessages, 0)
+		for len(toMaster) > 0 {
+			<-toMaster
+		}
+	}(*masterSendTimeout)
+
+	*masterSendTimeout = 10 * time.Millisecond
+	for len(toMaster) < cap(toMaster) {
+		toMaster <- newMessage("stats", nil, "")
+	}
+
+	if sendToMaster(newMessage("hatch_complete", nil, "")) {
+		t.Error("message should be dropped if the transport is stalled")
+	}
+	if n := atomic.LoadInt64(&droppedMessages); n != 1 {
+		t.Error("dropped messages should be counted, got", n)
+	}
+
+	<-toMaster
+	if !sendToMaster(newMessage("hatch_complete", nil, "")) {
+		t.Error("message should be sent once the transport catches up")
+	}
+}
+
+func TestCoalesceReports(t *testing.T) {
+	defer func() {
+		for len(messageToRunner) > 0 {
+			<-messageToRunner
+		}
+		clearStatsChannel <- true
+	}()
+
+	for len(messageToRunner) < cap(messageToRunner) {
+		messageToRunner <- map[string]interface{}{}
+	}
+
+	RecordSuccess("backpressure", "coalesce", 1, 1, nil)
+	reportStatsChannel <- true
+	RecordSuccess("backpressure", "coalesce", 1, 1, nil)
+	// the stats goroutine is not blocked by the runner
+	getSummary()
+
+	for len(messageToRunner) > 0 {
+		<-messageToRunner
+	}
+	reportStatsChannel <- true
+
+	select {
+	case data := <-messageToRunner:
+		s, err := decodeStats(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var entry *statsEntry
+		for _, e := range s.entries {
+			if e.name == "coalesce" {
+				entry = e
+			}
+		}
+		if entry == nil || entry.numRequests != 2 {
+			t.Error("requests of the coalesced report should be in the next one, got", entry)
+		}
+		counters, _ := toMap(s.customMetrics["counters"])
+		if n, _ := toInt64(counters["boomer_coalesced_reports"]); n < 1 {
+			t.Error("coalesced reports should be reported as custom metrics, got", s.customMetrics)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for the report")
+	}
+}
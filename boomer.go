@@ -2,17 +2,25 @@ package boomer
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"runtime"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
 )
 
-// Run accepts a slice of Task and connects
-// to a locust master.
-func Run(tasks ...*Task) {
+// Run accepts a slice of Task and connects to a locust master, or runs them without a master with --standalone.
+// It returns when the master asks the worker to quit, Stop is called, --run-time is up, or SIGINT/SIGTERM is received,
+// after the users are stopped, the final stats are sent and the connection is closed.
+// If any threshold fails, ErrThresholdsFailed is returned, exit with a non-zero code for CI.
+// If the connection to the master is lost, the users are stopped too, and an error wrapping ErrMasterLost is returned.
+func Run(tasks ...*Task) error {
+	setRunning(true)
+	defer setRunning(false)
 
 	// support go version below 1.5
 	runtime.GOMAXPROCS(runtime.NumCPU())
@@ -21,6 +29,10 @@ func Run(tasks ...*Task) {
 		flag.Parse()
 	}
 
+	// the time series file is closed after the final report
+	startTimeline(newTimelineFromFlags())
+	defer closeTimeline()
+
 	if *runTasks != "" {
 		// Run tasks without connecting to the master.
 		taskNames := strings.Split(*runTasks, ",")
@@ -31,50 +43,117 @@ func Run(tasks ...*Task) {
 				for _, name := range taskNames {
 					if name == task.Name {
 						log.Println("Running " + task.Name)
-						task.Fn()
+						task.runOnce()
 					}
 				}
 			}
 		}
-		return
+		return finishReport()
 	}
 
 	if maxRPS > 0 {
 		log.Println("Max RPS that boomer may generate is limited to", maxRPS)
-		maxRPSEnabled = true
 	}
 
-	var r *runner
-	client := newClient()
-	r = &runner{
-		tasks:  tasks,
-		client: client,
-		nodeID: getNodeID(),
+	var c client
+	if *standalone {
+		if *standaloneClients <= 0 || *standaloneHatchRate <= 0 {
+			return fmt.Errorf("--clients and --hatch-rate should be positive, not %d and %d", *standaloneClients, *standaloneHatchRate)
+		}
+		c = newStandaloneClient()
+	} else {
+		c = newClient()
 	}
 
-	Events.Subscribe("boomer:quit", r.onQuiting)
+	r := newRunner(tasks, c)
+	quitByMaster := make(chan bool, 1)
+	r.onQuit = func() {
+		select {
+		case quitByMaster <- true:
+		default:
+		}
+	}
 
+	// drop the notification left by a previous run
+	select {
+	case <-masterLost:
+	default:
+	}
 	r.getReady()
 
-	c := make(chan os.Signal)
-	signal.Notify(c, syscall.SIGINT)
+	var runTimer <-chan time.Time
+	if *standalone {
+		r.startHatching(*standaloneClients, *standaloneHatchRate)
+		if *runTime > 0 {
+			timer := time.NewTimer(*runTime)
+			defer timer.Stop()
+			runTimer = timer.C
+		}
+	}
 
-	<-c
-	Events.Publish("boomer:quit")
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
 
-	// wait for quit message is sent to master
-	<-disconnectedFromMaster
+	select {
+	case sig := <-signals:
+		log.Printf("Got %v, shutting down...\n", sig)
+		Events.Publish("boomer:quit")
+		r.shutdown(true)
+	case <-stopRequestChannel:
+		Events.Publish("boomer:quit")
+		r.shutdown(true)
+	case <-runTimer:
+		log.Println("Run time is up, shutting down...")
+		Events.Publish("boomer:quit")
+		r.shutdown(true)
+	case <-quitByMaster:
+		r.shutdown(false)
+	case err := <-masterLost:
+		r.shutdown(false)
+		log.Println("shut down")
+		if reportErr := finishReport(); reportErr != nil {
+			log.Println(reportErr)
+		}
+		return fmt.Errorf("%w, %v", ErrMasterLost, err)
+	}
 	log.Println("shut down")
+	return finishReport()
+}
+
+// Stop asks Run to shut down, it doesn't wait for Run to return. It's ignored if Run isn't running.
+func Stop() {
+	runningLock.Lock()
+	defer runningLock.Unlock()
+	if !running {
+		return
+	}
+	select {
+	case stopRequestChannel <- true:
+	default:
+	}
+}
 
+// setRunning drops the stop request left by Stop called more than once, when Run returns.
+func setRunning(value bool) {
+	runningLock.Lock()
+	defer runningLock.Unlock()
+	running = value
+	select {
+	case <-stopRequestChannel:
+	default:
+	}
 }
 
 var runTasks *string
 var maxRPS int64
-var maxRPSThreshold int64
-var maxRPSEnabled = false
-var maxRPSControlChannel = make(chan bool)
+var shutdownTimeout *time.Duration
+var stopRequestChannel = make(chan bool, 1)
+var runningLock sync.Mutex
+var running bool
 
 func init() {
 	runTasks = flag.String("run-tasks", "", "Run tasks without connecting to the master, multiply tasks is separated by comma. Usually, it's for debug purpose.")
-	flag.Int64Var(&maxRPS, "max-rps", 0, "Max RPS that boomer can generate.")
+	flag.Int64Var(&maxRPS, "max-rps", 0, "Max RPS that boomer can generate, the share of the cluster-wide max RPS from the master can't exceed it.")
+	shutdownTimeout = flag.Duration("shutdown-timeout", 10*time.Second, "How long to wait for users to finish their current tasks on shutdown.")
 }
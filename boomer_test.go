@@ -0,0 +1,188 @@
+package boomer
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// runWorker runs Run against the master in the background, the returned channel receives what Run returns.
+func runWorker(t *testing.T, m *Master, tasks ...*Task) chan error {
+	oldRPC, oldHost, oldPort := *rpc, *masterHost, *masterPort
+	t.Cleanup(func() {
+		*rpc, *masterHost, *masterPort = oldRPC, oldHost, oldPort
+	})
+
+	addr := m.Addr().(*net.TCPAddr)
+	*rpc = "socket"
+	*masterHost = addr.IP.String()
+	*masterPort = addr.Port
+
+	for len(fromMaster) > 0 {
+		<-fromMaster
+	}
+	for len(toMaster) > 0 {
+		<-toMaster
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(tasks...)
+	}()
+	waitForWorkers(t, m, 1)
+	return done
+}
+
+func waitForRun(t *testing.T, done chan error) {
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error("Run should return nil without thresholds, got", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for Run to return")
+	}
+}
+
+func TestRunStop(t *testing.T) {
+	m := newTestMaster(t)
+	defer m.Close()
+
+	var running int32
+	task := &Task{
+		Name:   "stop",
+		Weight: 1,
+		Fn: func() {
+			atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			time.Sleep(20 * time.Millisecond)
+			RecordSuccess("run", "stop", 20, 1, nil)
+		},
+	}
+	done := runWorker(t, m, task)
+
+	if err := m.Swarm(2, 10); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&running) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("users should be running")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	Stop()
+	waitForRun(t, done)
+
+	if n := atomic.LoadInt32(&running); n != 0 {
+		t.Error("users should finish their tasks before Run returns, still running", n)
+	}
+	// the worker quits after the final stats, which are sent before the first report
+	waitForWorkers(t, m, 0)
+	entry := m.summary().stats.get("stop", "run", nil)
+	if entry.numRequests == 0 {
+		t.Error("final stats should be sent to master")
+	}
+}
+
+func TestRunQuitByMaster(t *testing.T) {
+	m := newTestMaster(t)
+	defer m.Close()
+
+	done := runWorker(t, m, &Task{
+		Name:   "quit",
+		Weight: 1,
+		Fn: func() {
+			time.Sleep(10 * time.Millisecond)
+		},
+	})
+
+	m.Quit()
+	waitForRun(t, done)
+}
+
+func TestRunStandalone(t *testing.T) {
+	defer func(clients, hatchRate int, d time.Duration) {
+		*standalone = false
+		*standaloneClients, *standaloneHatchRate, *runTime = clients, hatchRate, d
+		standaloneOutput = os.Stdout
+	}(*standaloneClients, *standaloneHatchRate, *runTime)
+
+	output := &bytes.Buffer{}
+	standaloneOutput = output
+	*standalone = true
+	*standaloneClients = 2
+	*standaloneHatchRate = 10
+	*runTime = 100 * time.Millisecond
+
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(&Task{
+			Name:   "standalone",
+			Weight: 1,
+			Fn: func() {
+				time.Sleep(10 * time.Millisecond)
+				RecordSuccess("local", "standalone", 10, 1, nil)
+				NewTrend("standalone_trend").Add(10)
+			},
+		})
+	}()
+	waitForRun(t, done)
+
+	if !strings.Contains(output.String(), "standalone") || !strings.Contains(output.String(), "Aggregated") {
+		t.Error("stats should be printed, got", output.String())
+	}
+	if !strings.Contains(output.String(), "standalone_trend") {
+		t.Error("custom metrics should be printed, got", output.String())
+	}
+}
+
+func TestRunMasterLost(t *testing.T) {
+	m := newTestMaster(t)
+
+	done := runWorker(t, m, &Task{
+		Name:   "lost",
+		Weight: 1,
+		Fn: func() {
+			time.Sleep(10 * time.Millisecond)
+		},
+	})
+
+	m.Close()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrMasterLost) {
+			t.Error("Run should return ErrMasterLost, got", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run should return when the master is lost")
+	}
+}
+
+func TestStopBeforeRun(t *testing.T) {
+	m := newTestMaster(t)
+	defer m.Close()
+
+	Stop()
+	done := runWorker(t, m, &Task{
+		Name:   "stop",
+		Weight: 1,
+		Fn: func() {
+			time.Sleep(10 * time.Millisecond)
+		},
+	})
+	select {
+	case err := <-done:
+		t.Fatal("Stop before Run should be ignored, Run returned", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	m.Quit()
+	waitForRun(t, done)
+}
@@ -0,0 +1,1139 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: boomer.proto
+
+package boomerpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Message is the same as the msgpack message of locust, data is one of the typed payloads,
+// and it's empty for client_ready, hatching, client_stopped, stop and quit.
+type Message struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Type   string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	NodeId string                 `protobuf:"bytes,2,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	// Types that are valid to be assigned to Data:
+	//
+	//	*Message_Hatch
+	//	*Message_HatchComplete
+	//	*Message_Stats
+	//	*Message_CustomData
+	//	*Message_MaxRps
+	//	*Message_Rendezvous
+	//	*Message_RendezvousRelease
+	Data          isMessage_Data `protobuf_oneof:"data"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Message) Reset() {
+	*x = Message{}
+	mi := &file_boomer_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Message) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Message) ProtoMessage() {}
+
+func (x *Message) ProtoReflect() protoreflect.Message {
+	mi := &file_boomer_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Message.ProtoReflect.Descriptor instead.
+func (*Message) Descriptor() ([]byte, []int) {
+	return file_boomer_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Message) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Message) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *Message) GetData() isMessage_Data {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Message) GetHatch() *Hatch {
+	if x != nil {
+		if x, ok := x.Data.(*Message_Hatch); ok {
+			return x.Hatch
+		}
+	}
+	return nil
+}
+
+func (x *Message) GetHatchComplete() *HatchComplete {
+	if x != nil {
+		if x, ok := x.Data.(*Message_HatchComplete); ok {
+			return x.HatchComplete
+		}
+	}
+	return nil
+}
+
+func (x *Message) GetStats() *Stats {
+	if x != nil {
+		if x, ok := x.Data.(*Message_Stats); ok {
+			return x.Stats
+		}
+	}
+	return nil
+}
+
+func (x *Message) GetCustomData() []byte {
+	if x != nil {
+		if x, ok := x.Data.(*Message_CustomData); ok {
+			return x.CustomData
+		}
+	}
+	return nil
+}
+
+func (x *Message) GetMaxRps() *MaxRPS {
+	if x != nil {
+		if x, ok := x.Data.(*Message_MaxRps); ok {
+			return x.MaxRps
+		}
+	}
+	return nil
+}
+
+func (x *Message) GetRendezvous() *Rendezvous {
+	if x != nil {
+		if x, ok := x.Data.(*Message_Rendezvous); ok {
+			return x.Rendezvous
+		}
+	}
+	return nil
+}
+
+func (x *Message) GetRendezvousRelease() *RendezvousRelease {
+	if x != nil {
+		if x, ok := x.Data.(*Message_RendezvousRelease); ok {
+			return x.RendezvousRelease
+		}
+	}
+	return nil
+}
+
+type isMessage_Data interface {
+	isMessage_Data()
+}
+
+type Message_Hatch struct {
+	Hatch *Hatch `protobuf:"bytes,3,opt,name=hatch,proto3,oneof"`
+}
+
+type Message_HatchComplete struct {
+	HatchComplete *HatchComplete `protobuf:"bytes,4,opt,name=hatch_complete,json=hatchComplete,proto3,oneof"`
+}
+
+type Message_Stats struct {
+	Stats *Stats `protobuf:"bytes,5,opt,name=stats,proto3,oneof"`
+}
+
+type Message_CustomData struct {
+	// data of custom messages has no schema, it's a msgpack encoded map.
+	CustomData []byte `protobuf:"bytes,6,opt,name=custom_data,json=customData,proto3,oneof"`
+}
+
+type Message_MaxRps struct {
+	MaxRps *MaxRPS `protobuf:"bytes,7,opt,name=max_rps,json=maxRps,proto3,oneof"`
+}
+
+type Message_Rendezvous struct {
+	// for both rendezvous and rendezvous_leave
+	Rendezvous *Rendezvous `protobuf:"bytes,8,opt,name=rendezvous,proto3,oneof"`
+}
+
+type Message_RendezvousRelease struct {
+	RendezvousRelease *RendezvousRelease `protobuf:"bytes,9,opt,name=rendezvous_release,json=rendezvousRelease,proto3,oneof"`
+}
+
+func (*Message_Hatch) isMessage_Data() {}
+
+func (*Message_HatchComplete) isMessage_Data() {}
+
+func (*Message_Stats) isMessage_Data() {}
+
+func (*Message_CustomData) isMessage_Data() {}
+
+func (*Message_MaxRps) isMessage_Data() {}
+
+func (*Message_Rendezvous) isMessage_Data() {}
+
+func (*Message_RendezvousRelease) isMessage_Data() {}
+
+type Hatch struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	NumClients int64                  `protobuf:"varint,1,opt,name=num_clients,json=numClients,proto3" json:"num_clients,omitempty"`
+	HatchRate  float64                `protobuf:"fixed64,2,opt,name=hatch_rate,json=hatchRate,proto3" json:"hatch_rate,omitempty"`
+	// worker_index and worker_count partition test data among the workers with users, worker_count is 0 if unknown.
+	WorkerIndex   int64 `protobuf:"varint,3,opt,name=worker_index,json=workerIndex,proto3" json:"worker_index,omitempty"`
+	WorkerCount   int64 `protobuf:"varint,4,opt,name=worker_count,json=workerCount,proto3" json:"worker_count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Hatch) Reset() {
+	*x = Hatch{}
+	mi := &file_boomer_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Hatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Hatch) ProtoMessage() {}
+
+func (x *Hatch) ProtoReflect() protoreflect.Message {
+	mi := &file_boomer_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Hatch.ProtoReflect.Descriptor instead.
+func (*Hatch) Descriptor() ([]byte, []int) {
+	return file_boomer_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Hatch) GetNumClients() int64 {
+	if x != nil {
+		return x.NumClients
+	}
+	return 0
+}
+
+func (x *Hatch) GetHatchRate() float64 {
+	if x != nil {
+		return x.HatchRate
+	}
+	return 0
+}
+
+func (x *Hatch) GetWorkerIndex() int64 {
+	if x != nil {
+		return x.WorkerIndex
+	}
+	return 0
+}
+
+func (x *Hatch) GetWorkerCount() int64 {
+	if x != nil {
+		return x.WorkerCount
+	}
+	return 0
+}
+
+type HatchComplete struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Count         int64                  `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HatchComplete) Reset() {
+	*x = HatchComplete{}
+	mi := &file_boomer_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HatchComplete) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HatchComplete) ProtoMessage() {}
+
+func (x *HatchComplete) ProtoReflect() protoreflect.Message {
+	mi := &file_boomer_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HatchComplete.ProtoReflect.Descriptor instead.
+func (*HatchComplete) Descriptor() ([]byte, []int) {
+	return file_boomer_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *HatchComplete) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+// Rendezvous is sent by workers when a user arrives at a rendezvous point, or leaves it on timeout.
+type Rendezvous struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Name         string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Participants int64                  `protobuf:"varint,2,opt,name=participants,proto3" json:"participants,omitempty"`
+	// unique on a worker
+	Id            int64 `protobuf:"varint,3,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Rendezvous) Reset() {
+	*x = Rendezvous{}
+	mi := &file_boomer_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Rendezvous) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Rendezvous) ProtoMessage() {}
+
+func (x *Rendezvous) ProtoReflect() protoreflect.Message {
+	mi := &file_boomer_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Rendezvous.ProtoReflect.Descriptor instead.
+func (*Rendezvous) Descriptor() ([]byte, []int) {
+	return file_boomer_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Rendezvous) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Rendezvous) GetParticipants() int64 {
+	if x != nil {
+		return x.Participants
+	}
+	return 0
+}
+
+func (x *Rendezvous) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+// RendezvousRelease releases the users of a worker waiting at a rendezvous point.
+type RendezvousRelease struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Ids           []int64                `protobuf:"varint,2,rep,packed,name=ids,proto3" json:"ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RendezvousRelease) Reset() {
+	*x = RendezvousRelease{}
+	mi := &file_boomer_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RendezvousRelease) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RendezvousRelease) ProtoMessage() {}
+
+func (x *RendezvousRelease) ProtoReflect() protoreflect.Message {
+	mi := &file_boomer_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RendezvousRelease.ProtoReflect.Descriptor instead.
+func (*RendezvousRelease) Descriptor() ([]byte, []int) {
+	return file_boomer_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *RendezvousRelease) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *RendezvousRelease) GetIds() []int64 {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+// MaxRPS is the share of the cluster-wide max RPS of a worker, 0 means unlimited.
+type MaxRPS struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MaxRps        int64                  `protobuf:"varint,1,opt,name=max_rps,json=maxRps,proto3" json:"max_rps,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MaxRPS) Reset() {
+	*x = MaxRPS{}
+	mi := &file_boomer_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MaxRPS) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MaxRPS) ProtoMessage() {}
+
+func (x *MaxRPS) ProtoReflect() protoreflect.Message {
+	mi := &file_boomer_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MaxRPS.ProtoReflect.Descriptor instead.
+func (*MaxRPS) Descriptor() ([]byte, []int) {
+	return file_boomer_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *MaxRPS) GetMaxRps() int64 {
+	if x != nil {
+		return x.MaxRps
+	}
+	return 0
+}
+
+type Stats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Stats         []*StatsEntry          `protobuf:"bytes,1,rep,name=stats,proto3" json:"stats,omitempty"`
+	StatsTotal    *StatsEntry            `protobuf:"bytes,2,opt,name=stats_total,json=statsTotal,proto3" json:"stats_total,omitempty"`
+	Errors        map[string]*StatsError `protobuf:"bytes,3,rep,name=errors,proto3" json:"errors,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	UserCount     int64                  `protobuf:"varint,4,opt,name=user_count,json=userCount,proto3" json:"user_count,omitempty"`
+	CustomMetrics *CustomMetrics         `protobuf:"bytes,5,opt,name=custom_metrics,json=customMetrics,proto3" json:"custom_metrics,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Stats) Reset() {
+	*x = Stats{}
+	mi := &file_boomer_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Stats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Stats) ProtoMessage() {}
+
+func (x *Stats) ProtoReflect() protoreflect.Message {
+	mi := &file_boomer_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Stats.ProtoReflect.Descriptor instead.
+func (*Stats) Descriptor() ([]byte, []int) {
+	return file_boomer_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Stats) GetStats() []*StatsEntry {
+	if x != nil {
+		return x.Stats
+	}
+	return nil
+}
+
+func (x *Stats) GetStatsTotal() *StatsEntry {
+	if x != nil {
+		return x.StatsTotal
+	}
+	return nil
+}
+
+func (x *Stats) GetErrors() map[string]*StatsError {
+	if x != nil {
+		return x.Errors
+	}
+	return nil
+}
+
+func (x *Stats) GetUserCount() int64 {
+	if x != nil {
+		return x.UserCount
+	}
+	return 0
+}
+
+func (x *Stats) GetCustomMetrics() *CustomMetrics {
+	if x != nil {
+		return x.CustomMetrics
+	}
+	return nil
+}
+
+type StatsEntry struct {
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	Name                 string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Method               string                 `protobuf:"bytes,2,opt,name=method,proto3" json:"method,omitempty"`
+	NumRequests          int64                  `protobuf:"varint,3,opt,name=num_requests,json=numRequests,proto3" json:"num_requests,omitempty"`
+	NumFailures          int64                  `protobuf:"varint,4,opt,name=num_failures,json=numFailures,proto3" json:"num_failures,omitempty"`
+	TotalResponseTime    int64                  `protobuf:"varint,5,opt,name=total_response_time,json=totalResponseTime,proto3" json:"total_response_time,omitempty"`
+	MinResponseTime      int64                  `protobuf:"varint,6,opt,name=min_response_time,json=minResponseTime,proto3" json:"min_response_time,omitempty"`
+	MaxResponseTime      int64                  `protobuf:"varint,7,opt,name=max_response_time,json=maxResponseTime,proto3" json:"max_response_time,omitempty"`
+	TotalContentLength   int64                  `protobuf:"varint,8,opt,name=total_content_length,json=totalContentLength,proto3" json:"total_content_length,omitempty"`
+	StartTime            int64                  `protobuf:"varint,9,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	LastRequestTimestamp int64                  `protobuf:"varint,10,opt,name=last_request_timestamp,json=lastRequestTimestamp,proto3" json:"last_request_timestamp,omitempty"`
+	ResponseTimes        map[int64]int64        `protobuf:"bytes,11,rep,name=response_times,json=responseTimes,proto3" json:"response_times,omitempty" protobuf_key:"varint,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	NumReqsPerSec        map[int64]int64        `protobuf:"bytes,12,rep,name=num_reqs_per_sec,json=numReqsPerSec,proto3" json:"num_reqs_per_sec,omitempty" protobuf_key:"varint,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *StatsEntry) Reset() {
+	*x = StatsEntry{}
+	mi := &file_boomer_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsEntry) ProtoMessage() {}
+
+func (x *StatsEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_boomer_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsEntry.ProtoReflect.Descriptor instead.
+func (*StatsEntry) Descriptor() ([]byte, []int) {
+	return file_boomer_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *StatsEntry) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *StatsEntry) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *StatsEntry) GetNumRequests() int64 {
+	if x != nil {
+		return x.NumRequests
+	}
+	return 0
+}
+
+func (x *StatsEntry) GetNumFailures() int64 {
+	if x != nil {
+		return x.NumFailures
+	}
+	return 0
+}
+
+func (x *StatsEntry) GetTotalResponseTime() int64 {
+	if x != nil {
+		return x.TotalResponseTime
+	}
+	return 0
+}
+
+func (x *StatsEntry) GetMinResponseTime() int64 {
+	if x != nil {
+		return x.MinResponseTime
+	}
+	return 0
+}
+
+func (x *StatsEntry) GetMaxResponseTime() int64 {
+	if x != nil {
+		return x.MaxResponseTime
+	}
+	return 0
+}
+
+func (x *StatsEntry) GetTotalContentLength() int64 {
+	if x != nil {
+		return x.TotalContentLength
+	}
+	return 0
+}
+
+func (x *StatsEntry) GetStartTime() int64 {
+	if x != nil {
+		return x.StartTime
+	}
+	return 0
+}
+
+func (x *StatsEntry) GetLastRequestTimestamp() int64 {
+	if x != nil {
+		return x.LastRequestTimestamp
+	}
+	return 0
+}
+
+func (x *StatsEntry) GetResponseTimes() map[int64]int64 {
+	if x != nil {
+		return x.ResponseTimes
+	}
+	return nil
+}
+
+func (x *StatsEntry) GetNumReqsPerSec() map[int64]int64 {
+	if x != nil {
+		return x.NumReqsPerSec
+	}
+	return nil
+}
+
+type StatsError struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Method        string                 `protobuf:"bytes,2,opt,name=method,proto3" json:"method,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	Occurrences   int64                  `protobuf:"varint,4,opt,name=occurrences,proto3" json:"occurrences,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatsError) Reset() {
+	*x = StatsError{}
+	mi := &file_boomer_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsError) ProtoMessage() {}
+
+func (x *StatsError) ProtoReflect() protoreflect.Message {
+	mi := &file_boomer_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsError.ProtoReflect.Descriptor instead.
+func (*StatsError) Descriptor() ([]byte, []int) {
+	return file_boomer_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *StatsError) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *StatsError) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *StatsError) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *StatsError) GetOccurrences() int64 {
+	if x != nil {
+		return x.Occurrences
+	}
+	return 0
+}
+
+type CustomMetrics struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Counters      map[string]int64       `protobuf:"bytes,1,rep,name=counters,proto3" json:"counters,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	Gauges        map[string]float64     `protobuf:"bytes,2,rep,name=gauges,proto3" json:"gauges,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"fixed64,2,opt,name=value"`
+	Trends        map[string]*Trend      `protobuf:"bytes,3,rep,name=trends,proto3" json:"trends,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CustomMetrics) Reset() {
+	*x = CustomMetrics{}
+	mi := &file_boomer_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CustomMetrics) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CustomMetrics) ProtoMessage() {}
+
+func (x *CustomMetrics) ProtoReflect() protoreflect.Message {
+	mi := &file_boomer_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CustomMetrics.ProtoReflect.Descriptor instead.
+func (*CustomMetrics) Descriptor() ([]byte, []int) {
+	return file_boomer_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *CustomMetrics) GetCounters() map[string]int64 {
+	if x != nil {
+		return x.Counters
+	}
+	return nil
+}
+
+func (x *CustomMetrics) GetGauges() map[string]float64 {
+	if x != nil {
+		return x.Gauges
+	}
+	return nil
+}
+
+func (x *CustomMetrics) GetTrends() map[string]*Trend {
+	if x != nil {
+		return x.Trends
+	}
+	return nil
+}
+
+type Trend struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Count int64                  `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	Sum   float64                `protobuf:"fixed64,2,opt,name=sum,proto3" json:"sum,omitempty"`
+	Min   float64                `protobuf:"fixed64,3,opt,name=min,proto3" json:"min,omitempty"`
+	Max   float64                `protobuf:"fixed64,4,opt,name=max,proto3" json:"max,omitempty"`
+	// map keys can't be double
+	Values        []*TrendValue `protobuf:"bytes,5,rep,name=values,proto3" json:"values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Trend) Reset() {
+	*x = Trend{}
+	mi := &file_boomer_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Trend) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Trend) ProtoMessage() {}
+
+func (x *Trend) ProtoReflect() protoreflect.Message {
+	mi := &file_boomer_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Trend.ProtoReflect.Descriptor instead.
+func (*Trend) Descriptor() ([]byte, []int) {
+	return file_boomer_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Trend) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *Trend) GetSum() float64 {
+	if x != nil {
+		return x.Sum
+	}
+	return 0
+}
+
+func (x *Trend) GetMin() float64 {
+	if x != nil {
+		return x.Min
+	}
+	return 0
+}
+
+func (x *Trend) GetMax() float64 {
+	if x != nil {
+		return x.Max
+	}
+	return 0
+}
+
+func (x *Trend) GetValues() []*TrendValue {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type TrendValue struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Value         float64                `protobuf:"fixed64,1,opt,name=value,proto3" json:"value,omitempty"`
+	Count         int64                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TrendValue) Reset() {
+	*x = TrendValue{}
+	mi := &file_boomer_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TrendValue) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TrendValue) ProtoMessage() {}
+
+func (x *TrendValue) ProtoReflect() protoreflect.Message {
+	mi := &file_boomer_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TrendValue.ProtoReflect.Descriptor instead.
+func (*TrendValue) Descriptor() ([]byte, []int) {
+	return file_boomer_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *TrendValue) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *TrendValue) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+var File_boomer_proto protoreflect.FileDescriptor
+
+const file_boomer_proto_rawDesc = "" +
+	"\n" +
+	"\fboomer.proto\x12\x06boomer\"\x9c\x03\n" +
+	"\aMessage\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x17\n" +
+	"\anode_id\x18\x02 \x01(\tR\x06nodeId\x12%\n" +
+	"\x05hatch\x18\x03 \x01(\v2\r.boomer.HatchH\x00R\x05hatch\x12>\n" +
+	"\x0ehatch_complete\x18\x04 \x01(\v2\x15.boomer.HatchCompleteH\x00R\rhatchComplete\x12%\n" +
+	"\x05stats\x18\x05 \x01(\v2\r.boomer.StatsH\x00R\x05stats\x12!\n" +
+	"\vcustom_data\x18\x06 \x01(\fH\x00R\n" +
+	"customData\x12)\n" +
+	"\amax_rps\x18\a \x01(\v2\x0e.boomer.MaxRPSH\x00R\x06maxRps\x124\n" +
+	"\n" +
+	"rendezvous\x18\b \x01(\v2\x12.boomer.RendezvousH\x00R\n" +
+	"rendezvous\x12J\n" +
+	"\x12rendezvous_release\x18\t \x01(\v2\x19.boomer.RendezvousReleaseH\x00R\x11rendezvousReleaseB\x06\n" +
+	"\x04data\"\x8d\x01\n" +
+	"\x05Hatch\x12\x1f\n" +
+	"\vnum_clients\x18\x01 \x01(\x03R\n" +
+	"numClients\x12\x1d\n" +
+	"\n" +
+	"hatch_rate\x18\x02 \x01(\x01R\thatchRate\x12!\n" +
+	"\fworker_index\x18\x03 \x01(\x03R\vworkerIndex\x12!\n" +
+	"\fworker_count\x18\x04 \x01(\x03R\vworkerCount\"%\n" +
+	"\rHatchComplete\x12\x14\n" +
+	"\x05count\x18\x01 \x01(\x03R\x05count\"T\n" +
+	"\n" +
+	"Rendezvous\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\"\n" +
+	"\fparticipants\x18\x02 \x01(\x03R\fparticipants\x12\x0e\n" +
+	"\x02id\x18\x03 \x01(\x03R\x02id\"9\n" +
+	"\x11RendezvousRelease\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x10\n" +
+	"\x03ids\x18\x02 \x03(\x03R\x03ids\"!\n" +
+	"\x06MaxRPS\x12\x17\n" +
+	"\amax_rps\x18\x01 \x01(\x03R\x06maxRps\"\xc5\x02\n" +
+	"\x05Stats\x12(\n" +
+	"\x05stats\x18\x01 \x03(\v2\x12.boomer.StatsEntryR\x05stats\x123\n" +
+	"\vstats_total\x18\x02 \x01(\v2\x12.boomer.StatsEntryR\n" +
+	"statsTotal\x121\n" +
+	"\x06errors\x18\x03 \x03(\v2\x19.boomer.Stats.ErrorsEntryR\x06errors\x12\x1d\n" +
+	"\n" +
+	"user_count\x18\x04 \x01(\x03R\tuserCount\x12<\n" +
+	"\x0ecustom_metrics\x18\x05 \x01(\v2\x15.boomer.CustomMetricsR\rcustomMetrics\x1aM\n" +
+	"\vErrorsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12(\n" +
+	"\x05value\x18\x02 \x01(\v2\x12.boomer.StatsErrorR\x05value:\x028\x01\"\xaf\x05\n" +
+	"\n" +
+	"StatsEntry\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06method\x18\x02 \x01(\tR\x06method\x12!\n" +
+	"\fnum_requests\x18\x03 \x01(\x03R\vnumRequests\x12!\n" +
+	"\fnum_failures\x18\x04 \x01(\x03R\vnumFailures\x12.\n" +
+	"\x13total_response_time\x18\x05 \x01(\x03R\x11totalResponseTime\x12*\n" +
+	"\x11min_response_time\x18\x06 \x01(\x03R\x0fminResponseTime\x12*\n" +
+	"\x11max_response_time\x18\a \x01(\x03R\x0fmaxResponseTime\x120\n" +
+	"\x14total_content_length\x18\b \x01(\x03R\x12totalContentLength\x12\x1d\n" +
+	"\n" +
+	"start_time\x18\t \x01(\x03R\tstartTime\x124\n" +
+	"\x16last_request_timestamp\x18\n" +
+	" \x01(\x03R\x14lastRequestTimestamp\x12L\n" +
+	"\x0eresponse_times\x18\v \x03(\v2%.boomer.StatsEntry.ResponseTimesEntryR\rresponseTimes\x12N\n" +
+	"\x10num_reqs_per_sec\x18\f \x03(\v2%.boomer.StatsEntry.NumReqsPerSecEntryR\rnumReqsPerSec\x1a@\n" +
+	"\x12ResponseTimesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\x03R\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\x1a@\n" +
+	"\x12NumReqsPerSecEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\x03R\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"p\n" +
+	"\n" +
+	"StatsError\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06method\x18\x02 \x01(\tR\x06method\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\x12 \n" +
+	"\voccurrences\x18\x04 \x01(\x03R\voccurrences\"\x88\x03\n" +
+	"\rCustomMetrics\x12?\n" +
+	"\bcounters\x18\x01 \x03(\v2#.boomer.CustomMetrics.CountersEntryR\bcounters\x129\n" +
+	"\x06gauges\x18\x02 \x03(\v2!.boomer.CustomMetrics.GaugesEntryR\x06gauges\x129\n" +
+	"\x06trends\x18\x03 \x03(\v2!.boomer.CustomMetrics.TrendsEntryR\x06trends\x1a;\n" +
+	"\rCountersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\x1a9\n" +
+	"\vGaugesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value:\x028\x01\x1aH\n" +
+	"\vTrendsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12#\n" +
+	"\x05value\x18\x02 \x01(\v2\r.boomer.TrendR\x05value:\x028\x01\"\x7f\n" +
+	"\x05Trend\x12\x14\n" +
+	"\x05count\x18\x01 \x01(\x03R\x05count\x12\x10\n" +
+	"\x03sum\x18\x02 \x01(\x01R\x03sum\x12\x10\n" +
+	"\x03min\x18\x03 \x01(\x01R\x03min\x12\x10\n" +
+	"\x03max\x18\x04 \x01(\x01R\x03max\x12*\n" +
+	"\x06values\x18\x05 \x03(\v2\x12.boomer.TrendValueR\x06values\"8\n" +
+	"\n" +
+	"TrendValue\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\x01R\x05value\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x03R\x05count29\n" +
+	"\x06Master\x12/\n" +
+	"\aConnect\x12\x0f.boomer.Message\x1a\x0f.boomer.Message(\x010\x01B#Z!github.com/myzhan/boomer/boomerpbb\x06proto3"
+
+var (
+	file_boomer_proto_rawDescOnce sync.Once
+	file_boomer_proto_rawDescData []byte
+)
+
+func file_boomer_proto_rawDescGZIP() []byte {
+	file_boomer_proto_rawDescOnce.Do(func() {
+		file_boomer_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_boomer_proto_rawDesc), len(file_boomer_proto_rawDesc)))
+	})
+	return file_boomer_proto_rawDescData
+}
+
+var file_boomer_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
+var file_boomer_proto_goTypes = []any{
+	(*Message)(nil),           // 0: boomer.Message
+	(*Hatch)(nil),             // 1: boomer.Hatch
+	(*HatchComplete)(nil),     // 2: boomer.HatchComplete
+	(*Rendezvous)(nil),        // 3: boomer.Rendezvous
+	(*RendezvousRelease)(nil), // 4: boomer.RendezvousRelease
+	(*MaxRPS)(nil),            // 5: boomer.MaxRPS
+	(*Stats)(nil),             // 6: boomer.Stats
+	(*StatsEntry)(nil),        // 7: boomer.StatsEntry
+	(*StatsError)(nil),        // 8: boomer.StatsError
+	(*CustomMetrics)(nil),     // 9: boomer.CustomMetrics
+	(*Trend)(nil),             // 10: boomer.Trend
+	(*TrendValue)(nil),        // 11: boomer.TrendValue
+	nil,                       // 12: boomer.Stats.ErrorsEntry
+	nil,                       // 13: boomer.StatsEntry.ResponseTimesEntry
+	nil,                       // 14: boomer.StatsEntry.NumReqsPerSecEntry
+	nil,                       // 15: boomer.CustomMetrics.CountersEntry
+	nil,                       // 16: boomer.CustomMetrics.GaugesEntry
+	nil,                       // 17: boomer.CustomMetrics.TrendsEntry
+}
+var file_boomer_proto_depIdxs = []int32{
+	1,  // 0: boomer.Message.hatch:type_name -> boomer.Hatch
+	2,  // 1: boomer.Message.hatch_complete:type_name -> boomer.HatchComplete
+	6,  // 2: boomer.Message.stats:type_name -> boomer.Stats
+	5,  // 3: boomer.Message.max_rps:type_name -> boomer.MaxRPS
+	3,  // 4: boomer.Message.rendezvous:type_name -> boomer.Rendezvous
+	4,  // 5: boomer.Message.rendezvous_release:type_name -> boomer.RendezvousRelease
+	7,  // 6: boomer.Stats.stats:type_name -> boomer.StatsEntry
+	7,  // 7: boomer.Stats.stats_total:type_name -> boomer.StatsEntry
+	12, // 8: boomer.Stats.errors:type_name -> boomer.Stats.ErrorsEntry
+	9,  // 9: boomer.Stats.custom_metrics:type_name -> boomer.CustomMetrics
+	13, // 10: boomer.StatsEntry.response_times:type_name -> boomer.StatsEntry.ResponseTimesEntry
+	14, // 11: boomer.StatsEntry.num_reqs_per_sec:type_name -> boomer.StatsEntry.NumReqsPerSecEntry
+	15, // 12: boomer.CustomMetrics.counters:type_name -> boomer.CustomMetrics.CountersEntry
+	16, // 13: boomer.CustomMetrics.gauges:type_name -> boomer.CustomMetrics.GaugesEntry
+	17, // 14: boomer.CustomMetrics.trends:type_name -> boomer.CustomMetrics.TrendsEntry
+	11, // 15: boomer.Trend.values:type_name -> boomer.TrendValue
+	8,  // 16: boomer.Stats.ErrorsEntry.value:type_name -> boomer.StatsError
+	10, // 17: boomer.CustomMetrics.TrendsEntry.value:type_name -> boomer.Trend
+	0,  // 18: boomer.Master.Connect:input_type -> boomer.Message
+	0,  // 19: boomer.Master.Connect:output_type -> boomer.Message
+	19, // [19:20] is the sub-list for method output_type
+	18, // [18:19] is the sub-list for method input_type
+	18, // [18:18] is the sub-list for extension type_name
+	18, // [18:18] is the sub-list for extension extendee
+	0,  // [0:18] is the sub-list for field type_name
+}
+
+func init() { file_boomer_proto_init() }
+func file_boomer_proto_init() {
+	if File_boomer_proto != nil {
+		return
+	}
+	file_boomer_proto_msgTypes[0].OneofWrappers = []any{
+		(*Message_Hatch)(nil),
+		(*Message_HatchComplete)(nil),
+		(*Message_Stats)(nil),
+		(*Message_CustomData)(nil),
+		(*Message_MaxRps)(nil),
+		(*Message_Rendezvous)(nil),
+		(*Message_RendezvousRelease)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_boomer_proto_rawDesc), len(file_boomer_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   18,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_boomer_proto_goTypes,
+		DependencyIndexes: file_boomer_proto_depIdxs,
+		MessageInfos:      file_boomer_proto_msgTypes,
+	}.Build()
+	File_boomer_proto = out.File
+	file_boomer_proto_goTypes = nil
+	file_boomer_proto_depIdxs = nil
+}
@@ -0,0 +1,4 @@
+// Package boomerpb is the protobuf schema of messages between the master and workers, used by --rpc=grpc.
+package boomerpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative boomer.proto
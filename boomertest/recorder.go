@@ -0,0 +1,87 @@
+// Package boomertest helps to test code that reports requests to boomer, like the helper packages.
+//
+//	func TestLogin(t *testing.T) {
+//		recorder := boomertest.Record(t)
+//		login()
+//		if got := recorder.Requests(); len(got) != 1 || got[0].String() != "POST /login" {
+//			t.Error("login should be reported, got", got)
+//		}
+//	}
+package boomertest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/myzhan/boomer"
+)
+
+// Request is a request reported by boomer.RecordSuccess or boomer.RecordFailure.
+type Request struct {
+	Success      bool
+	Type         string
+	Name         string
+	ResponseTime int64
+	Length       int64
+	Exception    string
+	Tags         boomer.Tags
+}
+
+// String is like "GET /users" for successful requests, and "GET /users: timeout" for failures.
+func (r Request) String() string {
+	if r.Success {
+		return fmt.Sprintf("%s %s", r.Type, r.Name)
+	}
+	return fmt.Sprintf("%s %s: %s", r.Type, r.Name, r.Exception)
+}
+
+// Recorder keeps the requests reported, requests can be reported from many goroutines.
+type Recorder struct {
+	lock     sync.Mutex
+	requests []Request
+}
+
+// Record reports requests to a new Recorder until the test ends, instead of boomer.
+func Record(t testing.TB) *Recorder {
+	r := &Recorder{}
+	boomer.SetRecorder(r)
+	t.Cleanup(func() {
+		boomer.SetRecorder(nil)
+	})
+	return r
+}
+
+// RecordSuccess implements boomer.Recorder.
+func (r *Recorder) RecordSuccess(requestType string, name string, responseTime int64, responseLength int64, tags boomer.Tags) {
+	r.append(Request{true, requestType, name, responseTime, responseLength, "", tags})
+}
+
+// RecordFailure implements boomer.Recorder.
+func (r *Recorder) RecordFailure(requestType string, name string, responseTime int64, exception string, tags boomer.Tags) {
+	r.append(Request{false, requestType, name, responseTime, 0, exception, tags})
+}
+
+func (r *Recorder) append(request Request) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.requests = append(r.requests, request)
+}
+
+// Requests returns the requests reported so far.
+func (r *Recorder) Requests() []Request {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]Request(nil), r.requests...)
+}
+
+// Failures returns the failed requests reported so far.
+func (r *Recorder) Failures() []Request {
+	failures := make([]Request, 0)
+	for _, request := range r.Requests() {
+		if !request.Success {
+			failures = append(failures, request)
+		}
+	}
+	return failures
+}
@@ -1,24 +1,52 @@
 package boomer
 
 import (
+	"errors"
 	"flag"
+	"log"
 )
 
 type client interface {
 	recv()
 	send()
+	close()
 }
 
 var fromMaster = make(chan *message, 100)
 var toMaster = make(chan *message, 100)
-var disconnectedFromMaster = make(chan bool)
+
+// disconnectedFromMaster is notified when the quit message is sent to master.
+var disconnectedFromMaster = make(chan bool, 1)
 
 var masterHost *string
 var masterPort *int
 var rpc *string
 
+// notifyDisconnected never blocks the client, nobody may be waiting for it.
+func notifyDisconnected() {
+	select {
+	case disconnectedFromMaster <- true:
+	default:
+	}
+}
+
+// ErrMasterLost is wrapped by the error returned from Run if the connection to the master is broken.
+var ErrMasterLost = errors.New("lost connection to master")
+
+// masterLost is notified when the client can't read from the master any more.
+var masterLost = make(chan error, 1)
+
+// notifyMasterLost never blocks the client, like notifyDisconnected.
+func notifyMasterLost(err error) {
+	log.Println("Lost connection to master:", err)
+	select {
+	case masterLost <- err:
+	default:
+	}
+}
+
 func init() {
 	masterHost = flag.String("master-host", "127.0.0.1", "Host or IP address of locust master for distributed load testing. Defaults to 127.0.0.1.")
 	masterPort = flag.Int("master-port", 5557, "The port to connect to that is used by the locust master for distributed load testing. Defaults to 5557.")
-	rpc = flag.String("rpc", "zeromq", "Choose zeromq or tcp socket to communicate with master, don't mix them up.")
+	rpc = flag.String("rpc", "zeromq", "Choose zeromq, tcp socket or grpc to communicate with master, don't mix them up. grpc only works with the native master.")
 }
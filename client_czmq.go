@@ -10,8 +10,9 @@ import (
 )
 
 type czmqSocketClient struct {
-	pushConn *goczmq.Sock
-	pullConn *goczmq.Sock
+	pushConn     *goczmq.Sock
+	pullConn     *goczmq.Sock
+	closeChannel chan bool
 }
 
 func newClient() client {
@@ -24,6 +25,9 @@ func newClient() client {
 	} else if *rpc == "socket" {
 		client = newSocketClient(*masterHost, *masterPort)
 		message = fmt.Sprintf("Boomer is connected to master(%s:%d) press Ctrl+c to quit.", *masterHost, *masterPort)
+	} else if *rpc == "grpc" {
+		client = newGrpcClient(*masterHost, *masterPort)
+		message = fmt.Sprintf("Boomer is connected to master(%s:%d) with grpc, press Ctrl+c to quit.", *masterHost, *masterPort)
 	} else {
 		log.Fatal("Unknown rpc type:", *rpc)
 	}
@@ -31,21 +35,55 @@ func newClient() client {
 	return client
 }
 
+// newCzmqSock connects a socket of sockType to endpoint, with the security mechanism of --zmq-security.
+func newCzmqSock(sockType int, endpoint string) (*goczmq.Sock, error) {
+	sock := goczmq.NewSock(sockType)
+	switch *zmqSecurity {
+	case zmqSecurityPlain:
+		sock.SetPlainUsername(*zmqPlainUsername)
+		sock.SetPlainPassword(*zmqPlainPassword)
+	case zmqSecurityCurve:
+		var cert *goczmq.Cert
+		if *zmqCurveCertFile != "" {
+			var err error
+			cert, err = goczmq.NewCertFromFile(*zmqCurveCertFile)
+			if err != nil {
+				sock.Destroy()
+				return nil, err
+			}
+		} else {
+			cert = goczmq.NewCert()
+		}
+		cert.Apply(sock)
+		cert.Destroy()
+		sock.SetCurveServerkey(*zmqCurveServerKey)
+	}
+	if err := sock.Connect(endpoint); err != nil {
+		sock.Destroy()
+		return nil, err
+	}
+	return sock, nil
+}
+
 func newZmqClient(masterHost string, masterPort int) *czmqSocketClient {
+	if err := validateZmqSecurity(); err != nil {
+		log.Fatal(err)
+	}
 	tcpAddr := fmt.Sprintf("tcp://%s:%d", masterHost, masterPort)
-	pushConn, err := goczmq.NewPush(tcpAddr)
+	pushConn, err := newCzmqSock(goczmq.Push, tcpAddr)
 	if err != nil {
 		log.Fatalf("Failed to create zeromq pusher, %s", err)
 	}
-	tcpAddr = fmt.Sprintf(">tcp://%s:%d", masterHost, masterPort+1)
-	pullConn, err := goczmq.NewPull(tcpAddr)
+	tcpAddr = fmt.Sprintf("tcp://%s:%d", masterHost, masterPort+1)
+	pullConn, err := newCzmqSock(goczmq.Pull, tcpAddr)
 	if err != nil {
 		log.Fatalf("Failed to create zeromq puller, %s", err)
 	}
 	log.Println("ZMQ sockets connected")
 	newClient := &czmqSocketClient{
-		pushConn: pushConn,
-		pullConn: pullConn,
+		pushConn:     pushConn,
+		pullConn:     pullConn,
+		closeChannel: make(chan bool),
 	}
 	go newClient.recv()
 	go newClient.send()
@@ -54,8 +92,21 @@ func newZmqClient(masterHost string, masterPort int) *czmqSocketClient {
 
 func (c *czmqSocketClient) recv() {
 	for {
-		msg, _, _ := c.pullConn.RecvFrame()
-		msgFromMaster := newMessageFromBytes(msg)
+		msg, _, err := c.pullConn.RecvFrame()
+		if err != nil {
+			select {
+			case <-c.closeChannel:
+				return
+			default:
+			}
+			log.Printf("Error reading: %v\n", err)
+			continue
+		}
+		msgFromMaster, err := newMessageFromBytes(msg)
+		if err != nil {
+			log.Println("Invalid message from master:", err)
+			continue
+		}
 		fromMaster <- msgFromMaster
 	}
 
@@ -67,12 +118,28 @@ func (c *czmqSocketClient) send() {
 		case msg := <-toMaster:
 			c.sendMessage(msg)
 			if msg.Type == "quit" {
-				disconnectedFromMaster <- true
+				notifyDisconnected()
 			}
+		case <-c.closeChannel:
+			return
 		}
 	}
 }
 
+func (c *czmqSocketClient) close() {
+	close(c.closeChannel)
+	c.pushConn.Destroy()
+	c.pullConn.Destroy()
+}
+
 func (c *czmqSocketClient) sendMessage(msg *message) {
-	c.pushConn.SendFrame(msg.serialize(), 0)
+	packed, err := msg.serialize()
+	if err != nil {
+		log.Printf("Error sending: %v\n", err)
+		return
+	}
+	err = c.pushConn.SendFrame(packed, 0)
+	if err != nil {
+		log.Printf("Error sending: %v\n", err)
+	}
 }
@@ -13,8 +13,9 @@ import (
 )
 
 type gomqSocketClient struct {
-	pushSocket *gomq.Socket
-	pullSocket *gomq.Socket
+	pushSocket   *gomq.Socket
+	pullSocket   *gomq.Socket
+	closeChannel chan bool
 }
 
 func newClient() client {
@@ -27,6 +28,9 @@ func newClient() client {
 	} else if *rpc == "socket" {
 		client = newSocketClient(*masterHost, *masterPort)
 		message = fmt.Sprintf("Boomer is connected to master(%s:%d) press Ctrl+c to quit.", *masterHost, *masterPort)
+	} else if *rpc == "grpc" {
+		client = newGrpcClient(*masterHost, *masterPort)
+		message = fmt.Sprintf("Boomer is connected to master(%s:%d) with grpc, press Ctrl+c to quit.", *masterHost, *masterPort)
 	} else {
 		log.Fatal("Unknown rpc type:", *rpc)
 	}
@@ -61,6 +65,14 @@ func connectSock(socket *gomq.Socket, addr string) {
 }
 
 func newZmqClient(masterHost string, masterPort int) *gomqSocketClient {
+	if err := validateZmqSecurity(); err != nil {
+		log.Fatal(err)
+	}
+	if *zmqSecurity != zmqSecurityNull {
+		// gomq only implements the NULL mechanism
+		log.Fatalf("%s security of zeromq needs boomer built with goczmq, like \"go build -tags goczmq\"", *zmqSecurity)
+	}
+
 	pushAddr := fmt.Sprintf("tcp://%s:%d", masterHost, masterPort)
 	pullAddr := fmt.Sprintf("tcp://%s:%d", masterHost, masterPort+1)
 
@@ -73,8 +85,9 @@ func newZmqClient(masterHost string, masterPort int) *gomqSocketClient {
 	log.Println("ZMQ sockets connected")
 
 	newClient := &gomqSocketClient{
-		pushSocket: pushSocket,
-		pullSocket: pullSocket,
+		pushSocket:   pushSocket,
+		pullSocket:   pullSocket,
+		closeChannel: make(chan bool),
 	}
 	go newClient.recv()
 	go newClient.send()
@@ -85,9 +98,18 @@ func (c *gomqSocketClient) recv() {
 	for {
 		msg, err := c.pullSocket.Recv()
 		if err != nil {
+			select {
+			case <-c.closeChannel:
+				return
+			default:
+			}
 			log.Printf("Error reading: %v\n", err)
 		} else {
-			msgFromMaster := newMessageFromBytes(msg)
+			msgFromMaster, err := newMessageFromBytes(msg)
+			if err != nil {
+				log.Println("Invalid message from master:", err)
+				continue
+			}
 			fromMaster <- msgFromMaster
 		}
 	}
@@ -100,14 +122,27 @@ func (c *gomqSocketClient) send() {
 		case msg := <-toMaster:
 			c.sendMessage(msg)
 			if msg.Type == "quit" {
-				disconnectedFromMaster <- true
+				notifyDisconnected()
 			}
+		case <-c.closeChannel:
+			return
 		}
 	}
 }
 
+func (c *gomqSocketClient) close() {
+	close(c.closeChannel)
+	c.pushSocket.Close()
+	c.pullSocket.Close()
+}
+
 func (c *gomqSocketClient) sendMessage(msg *message) {
-	err := c.pushSocket.Send(msg.serialize())
+	packed, err := msg.serialize()
+	if err != nil {
+		log.Printf("Error sending: %v\n", err)
+		return
+	}
+	err = c.pushSocket.Send(packed)
 	if err != nil {
 		log.Printf("Error sending: %v\n", err)
 	}
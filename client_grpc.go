@@ -0,0 +1,102 @@
+package boomer
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/myzhan/boomer/boomerpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+type grpcClient struct {
+	conn         *grpc.ClientConn
+	stream       boomerpb.Master_ConnectClient
+	cancel       context.CancelFunc
+	closeChannel chan bool
+}
+
+func newGrpcClient(masterHost string, masterPort int) *grpcClient {
+	serverAddr := fmt.Sprintf("%s:%d", masterHost, masterPort)
+	tlsConfig, err := getClientTLSConfig()
+	if err != nil {
+		log.Fatalf("Invalid TLS config, %s", err)
+	}
+	creds := insecure.NewCredentials()
+	if tlsConfig != nil {
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
+	conn, err := grpc.NewClient(serverAddr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		log.Fatalf("Failed to connect to the master: %s %s", serverAddr, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := boomerpb.NewMasterClient(conn).Connect(ctx)
+	if err != nil {
+		log.Fatalf("Failed to connect to the master: %s %s", serverAddr, err)
+	}
+
+	newClient := &grpcClient{
+		conn:         conn,
+		stream:       stream,
+		cancel:       cancel,
+		closeChannel: make(chan bool),
+	}
+	go newClient.recv()
+	go newClient.send()
+	return newClient
+}
+
+func (c *grpcClient) recv() {
+	for {
+		pb, err := c.stream.Recv()
+		if err != nil {
+			select {
+			case <-c.closeChannel:
+			default:
+				notifyMasterLost(err)
+			}
+			return
+		}
+		msgFromMaster, err := newMessageFromPB(pb)
+		if err != nil {
+			log.Println("Invalid message from master:", err)
+			continue
+		}
+		fromMaster <- msgFromMaster
+	}
+}
+
+func (c *grpcClient) send() {
+	for {
+		select {
+		case msg := <-toMaster:
+			c.sendMessage(msg)
+			if msg.Type == "quit" {
+				notifyDisconnected()
+			}
+		case <-c.closeChannel:
+			return
+		}
+	}
+}
+
+func (c *grpcClient) close() {
+	close(c.closeChannel)
+	c.cancel()
+	c.conn.Close()
+}
+
+func (c *grpcClient) sendMessage(msg *message) {
+	pb, err := newPBMessage(msg)
+	if err != nil {
+		log.Printf("Error sending: %v\n", err)
+		return
+	}
+	if err := c.stream.Send(pb); err != nil {
+		log.Printf("Error sending: %v\n", err)
+	}
+}
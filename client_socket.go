@@ -2,50 +2,81 @@ package boomer
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"log"
 	"net"
 )
 
 type socketClient struct {
-	conn *net.TCPConn
+	conn         net.Conn
+	closeChannel chan bool
 }
 
 func newSocketClient(masterHost string, masterPort int) *socketClient {
 	serverAddr := fmt.Sprintf("%s:%d", masterHost, masterPort)
-	tcpAddr, _ := net.ResolveTCPAddr("tcp", serverAddr)
-	conn, err := net.DialTCP("tcp", nil, tcpAddr)
+	tlsConfig, err := getClientTLSConfig()
+	if err != nil {
+		log.Fatalf("Invalid TLS config, %s", err)
+	}
+	conn, err := dialMaster(serverAddr, tlsConfig)
 	if err != nil {
 		log.Fatalf("Failed to connect to the Locust master: %s %s", serverAddr, err)
 	}
-	conn.SetNoDelay(true)
 	newClient := &socketClient{
-		conn: conn,
+		conn:         conn,
+		closeChannel: make(chan bool),
 	}
 	go newClient.recv()
 	go newClient.send()
 	return newClient
 }
 
-func (c *socketClient) recvBytes(length int) []byte {
-	buf := make([]byte, length)
-	for length > 0 {
-		n, err := c.conn.Read(buf)
-		if err != nil {
-			log.Fatal(err)
-		}
-		length = length - n
+// dialMaster connects to the master, with TLS if tlsConfig is not nil.
+func dialMaster(serverAddr string, tlsConfig *tls.Config) (net.Conn, error) {
+	tcpAddr, err := net.ResolveTCPAddr("tcp", serverAddr)
+	if err != nil {
+		return nil, err
 	}
-	return buf
+	conn, err := net.DialTCP("tcp", nil, tcpAddr)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetNoDelay(true)
+	if tlsConfig == nil {
+		return conn, nil
+	}
+
+	if tlsConfig.ServerName == "" && !tlsConfig.InsecureSkipVerify {
+		// verify the master by the host that we dial, like tls.Dial
+		tlsConfig = tlsConfig.Clone()
+		tlsConfig.ServerName, _, _ = net.SplitHostPort(serverAddr)
+	}
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
 }
 
 func (c *socketClient) recv() {
 	for {
-		h := c.recvBytes(4)
-		msgLength := binary.BigEndian.Uint32(h)
-		msg := c.recvBytes(int(msgLength))
-		msgFromMaster := newMessageFromBytes(msg)
+		msgFromMaster, err := readFrame(c.conn)
+		if isInvalidMessage(err) {
+			log.Println("Invalid message from master:", err)
+			continue
+		}
+		if err != nil {
+			select {
+			case <-c.closeChannel:
+			default:
+				notifyMasterLost(err)
+			}
+			return
+		}
 		fromMaster <- msgFromMaster
 	}
 
@@ -57,14 +88,51 @@ func (c *socketClient) send() {
 		case msg := <-toMaster:
 			c.sendMessage(msg)
 			if msg.Type == "quit" {
-				disconnectedFromMaster <- true
+				notifyDisconnected()
 			}
+		case <-c.closeChannel:
+			return
 		}
 	}
 }
 
+func (c *socketClient) close() {
+	close(c.closeChannel)
+	c.conn.Close()
+}
+
 func (c *socketClient) sendMessage(msg *message) {
-	packed := msg.serialize()
+	err := writeFrame(c.conn, msg)
+	if err != nil {
+		log.Printf("Error sending: %v\n", err)
+	}
+}
+
+// maxFrameSize keeps a corrupted stream, or anyone connecting to the master, from allocating too much memory.
+const maxFrameSize = 64 << 20
+
+// readFrame reads a message sent by writeFrame.
+func readFrame(r io.Reader) (*message, error) {
+	h := make([]byte, 4)
+	if _, err := io.ReadFull(r, h); err != nil {
+		return nil, err
+	}
+	msgLength := binary.BigEndian.Uint32(h)
+	if msgLength > maxFrameSize {
+		return nil, fmt.Errorf("frame of %d bytes is larger than %d bytes", msgLength, maxFrameSize)
+	}
+	msg := make([]byte, msgLength)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return newMessageFromBytes(msg)
+}
+
+func writeFrame(w io.Writer, msg *message) error {
+	packed, err := msg.serialize()
+	if err != nil {
+		return err
+	}
 	buf := new(bytes.Buffer)
 
 	// use a fixed length header that indicates the length of the body
@@ -74,5 +142,6 @@ func (c *socketClient) sendMessage(msg *message) {
 
 	binary.Write(buf, binary.BigEndian, int32(len(packed)))
 	buf.Write(packed)
-	c.conn.Write(buf.Bytes())
+	_, err = w.Write(buf.Bytes())
+	return err
 }
@@ -0,0 +1,119 @@
+// Command boomer runs a native Go master for boomer workers, or a worker of a scenario file.
+//
+//	boomer master --master-bind-host=0.0.0.0 --master-bind-port=5557 --web-port=8089
+//
+// Workers connect to it with --rpc=socket, or --rpc=grpc if the master is started with --rpc=grpc.
+// It doesn't speak ZeroMQ, so locust workers can't connect to it.
+// The test is controlled through the HTTP API, like
+// "curl -XPOST -d locust_count=100 -d hatch_rate=10 http://127.0.0.1:8089/swarm".
+//
+//	boomer run shop.yaml --master-host=127.0.0.1 --master-port=5557 --rpc=socket
+//	boomer run shop.yaml --standalone --clients 100 --hatch-rate 10 --run-time 5m
+//
+// A scenario file in YAML or JSON declares the users and their HTTP steps, see the scenario package.
+// All the flags of boomer workers are supported.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/myzhan/boomer"
+	"github.com/myzhan/boomer/scenario"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\n", os.Args[0])
+	fmt.Fprintln(os.Stderr, "Commands:")
+	fmt.Fprintln(os.Stderr, "  master    run a master for boomer workers, see \"master -h\" for flags")
+	fmt.Fprintln(os.Stderr, "  run       run a worker of a scenario file, see \"run -h\" for flags")
+	os.Exit(2)
+}
+
+func runMaster(args []string) {
+	fs := flag.NewFlagSet("master", flag.ExitOnError)
+	bindHost := fs.String("master-bind-host", "0.0.0.0", "Interfaces that the master binds to.")
+	bindPort := fs.Int("master-bind-port", 5557, "Port that the master listens on for workers.")
+	webHost := fs.String("web-host", "", "Host to bind the HTTP API to. Defaults to all interfaces.")
+	webPort := fs.Int("web-port", 8089, "Port on which to run the HTTP API.")
+	rpc := fs.String("rpc", "socket", "How workers connect to the master, socket or grpc.")
+	tlsCertFile := fs.String("tls-cert-file", "", "Certificate of the master, workers must connect with --tls if it's set.")
+	tlsKeyFile := fs.String("tls-key-file", "", "Private key of --tls-cert-file.")
+	tlsClientCAFile := fs.String("tls-client-ca-file", "", "CA certificates to verify workers with, workers without a valid certificate are rejected if it's set.")
+	maxRPS := fs.Int64("max-rps", 0, "Cluster-wide max RPS, it's shared among the workers with users. It can be changed by max_rps of /swarm.")
+	fs.Parse(args)
+
+	master := boomer.NewMaster(*bindHost, *bindPort)
+	if err := master.SetRPC(*rpc); err != nil {
+		log.Fatalln(err)
+	}
+	if *tlsCertFile != "" {
+		tlsConfig, err := boomer.NewServerTLSConfig(*tlsCertFile, *tlsKeyFile, *tlsClientCAFile)
+		if err != nil {
+			log.Fatalln("Invalid TLS config:", err)
+		}
+		master.SetTLSConfig(tlsConfig)
+	} else if *tlsClientCAFile != "" {
+		log.Fatalln("--tls-client-ca-file needs --tls-cert-file")
+	}
+	if err := master.SetMaxRPS(*maxRPS); err != nil {
+		log.Fatalln(err)
+	}
+	if err := master.Listen(); err != nil {
+		log.Fatalln("Failed to start master:", err)
+	}
+	go func() {
+		log.Fatalln(master.Serve())
+	}()
+
+	webAddr := fmt.Sprintf("%s:%d", *webHost, *webPort)
+	log.Println("HTTP API is listening on", webAddr)
+	log.Fatalln(http.ListenAndServe(webAddr, master.Handler()))
+}
+
+func runScenario(args []string) {
+	// the scenario file comes first, or after the flags
+	var path string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		path, args = args[0], args[1:]
+	}
+	flag.CommandLine.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s run <scenario file> [flags]\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.CommandLine.Parse(args)
+	if path == "" {
+		path = flag.Arg(0)
+	}
+	if path == "" {
+		flag.CommandLine.Usage()
+		os.Exit(2)
+	}
+
+	s, err := scenario.Load(path)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err := boomer.Run(s.Tasks()...); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "master":
+		runMaster(os.Args[2:])
+	case "run":
+		runScenario(os.Args[2:])
+	default:
+		usage()
+	}
+}
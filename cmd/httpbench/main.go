@@ -0,0 +1,247 @@
+// Command httpbench is an HTTP benchmark tool like ab and wrk, built on boomer.
+//
+//	httpbench --url http://localhost/ --url 'POST http://localhost/login 3 @login.json' \
+//		--header 'Accept: application/json' --expect-status 200-299 \
+//		--standalone --clients 100 --hatch-rate 10 --run-time 1m
+//
+// URLs are "[METHOD] URL [WEIGHT] [@BODY_TEMPLATE]", bodies are text/template files with
+// functions seq, randInt, randString and now. Without --standalone, it connects to a master
+// like other boomer workers, all the flags of boomer are supported.
+//
+// Instead of URLs, it replays a HAR file or an access log of nginx or Apache with --replay:
+//
+//	httpbench --replay access.log --base-url http://staging:8080 --speed 2 \
+//		--name-rule '^/users/[^/]+=>/users/:name' --standalone --clients 100 --hatch-rate 10
+package main
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/myzhan/boomer"
+	boomerhttp "github.com/myzhan/boomer/http"
+	"github.com/myzhan/boomer/replay"
+)
+
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+var urls stringList
+var headers stringList
+var bodyTemplate string
+var contentType string
+var basicAuth string
+var bearerToken string
+var useHTTP2 bool
+var timeout time.Duration
+var disableKeepalive bool
+var disableCompression bool
+var maxIdleConnsPerHost int
+var idleConnTimeout time.Duration
+var insecure bool
+var expectStatus string
+var expectBody string
+var traceTiming bool
+var verbose bool
+var replayFile string
+var baseURL string
+var speed float64
+var nameRules stringList
+
+var client *boomerhttp.Client
+var header = make(http.Header)
+
+func send(t *target) {
+	request, err := t.newRequest()
+	if err != nil {
+		log.Printf("Invalid request of %s, %v\n", t.name(), err)
+		return
+	}
+	for k, v := range header {
+		request.Header[k] = v
+	}
+	if t.body != nil && request.Header.Get("Content-Type") == "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	if basicAuth != "" {
+		parts := strings.SplitN(basicAuth, ":", 2)
+		request.SetBasicAuth(parts[0], parts[1])
+	}
+	if bearerToken != "" {
+		request.Header.Set("Authorization", "Bearer "+bearerToken)
+	}
+
+	response, err := client.Do(request, boomerhttp.Name(t.url))
+	if verbose {
+		if response == nil {
+			log.Printf("%v\n", err)
+		} else {
+			log.Printf("%s %s, status code: %d, %v\n", t.method, t.url, response.StatusCode, err)
+			log.Println(string(response.Content))
+		}
+	}
+}
+
+func newClient() (*boomerhttp.Client, error) {
+	tr := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: insecure,
+		},
+		// with TLSClientConfig, HTTP/2 is only used if it's forced
+		ForceAttemptHTTP2:   useHTTP2,
+		MaxIdleConnsPerHost: maxIdleConnsPerHost,
+		IdleConnTimeout:     idleConnTimeout,
+		DisableCompression:  disableCompression,
+		DisableKeepAlives:   disableKeepalive,
+	}
+	c := boomerhttp.NewClient(&http.Client{
+		Transport: tr,
+		Timeout:   timeout,
+	})
+	c.TagStatus = true
+	c.TraceTiming = traceTiming
+
+	if expectStatus != "" {
+		check, err := boomerhttp.ParseStatus(expectStatus)
+		if err != nil {
+			return nil, err
+		}
+		c.Checks = []boomerhttp.Check{check}
+	}
+	if expectBody != "" {
+		c.Checks = append(c.Checks, boomerhttp.BodyContains(expectBody))
+	}
+	return c, nil
+}
+
+func main() {
+	flag.Var(&urls, "url", "URL to test, \"[METHOD] URL [WEIGHT] [@BODY_TEMPLATE]\", it can be repeated.")
+	flag.Var(&headers, "header", "Header of requests, like \"Accept: application/json\", it can be repeated.")
+	flag.StringVar(&bodyTemplate, "body-template", "", "Template file of request bodies, for URLs without their own.")
+	flag.StringVar(&contentType, "content-type", "text/plain", "Content-Type of requests with bodies, unless it's set by --header.")
+	flag.StringVar(&basicAuth, "basic-auth", "", "Username and password of basic auth, like user:password. The password is empty without a colon.")
+	flag.StringVar(&bearerToken, "bearer-token", "", "Bearer token sent in the Authorization header.")
+	flag.BoolVar(&useHTTP2, "http2", false, "Use HTTP/2 if the server supports it, only for https.")
+	flag.DurationVar(&timeout, "timeout", 10*time.Second, "Timeout of each request.")
+	flag.BoolVar(&disableKeepalive, "disable-keepalive", false, "Disable keepalive.")
+	flag.BoolVar(&disableCompression, "disable-compression", false, "Disable compression.")
+	flag.IntVar(&maxIdleConnsPerHost, "max-idle-conns-per-host", 2000, "Max idle connections kept alive per host.")
+	flag.DurationVar(&idleConnTimeout, "idle-conn-timeout", 90*time.Second, "How long idle connections are kept alive.")
+	flag.BoolVar(&insecure, "insecure", false, "Skip verifying certificates of https servers.")
+	flag.StringVar(&expectStatus, "expect-status", "", "Expected status codes, like 200-299,304. Status codes below 400 by default.")
+	flag.StringVar(&expectBody, "expect-body", "", "Responses should contain it.")
+	flag.BoolVar(&traceTiming, "trace-timing", false, "Record DNS lookup, TCP connect, TLS handshake, time to first byte and content transfer.")
+	flag.BoolVar(&verbose, "verbose", false, "Print responses.")
+	flag.StringVar(&replayFile, "replay", "", "HAR file or access log of nginx or Apache to replay, instead of --url.")
+	flag.StringVar(&baseURL, "base-url", "", "Scheme and host of replayed requests, like http://staging:8080, it's required by access logs.")
+	flag.Float64Var(&speed, "speed", 1, "Speed of replaying, 1 keeps the recorded timing, 2 is twice as fast, 0 is as fast as possible.")
+	flag.Var(&nameRules, "name-rule", "Regex rule to group replayed URLs, like '^/users/[^/]+=>/users/:name'. Can be repeated.")
+	flag.Parse()
+
+	if len(urls) == 0 && replayFile == "" {
+		log.Fatalln("--url can't be empty, please specify URLs that you want to test.")
+	}
+	for _, h := range headers {
+		parts := strings.SplitN(h, ":", 2)
+		if len(parts) != 2 {
+			log.Fatalf("Invalid header %q, it should be like \"Accept: application/json\"\n", h)
+		}
+		header.Add(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+	}
+	if basicAuth != "" && !strings.Contains(basicAuth, ":") {
+		// the password is empty, like "user:"
+		basicAuth += ":"
+	}
+
+	var err error
+	if client, err = newClient(); err != nil {
+		log.Fatalln(err)
+	}
+
+	// report status codes, like "http://localhost/ (status=200)"
+	boomer.SetNameTags("status")
+
+	if replayFile != "" {
+		runReplay()
+		return
+	}
+
+	var defaultBody *template.Template
+	if bodyTemplate != "" {
+		var err error
+		if defaultBody, err = parseBodyTemplate(bodyTemplate); err != nil {
+			log.Fatalln("Invalid body template:", err)
+		}
+	}
+
+	var tasks []*boomer.Task
+	for _, spec := range urls {
+		t, err := parseTarget(spec, defaultBody)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		tasks = append(tasks, &boomer.Task{
+			Name:   t.name(),
+			Weight: t.weight,
+			Fn: func() {
+				send(t)
+			},
+		})
+		log.Printf("Testing %s, weight %d\n", t.name(), t.weight)
+	}
+
+	if err := boomer.Run(tasks...); err != nil {
+		log.Fatalln(err)
+	}
+	fmt.Println("Done")
+}
+
+func runReplay() {
+	entries, err := replay.Load(replayFile, baseURL)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	replayer := replay.NewReplayer(entries)
+	replayer.Client = client
+	replayer.Speed = speed
+	replayer.Header = header
+	if basicAuth != "" {
+		replayer.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(basicAuth)))
+	}
+	if bearerToken != "" {
+		replayer.Header.Set("Authorization", "Bearer "+bearerToken)
+	}
+	for _, spec := range nameRules {
+		parts := strings.SplitN(spec, "=>", 2)
+		if len(parts) != 2 {
+			log.Fatalf("Name rule should be like pattern=>replacement, not %q\n", spec)
+		}
+		rule, err := replay.NewRule(parts[0], parts[1])
+		if err != nil {
+			log.Fatalln("Invalid name rule:", err)
+		}
+		replayer.Rules = append(replayer.Rules, rule)
+	}
+	log.Printf("Replaying %d requests of %s at the speed %v\n", len(entries), replayFile, speed)
+
+	if err := boomer.Run(replayer.Task()); err != nil {
+		log.Fatalln(err)
+	}
+	fmt.Println("Done")
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+
+	boomerhttp "github.com/myzhan/boomer/http"
+)
+
+// target is a URL to test, it's parsed from "[METHOD] URL [WEIGHT] [@BODY_TEMPLATE]".
+type target struct {
+	method string
+	url    string
+	weight int
+	body   *template.Template
+}
+
+var methods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+func parseTarget(spec string, defaultBody *template.Template) (*target, error) {
+	fields := strings.Fields(spec)
+	t := &target{
+		method: http.MethodGet,
+		weight: 1,
+		body:   defaultBody,
+	}
+	if len(fields) > 0 && methods[strings.ToUpper(fields[0])] {
+		t.method = strings.ToUpper(fields[0])
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no URL in %q", spec)
+	}
+	t.url = fields[0]
+	if !strings.HasPrefix(t.url, "http://") && !strings.HasPrefix(t.url, "https://") {
+		return nil, fmt.Errorf("URL should start with http:// or https://, not %q", t.url)
+	}
+
+	for _, field := range fields[1:] {
+		if strings.HasPrefix(field, "@") {
+			body, err := parseBodyTemplate(field[1:])
+			if err != nil {
+				return nil, err
+			}
+			t.body = body
+			continue
+		}
+		weight, err := strconv.Atoi(field)
+		if err != nil || weight <= 0 {
+			return nil, fmt.Errorf("invalid weight %q in %q", field, spec)
+		}
+		t.weight = weight
+	}
+	return t, nil
+}
+
+func (t *target) name() string {
+	return t.method + " " + t.url
+}
+
+func (t *target) newRequest() (*http.Request, error) {
+	var body io.Reader
+	if t.body != nil {
+		buf := &bytes.Buffer{}
+		if err := t.body.Execute(buf, nil); err != nil {
+			return nil, err
+		}
+		body = buf
+	}
+	return http.NewRequest(t.method, t.url, body)
+}
+
+func parseBodyTemplate(path string) (*template.Template, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return template.New(path).Funcs(boomerhttp.TemplateFuncs).Parse(string(content))
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestParseTarget(t *testing.T) {
+	dir := t.TempDir()
+	bodyFile := filepath.Join(dir, "body.json")
+	if err := ioutil.WriteFile(bodyFile, []byte(`{"id": {{seq}}, "n": {{randInt 5 5}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	target, err := parseTarget("http://localhost/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target.method != http.MethodGet || target.url != "http://localhost/" || target.weight != 1 || target.body != nil {
+		t.Error("unexpected target", target)
+	}
+
+	target, err = parseTarget("post http://localhost/login 3 @"+bodyFile, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target.method != http.MethodPost || target.weight != 3 {
+		t.Error("unexpected target", target)
+	}
+	request, err := target.newRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(request.Body)
+	if !regexp.MustCompile(`^\{"id": [1-9][0-9]*, "n": 5\}$`).Match(body) {
+		t.Error("unexpected body", string(body))
+	}
+
+	for _, spec := range []string{"", "GET", "localhost", "http://localhost/ 0", "http://localhost/ @" + filepath.Join(dir, "missing")} {
+		if _, err := parseTarget(spec, nil); err == nil {
+			t.Errorf("%q should be invalid", spec)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package boomer
+
+// msgpack decodes integers as int64 or uint64, strings as []byte and maps as
+// map[interface{}]interface{}, depending on who encoded them. These helpers
+// convert decoded values back to what we expect, ok is false if they can't.
+
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case uint64:
+		return int64(n), true
+	case int:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case int8:
+		return int64(n), true
+	case uint8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case float64:
+		return int64(n), true
+	case float32:
+		return int64(n), true
+	}
+	return 0, false
+}
+
+func toFloat64(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	}
+	i, ok := toInt64(v)
+	return float64(i), ok
+}
+
+func toString(v interface{}) (string, bool) {
+	switch s := v.(type) {
+	case string:
+		return s, true
+	case []byte:
+		return string(s), true
+	}
+	return "", false
+}
+
+func toMap(v interface{}) (map[string]interface{}, bool) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m, true
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			key, ok := toString(k)
+			if !ok {
+				return nil, false
+			}
+			result[key] = v
+		}
+		return result, true
+	// maps of other types are not encoded yet, like errors and custom metrics of a worker
+	case map[string]map[string]interface{}:
+		result := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			result[k] = v
+		}
+		return result, true
+	case map[string]int64:
+		result := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			result[k] = v
+		}
+		return result, true
+	case map[string]float64:
+		result := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			result[k] = v
+		}
+		return result, true
+	}
+	return nil, false
+}
+
+// toInt64Slice converts lists of integers, like ids of a rendezvous_release message.
+func toInt64Slice(v interface{}) ([]int64, bool) {
+	switch l := v.(type) {
+	case []int64:
+		return l, true
+	case []interface{}:
+		result := make([]int64, 0, len(l))
+		for _, v := range l {
+			n, ok := toInt64(v)
+			if !ok {
+				return nil, false
+			}
+			result = append(result, n)
+		}
+		return result, true
+	}
+	return nil, false
+}
+
+// toInt64Map converts maps like response_times and num_reqs_per_sec.
+func toInt64Map(v interface{}) (map[int64]int64, bool) {
+	switch m := v.(type) {
+	case map[int64]int64:
+		return m, true
+	case map[interface{}]interface{}:
+		result := make(map[int64]int64, len(m))
+		for k, v := range m {
+			key, ok := toInt64(k)
+			if !ok {
+				return nil, false
+			}
+			value, ok := toInt64(v)
+			if !ok {
+				return nil, false
+			}
+			result[key] = value
+		}
+		return result, true
+	}
+	return nil, false
+}
@@ -0,0 +1,103 @@
+package boomer
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Besides the built-in messages, the master and workers can exchange custom messages, like
+// register_message and send_message of locust. Data of custom messages must be a map, a dict in python.
+
+var builtinMessages = map[string]bool{
+	"client_ready":       true,
+	"client_stopped":     true,
+	"hatch":              true,
+	"hatching":           true,
+	"hatch_complete":     true,
+	"max_rps":            true,
+	"rendezvous":         true,
+	"rendezvous_leave":   true,
+	"rendezvous_release": true,
+	"stats":              true,
+	"stop":               true,
+	"quit":               true,
+}
+
+// MessageHandler handles custom messages from the master.
+type MessageHandler func(data map[string]interface{})
+
+var messageHandlers = make(map[string]MessageHandler)
+var messageHandlersLock sync.RWMutex
+
+// workerNodeID is the node id of the runner connected to the master, custom messages are sent with it.
+var workerNodeID string
+var workerNodeIDLock sync.RWMutex
+
+// ErrWorkerNotRunning is returned by SendToMaster if the worker isn't connected to the master.
+var ErrWorkerNotRunning = errors.New("worker is not running")
+
+func checkCustomMessage(msgType string) error {
+	if msgType == "" {
+		return errors.New("message type is empty")
+	}
+	if builtinMessages[msgType] {
+		return fmt.Errorf("%s is a built-in message", msgType)
+	}
+	return nil
+}
+
+// RegisterMessageHandler registers a handler for custom messages of msgType from the master.
+// Handlers are called one by one in the goroutine receiving messages, they should return quickly.
+func RegisterMessageHandler(msgType string, handler MessageHandler) error {
+	if err := checkCustomMessage(msgType); err != nil {
+		return err
+	}
+	messageHandlersLock.Lock()
+	defer messageHandlersLock.Unlock()
+	messageHandlers[msgType] = handler
+	return nil
+}
+
+func getMessageHandler(msgType string) MessageHandler {
+	messageHandlersLock.RLock()
+	defer messageHandlersLock.RUnlock()
+	return messageHandlers[msgType]
+}
+
+// SendToMaster sends a custom message to the master, like per-worker diagnostics.
+func SendToMaster(msgType string, data map[string]interface{}) error {
+	if err := checkCustomMessage(msgType); err != nil {
+		return err
+	}
+	return sendWorkerMessage(msgType, data)
+}
+
+// sendWorkerMessage sends a message with the node id of the runner connected to the master.
+func sendWorkerMessage(msgType string, data map[string]interface{}) error {
+	workerNodeIDLock.RLock()
+	nodeID := workerNodeID
+	workerNodeIDLock.RUnlock()
+	if nodeID == "" {
+		return ErrWorkerNotRunning
+	}
+	if !sendToMaster(newMessage(msgType, data, nodeID)) {
+		return fmt.Errorf("%s message is dropped, the transport to master is stalled", msgType)
+	}
+	return nil
+}
+
+func setWorkerNodeID(nodeID string) {
+	workerNodeIDLock.Lock()
+	defer workerNodeIDLock.Unlock()
+	workerNodeID = nodeID
+}
+
+// clearWorkerNodeID is called when the runner is closed, another runner may be started already.
+func clearWorkerNodeID(nodeID string) {
+	workerNodeIDLock.Lock()
+	defer workerNodeIDLock.Unlock()
+	if workerNodeID == nodeID {
+		workerNodeID = ""
+	}
+}
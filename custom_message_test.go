@@ -0,0 +1,60 @@
+package boomer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCustomMessages(t *testing.T) {
+	if err := RegisterMessageHandler("hatch", func(map[string]interface{}) {}); err == nil {
+		t.Error("built-in messages can't be handled")
+	}
+	if err := SendToMaster("diagnostics", nil); err != ErrWorkerNotRunning {
+		t.Error("custom messages can't be sent before the worker is running, got", err)
+	}
+
+	received := make(chan map[string]interface{}, 1)
+	if err := RegisterMessageHandler("tokens", func(data map[string]interface{}) {
+		received <- data
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		messageHandlersLock.Lock()
+		delete(messageHandlers, "tokens")
+		messageHandlersLock.Unlock()
+	}()
+
+	master, err := NewTestMaster()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer master.Close()
+	if err := master.StartWorker(&Task{Name: "foo", Weight: 1, Fn: func() {}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := master.Expect("client_ready", time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	master.Send("tokens", map[string]interface{}{"tokens": []string{"a", "b"}})
+	select {
+	case data := <-received:
+		if tokens, _ := data["tokens"].([]interface{}); len(tokens) != 2 {
+			t.Error("custom data mismatched.", data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for the custom message")
+	}
+
+	if err := SendToMaster("diagnostics", map[string]interface{}{"goroutines": 10}); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := master.Expect("diagnostics", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := toInt64(msg.Data["goroutines"]); n != 10 {
+		t.Error("custom data mismatched.", msg.Data)
+	}
+}
@@ -3,6 +3,7 @@ package boomer
 import (
 	"fmt"
 	"reflect"
+	"sync/atomic"
 
 	"github.com/asaskevich/EventBus"
 )
@@ -25,20 +26,94 @@ func convertResponseTime(origin interface{}) int64 {
 }
 
 func requestSuccessHandler(requestType string, name string, responseTime interface{}, responseLength int64) {
-	requestSuccessChannel <- &requestSuccess{
+	RecordSuccess(requestType, name, convertResponseTime(responseTime), responseLength, nil)
+}
+
+func requestFailureHandler(requestType string, name string, responseTime interface{}, exception string) {
+	RecordFailure(requestType, name, convertResponseTime(responseTime), exception, nil)
+}
+
+// Recorder receives the requests reported by RecordSuccess and RecordFailure instead of boomer,
+// to test code that reports requests, like the helper packages. See the boomertest package.
+type Recorder interface {
+	RecordSuccess(requestType string, name string, responseTime int64, responseLength int64, tags Tags)
+	RecordFailure(requestType string, name string, responseTime int64, exception string, tags Tags)
+}
+
+// recorderValue holds a Recorder, atomic.Value can't hold nil.
+type recorderValue struct {
+	Recorder
+}
+
+var recorder atomic.Value
+
+// SetRecorder sends the requests reported from now on to r, nil sends them to boomer again.
+func SetRecorder(r Recorder) {
+	recorder.Store(recorderValue{r})
+}
+
+func getRecorder() Recorder {
+	v, _ := recorder.Load().(recorderValue)
+	return v.Recorder
+}
+
+// RecordSuccess reports a successful request with tags, like publishing "request_success".
+// responseTime is in milliseconds, tags must not be modified after being recorded.
+func RecordSuccess(requestType string, name string, responseTime int64, responseLength int64, tags Tags) {
+	if r := getRecorder(); r != nil {
+		r.RecordSuccess(requestType, name, responseTime, responseLength, tags)
+		return
+	}
+	request := &requestSuccess{
 		requestType:    requestType,
 		name:           name,
-		responseTime:   convertResponseTime(responseTime),
+		responseTime:   responseTime,
 		responseLength: responseLength,
+		tags:           tags,
+	}
+	select {
+	case requestSuccessChannel <- request:
+		return
+	default:
+	}
+
+	// the queue is full
+	timeout, stop := requestOverflowTimer()
+	defer stop()
+	select {
+	case requestSuccessChannel <- request:
+	case <-timeout:
+		atomic.AddInt64(&droppedRequests, 1)
 	}
 }
 
-func requestFailureHandler(requestType string, name string, responseTime interface{}, exception string) {
-	requestFailureChannel <- &requestFailure{
+// RecordFailure reports a failed request with tags, like publishing "request_failure".
+// responseTime is in milliseconds, tags must not be modified after being recorded.
+func RecordFailure(requestType string, name string, responseTime int64, exception string, tags Tags) {
+	if r := getRecorder(); r != nil {
+		r.RecordFailure(requestType, name, responseTime, exception, tags)
+		return
+	}
+	request := &requestFailure{
 		requestType:  requestType,
 		name:         name,
-		responseTime: convertResponseTime(responseTime),
+		responseTime: responseTime,
 		error:        exception,
+		tags:         tags,
+	}
+	select {
+	case requestFailureChannel <- request:
+		return
+	default:
+	}
+
+	// the queue is full
+	timeout, stop := requestOverflowTimer()
+	defer stop()
+	select {
+	case requestFailureChannel <- request:
+	case <-timeout:
+		atomic.AddInt64(&droppedRequests, 1)
 	}
 }
 
@@ -2,6 +2,7 @@ package main
 
 import "github.com/myzhan/boomer"
 import "time"
+import "log"
 
 func foo() {
 
@@ -43,6 +44,8 @@ func main() {
 		Fn:     bar,
 	}
 
-	boomer.Run(task1, task2)
+	if err := boomer.Run(task1, task2); err != nil {
+		log.Fatal(err)
+	}
 
 }
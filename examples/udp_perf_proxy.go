@@ -4,9 +4,12 @@ import (
 	"flag"
 	"log"
 	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/myzhan/boomer"
+	"github.com/myzhan/boomer/raw"
 )
 
 //                            +------------+
@@ -26,45 +29,31 @@ import (
 // While requests from udpcopy passing through this udp server, it keeps track of qps and timeout.
 // Also, it can multi-copy the original request for more stress.
 
-// Known Issues:
-// 1. Once locust start the test, it can't be stopped by locust. You should restart locust and this udp server if you
-//    want to restart.
+// Requests are forwarded after locust starts hatching, and dropped after locust stops the test,
+// so the test can be stopped and started again without restarting locust and this udp server.
+// The number of copies and the backend timeout can be changed by a custom message from the master.
+//
+// With the native master:
+//	curl -XPOST -d type=udp_proxy -d 'data={"copies": 3, "backend_timeout": 500}' http://127.0.0.1:8089/message
+// With locust, in the locustfile:
+//	environment.runner.send_message("udp_proxy", {"copies": 3, "backend_timeout": 500})
 
 // See also:
 // udpcopy: https://github.com/wangbin579/udpcopy
 
-func sendReq(req []byte, addr string) {
+func sendReq(req []byte) {
 
-	a, err := net.ResolveUDPAddr("udp", addr)
+	lock.RLock()
+	client, n := backend, copies
+	client.inFlight.Add(1)
+	lock.RUnlock()
+	defer client.inFlight.Done()
 
-	conn, err := net.DialUDP("udp", nil, a)
-	if err != nil {
-		boomer.Events.Publish("request_failure", "udp-dial", name, 0.0, err.Error())
-		return
-	}
-
-	conn.SetReadDeadline(time.Now().Add(backendTimeout))
-
-	for n := 0; n < *number; n++ {
-
-		startTime := boomer.Now()
-
-		_, err = conn.Write(req)
-		if err != nil {
-			boomer.Events.Publish("request_failure", "udp-write", name, 0.0, err.Error())
-			return
-		}
-
-		resp := make([]byte, *udpBufferSize)
-		respLength, err := conn.Read(resp)
-		if err != nil {
-			boomer.Events.Publish("request_failure", "udp-read", name, 0.0, err.Error())
+	// dial, deadlines and reporting are done by the client
+	for i := 0; i < n; i++ {
+		if _, err := client.Request(name, req); err != nil {
 			return
 		}
-
-		elapsed := boomer.Now() - startTime
-
-		boomer.Events.Publish("request_success", "udp-resp", name, elapsed, int64(respLength))
 	}
 
 }
@@ -90,42 +79,115 @@ func proxy() {
 			log.Printf("request size is larger than %d，please enlarge udp-buffer-size. current request is dropped.\n", *udpBufferSize)
 			continue
 		}
-		if !testStarted {
+		if atomic.LoadInt32(&forwarding) == 0 {
 			// test is not started, drop current request.
 			continue
 		}
-		go sendReq(data[:n], *backendAddr)
+		go sendReq(data[:n])
+	}
+}
+
+// backendClient counts the requests in flight, so that it's closed after they finish.
+type backendClient struct {
+	*raw.Client
+	inFlight sync.WaitGroup
+}
+
+func newBackend(timeout int) *backendClient {
+	return &backendClient{
+		Client: &raw.Client{
+			Network:    "udp",
+			Address:    *backendAddr,
+			Name:       name,
+			Timeout:    time.Duration(timeout) * time.Millisecond,
+			PoolSize:   *backendConns,
+			BufferSize: *udpBufferSize,
+		},
 	}
 }
 
-func deadend() {
+func onHatch(spawnCount, hatchRate int) {
+	atomic.StoreInt32(&forwarding, 1)
+	log.Println("Forwarding requests to", *backendAddr)
+}
+
+func onStop() {
+	atomic.StoreInt32(&forwarding, 0)
+	log.Println("Requests are dropped until the next hatch")
+}
 
-	testStarted = true
+func toInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int64:
+		return int(n), true
+	case uint64:
+		return int(n), true
+	case float64:
+		return int(n), true
+	}
+	return 0, false
+}
 
-	for {
-		time.Sleep(time.Second * 100)
+func onParams(data map[string]interface{}) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if n, ok := toInt(data["copies"]); ok && n > 0 {
+		copies = n
+		log.Println("Each request is copied", n, "times")
+	}
+	if timeout, ok := toInt(data["backend_timeout"]); ok && timeout > 0 {
+		// requests in flight finish with the old client, it's closed after them
+		old := backend
+		backend = newBackend(timeout)
+		go func() {
+			old.inFlight.Wait()
+			old.Close()
+		}()
+		log.Printf("Backend timeout is %dms\n", timeout)
 	}
 }
 
+// idle is the task of users, they do nothing, the proxy follows hatch and stop of the test.
+func idle() {
+	time.Sleep(time.Second)
+}
+
 func main() {
 
 	task := &boomer.Task{
 		Name:   "udproxy",
 		Weight: 10,
-		Fn:     deadend,
+		Fn:     idle,
 	}
 
+	backend = newBackend(*backendTimeout)
+	copies = *number
+
+	boomer.Events.Subscribe("boomer:hatch", onHatch)
+	boomer.Events.Subscribe("boomer:stop", onStop)
+	boomer.Events.Subscribe("boomer:quit", onStop)
+	boomer.RegisterMessageHandler("udp_proxy", onParams)
+
 	go proxy()
 
-	boomer.Run(task)
+	if err := boomer.Run(task); err != nil {
+		log.Fatal(err)
+	}
 }
 
 const name = "udproxy"
 
-var testStarted = false
+var forwarding int32
+
+// lock protects backend and copies, they can be changed by the master.
+var lock sync.RWMutex
+var backend *backendClient
+var copies int
 
 var backendAddr *string
-var backendTimeout time.Duration
+var backendTimeout *int
+var backendConns *int
 var proxyHost *string
 var proxyPort *int
 var udpBufferSize *int
@@ -134,12 +196,12 @@ var number *int
 func init() {
 
 	backendAddr = flag.String("backend-addr", "127.0.0.1:44444", "backend address")
-	timeout := flag.Int("backend-timeout", 1000, "backend timeout(ms)")
-	backendTimeout = time.Duration(*timeout) * time.Millisecond
+	backendTimeout = flag.Int("backend-timeout", 1000, "backend timeout(ms), it can be changed by the master")
+	backendConns = flag.Int("backend-conns", 100, "max number of udp sockets to the backend")
 	proxyHost = flag.String("proxy-host", "0.0.0.0", "proxy bind-host")
 	proxyPort = flag.Int("proxy-port", 23333, "proxy bind-port")
 	udpBufferSize = flag.Int("udp-buffer-size", 10240, "udp recv buffer size")
-	number = flag.Int("number", 1, "the number of replication for multi-copying")
+	number = flag.Int("number", 1, "the number of replication for multi-copying, it can be changed by the master")
 	flag.Parse()
 
 }
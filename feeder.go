@@ -0,0 +1,302 @@
+package boomer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+)
+
+// Strategy decides which record is drawn from a feeder.
+type Strategy int
+
+const (
+	// Sequential draws the records in order, ErrFeederExhausted is returned after the last one.
+	Sequential Strategy = iota
+	// Circular draws the records in order, and starts over after the last one.
+	Circular
+	// Random draws a random record each time, a record may be drawn repeatedly.
+	Random
+	// UniquePerUser draws a record held by no other user, until it's released by Release.
+	// ErrFeederExhausted is returned if all the records are held. It's meant for credentials,
+	// drawn in OnStart of a user and released in OnStop.
+	UniquePerUser
+)
+
+// ErrFeederExhausted is returned by Next if there are no more records to draw.
+var ErrFeederExhausted = errors.New("feeder is exhausted")
+
+// Record is a row of a CSV file, or a line of a JSON-lines file.
+type Record struct {
+	index  int
+	fields map[string]interface{}
+}
+
+// Get returns the value of the field, nil if it's absent.
+// Values of CSV are strings, numbers of JSON lines are json.Number.
+func (r *Record) Get(field string) interface{} {
+	return r.fields[field]
+}
+
+// String returns the value of the field as a string, "" if it's absent.
+func (r *Record) String(field string) string {
+	switch value := r.fields[field].(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// Fields returns a copy of the fields of the record.
+func (r *Record) Fields() map[string]interface{} {
+	fields := make(map[string]interface{}, len(r.fields))
+	for k, v := range r.fields {
+		fields[k] = v
+	}
+	return fields
+}
+
+// FeederOption configures a feeder.
+type FeederOption func(*Feeder)
+
+// PartitionByWorker draws records only from the share of the worker, so workers never draw the same record.
+// The worker with index i of n workers draws the records at i, i+n, i+2n... Workers learn their index
+// from boomer's master when they hatch, the records are not partitioned with locust or standalone.
+// When workers join or leave, the records are partitioned again, and the feeder starts over.
+func PartitionByWorker() FeederOption {
+	return func(f *Feeder) {
+		f.partitioned = true
+	}
+}
+
+// Feeder draws test data for users, like credentials or product IDs. It's safe to be shared by users.
+type Feeder struct {
+	records     []*Record
+	strategy    Strategy
+	partitioned bool
+
+	lock sync.Mutex
+	rand *rand.Rand
+	// share is the indexes of the records in the partition of the worker
+	share        []int
+	shareIndex   int
+	shareCount   int
+	shareCreated bool
+	next         int
+	// free and held are the indexes of records, for UniquePerUser
+	free []int
+	held map[int]bool
+}
+
+// NewFeeder creates a feeder of the records, like what a JSON-lines file is decoded into.
+func NewFeeder(records []map[string]interface{}, strategy Strategy, options ...FeederOption) *Feeder {
+	f := &Feeder{
+		records:  make([]*Record, len(records)),
+		strategy: strategy,
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		held:     make(map[int]bool),
+	}
+	for i, fields := range records {
+		f.records[i] = &Record{index: i, fields: fields}
+	}
+	for _, option := range options {
+		option(f)
+	}
+	return f
+}
+
+// NewCSVFeeder creates a feeder of a CSV file, the first row is the header with the names of the fields.
+func NewCSVFeeder(path string, strategy Strategy, options ...FeederOption) (*Feeder, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	records, err := readCSV(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return NewFeeder(records, strategy, options...), nil
+}
+
+func readCSV(r io.Reader) ([]map[string]interface{}, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("no header")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var records []map[string]interface{}
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		record := make(map[string]interface{}, len(header))
+		for i, value := range row {
+			record[header[i]] = value
+		}
+		records = append(records, record)
+	}
+}
+
+// NewJSONLinesFeeder creates a feeder of a JSON-lines file, each line is an object, blank lines are skipped.
+func NewJSONLinesFeeder(path string, strategy Strategy, options ...FeederOption) (*Feeder, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	records, err := readJSONLines(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return NewFeeder(records, strategy, options...), nil
+}
+
+func readJSONLines(r io.Reader) ([]map[string]interface{}, error) {
+	reader := bufio.NewReader(r)
+	var records []map[string]interface{}
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			decoder := json.NewDecoder(bytes.NewReader(line))
+			decoder.UseNumber()
+			var record map[string]interface{}
+			if decodeErr := decoder.Decode(&record); decodeErr != nil || record == nil {
+				return nil, fmt.Errorf("line %d is not a JSON object", lineNumber)
+			}
+			records = append(records, record)
+		}
+		if err == io.EOF {
+			return records, nil
+		}
+	}
+}
+
+// updateShare partitions the records again if the partition of the worker is changed.
+func (f *Feeder) updateShare() {
+	index, count := 0, 0
+	if f.partitioned {
+		index, count = getWorkerPartition()
+	}
+	if f.shareCreated && index == f.shareIndex && count == f.shareCount {
+		return
+	}
+
+	f.share = f.share[:0]
+	for i := range f.records {
+		if count == 0 || i%count == index {
+			f.share = append(f.share, i)
+		}
+	}
+	if f.shareCreated {
+		log.Printf("Feeder is partitioned again as worker %d of %d, %d of %d records are drawn\n",
+			index, count, len(f.share), len(f.records))
+	}
+	f.shareIndex, f.shareCount, f.shareCreated = index, count, true
+	f.next = 0
+	// records held from the previous share are not drawn again until released
+	f.free = f.free[:0]
+	for _, i := range f.share {
+		if !f.held[i] {
+			f.free = append(f.free, i)
+		}
+	}
+}
+
+// Next draws a record by the strategy of the feeder.
+func (f *Feeder) Next() (*Record, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.updateShare()
+	if len(f.share) == 0 {
+		return nil, ErrFeederExhausted
+	}
+
+	switch f.strategy {
+	case Circular:
+		i := f.share[f.next%len(f.share)]
+		f.next = (f.next + 1) % len(f.share)
+		return f.records[i], nil
+	case Random:
+		return f.records[f.share[f.rand.Intn(len(f.share))]], nil
+	case UniquePerUser:
+		if len(f.free) == 0 {
+			return nil, ErrFeederExhausted
+		}
+		i := f.free[0]
+		f.free = f.free[1:]
+		f.held[i] = true
+		return f.records[i], nil
+	default:
+		if f.next >= len(f.share) {
+			return nil, ErrFeederExhausted
+		}
+		i := f.share[f.next]
+		f.next++
+		return f.records[i], nil
+	}
+}
+
+// Release returns a record drawn by UniquePerUser, so it can be drawn by another user.
+// It does nothing with other strategies.
+func (f *Feeder) Release(record *Record) {
+	if record == nil || f.strategy != UniquePerUser {
+		return
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.updateShare()
+	if !f.held[record.index] {
+		return
+	}
+	delete(f.held, record.index)
+	if f.shareCount == 0 || record.index%f.shareCount == f.shareIndex {
+		f.free = append(f.free, record.index)
+	}
+}
+
+// workerPartition is set by the hatch messages from boomer's master, count is 0 if it's unknown.
+var workerPartition struct {
+	sync.RWMutex
+	index int
+	count int
+}
+
+func setWorkerPartition(index, count int) {
+	workerPartition.Lock()
+	defer workerPartition.Unlock()
+	workerPartition.index = index
+	workerPartition.count = count
+}
+
+func getWorkerPartition() (index, count int) {
+	workerPartition.RLock()
+	defer workerPartition.RUnlock()
+	return workerPartition.index, workerPartition.count
+}
@@ -0,0 +1,208 @@
+package boomer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newTestFeeder(n int, strategy Strategy, options ...FeederOption) *Feeder {
+	records := make([]map[string]interface{}, n)
+	for i := range records {
+		records[i] = map[string]interface{}{"id": fmt.Sprint(i)}
+	}
+	return NewFeeder(records, strategy, options...)
+}
+
+// draw draws n records, or until the feeder is exhausted.
+func draw(t *testing.T, f *Feeder, n int) []string {
+	var ids []string
+	for i := 0; i < n; i++ {
+		record, err := f.Next()
+		if err == ErrFeederExhausted {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, record.String("id"))
+	}
+	return ids
+}
+
+func TestCSVFeeder(t *testing.T) {
+	path := writeTestFile(t, "users.csv", "username,password\nalice,\"p,1\"\nbob,p2\n")
+	f, err := NewCSVFeeder(path, Sequential)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, _ := f.Next()
+	if record.String("username") != "alice" || record.String("password") != "p,1" || record.Get("email") != nil {
+		t.Error("first record mismatched", record.fields)
+	}
+	record, _ = f.Next()
+	if record.String("username") != "bob" {
+		t.Error("second record mismatched", record.fields)
+	}
+	if _, err := f.Next(); err != ErrFeederExhausted {
+		t.Error("sequential feeder should be exhausted, got", err)
+	}
+
+	for _, content := range []string{"", "a,b\n1,2,3\n"} {
+		if _, err := NewCSVFeeder(writeTestFile(t, "invalid.csv", content), Sequential); err == nil {
+			t.Errorf("%q should be invalid", content)
+		}
+	}
+	if _, err := NewCSVFeeder(filepath.Join(t.TempDir(), "missing.csv"), Sequential); err == nil {
+		t.Error("missing file should fail")
+	}
+}
+
+func TestJSONLinesFeeder(t *testing.T) {
+	path := writeTestFile(t, "products.jsonl", "{\"id\": 12345678901234567890, \"tags\": [\"a\"]}\n\n{\"id\": \"x\"}")
+	f, err := NewJSONLinesFeeder(path, Circular)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := draw(t, f, 3); fmt.Sprint(ids) != "[12345678901234567890 x 12345678901234567890]" {
+		t.Error("records should be drawn circularly, got", ids)
+	}
+	record, _ := f.Next()
+	if _, ok := record.Get("id").(string); !ok {
+		t.Error("record should be the second one, got", record.fields)
+	}
+	record, _ = f.Next()
+	if _, ok := record.Get("id").(json.Number); !ok {
+		t.Error("numbers should be kept as json.Number, got", record.Get("id"))
+	}
+
+	for _, content := range []string{"[1]\n", "{\"id\": 1}\n{oops\n", "null\n"} {
+		if _, err := NewJSONLinesFeeder(writeTestFile(t, "invalid.jsonl", content), Sequential); err == nil {
+			t.Errorf("%q should be invalid", content)
+		}
+	}
+}
+
+func TestRandomFeeder(t *testing.T) {
+	f := newTestFeeder(3, Random)
+	seen := make(map[string]bool)
+	for _, id := range draw(t, f, 100) {
+		seen[id] = true
+	}
+	if len(seen) != 3 {
+		t.Error("all the records should be drawn at random, got", seen)
+	}
+	if _, err := NewFeeder(nil, Random).Next(); err != ErrFeederExhausted {
+		t.Error("empty feeder should be exhausted, got", err)
+	}
+}
+
+func TestUniquePerUserFeeder(t *testing.T) {
+	f := newTestFeeder(10, UniquePerUser)
+
+	// records are never held by two users at the same time
+	var lock sync.Mutex
+	holding := make(map[string]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				record, err := f.Next()
+				if err == ErrFeederExhausted {
+					continue
+				}
+				id := record.String("id")
+				lock.Lock()
+				if holding[id] {
+					t.Error("record is held by two users", id)
+				}
+				holding[id] = true
+				lock.Unlock()
+
+				lock.Lock()
+				delete(holding, id)
+				lock.Unlock()
+				f.Release(record)
+			}
+		}()
+	}
+	wg.Wait()
+
+	records := make([]*Record, 0, 10)
+	for i := 0; i < 10; i++ {
+		record, err := f.Next()
+		if err != nil {
+			t.Fatal("all the records should be released, got", err)
+		}
+		records = append(records, record)
+	}
+	if _, err := f.Next(); err != ErrFeederExhausted {
+		t.Error("feeder should be exhausted when all the records are held, got", err)
+	}
+	f.Release(records[3])
+	f.Release(records[3])
+	if ids := draw(t, f, 2); len(ids) != 1 || ids[0] != records[3].String("id") {
+		t.Error("released record should be drawn once, got", ids)
+	}
+}
+
+func TestPartitionByWorker(t *testing.T) {
+	setWorkerPartition(0, 0)
+	defer setWorkerPartition(0, 0)
+
+	partitioned := newTestFeeder(7, Sequential, PartitionByWorker())
+	shared := newTestFeeder(7, Sequential)
+
+	setWorkerPartition(1, 3)
+	if ids := draw(t, partitioned, 10); fmt.Sprint(ids) != "[1 4]" {
+		t.Error("records should be partitioned among 3 workers, got", ids)
+	}
+	if ids := draw(t, shared, 10); len(ids) != 7 {
+		t.Error("records should not be partitioned without PartitionByWorker, got", ids)
+	}
+
+	// the feeder starts over when workers join or leave
+	setWorkerPartition(0, 2)
+	if ids := draw(t, partitioned, 10); fmt.Sprint(ids) != "[0 2 4 6]" {
+		t.Error("records should be partitioned again among 2 workers, got", ids)
+	}
+
+	setWorkerPartition(3, 8)
+	if ids := draw(t, newTestFeeder(2, Circular, PartitionByWorker()), 1); len(ids) != 0 {
+		t.Error("share of the worker should be empty, got", ids)
+	}
+}
+
+func TestPartitionUniquePerUser(t *testing.T) {
+	setWorkerPartition(0, 2)
+	defer setWorkerPartition(0, 0)
+
+	f := newTestFeeder(4, UniquePerUser, PartitionByWorker())
+	held, _ := f.Next()
+	if ids := draw(t, f, 10); fmt.Sprint(ids) != "[2]" {
+		t.Error("records of the worker should be drawn, got", ids)
+	}
+
+	// the held record is not drawn again after partitioning, until it's released
+	setWorkerPartition(0, 1)
+	if ids := draw(t, f, 10); fmt.Sprint(ids) != "[1 3]" {
+		t.Error("records not held should be drawn, got", ids)
+	}
+	f.Release(held)
+	if ids := draw(t, f, 10); fmt.Sprint(ids) != "[0]" {
+		t.Error("released record should be drawn, got", ids)
+	}
+}
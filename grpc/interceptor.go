@@ -0,0 +1,176 @@
+// Package grpc reports gRPC calls to boomer with client interceptors, and calls methods
+// without generated stubs, with requests in JSON.
+//
+//	conn, err := gogrpc.NewClient("localhost:50051",
+//		gogrpc.WithTransportCredentials(insecure.NewCredentials()),
+//		gogrpc.WithUnaryInterceptor(grpc.UnaryClientInterceptor(grpc.TagStatus())))
+//	invoker := grpc.NewReflectionInvoker(conn)
+//	resp, err := invoker.Invoke(ctx, "helloworld.Greeter/SayHello", []byte(`{"name": "boomer"}`))
+//
+// The request type is "grpc", the name is the full method, like "/helloworld.Greeter/SayHello",
+// and calls with status codes other than OK are failures by default.
+package grpc
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/myzhan/boomer"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const requestType = "grpc"
+
+type options struct {
+	tagStatus    bool
+	successCodes map[codes.Code]bool
+}
+
+// Option changes how calls are reported.
+type Option func(*options)
+
+// TagStatus adds the status code to every call as the tag "status", like "OK" or "Unavailable".
+func TagStatus() Option {
+	return func(o *options) {
+		o.tagStatus = true
+	}
+}
+
+// SuccessCodes makes calls with these status codes successful besides OK, like codes.NotFound.
+func SuccessCodes(successCodes ...codes.Code) Option {
+	return func(o *options) {
+		for _, code := range successCodes {
+			o.successCodes[code] = true
+		}
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		successCodes: map[codes.Code]bool{codes.OK: true},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func (o *options) report(method string, start time.Time, size int64, err error) {
+	elapsed := int64(time.Since(start) / time.Millisecond)
+	s := status.Convert(err)
+	var tags boomer.Tags
+	if o.tagStatus {
+		tags = boomer.Tags{"status": s.Code().String()}
+	}
+	if o.successCodes[s.Code()] {
+		boomer.RecordSuccess(requestType, method, elapsed, size, tags)
+	} else {
+		boomer.RecordFailure(requestType, method, elapsed, s.Code().String()+": "+s.Message(), tags)
+	}
+}
+
+// ignored tells if the method isn't a call of the test, like server reflection by the invoker.
+func ignored(method string) bool {
+	return strings.HasPrefix(method, "/grpc.reflection.")
+}
+
+func messageSize(msg interface{}) int64 {
+	if m, ok := msg.(proto.Message); ok {
+		return int64(proto.Size(m))
+	}
+	return 0
+}
+
+// UnaryClientInterceptor reports unary calls, the response length is the size of the reply.
+func UnaryClientInterceptor(opts ...Option) gogrpc.UnaryClientInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *gogrpc.ClientConn,
+		invoker gogrpc.UnaryInvoker, callOpts ...gogrpc.CallOption) error {
+		if ignored(method) {
+			return invoker(ctx, method, req, reply, cc, callOpts...)
+		}
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, callOpts...)
+		var size int64
+		if err == nil {
+			size = messageSize(reply)
+		}
+		o.report(method, start, size, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor reports a stream as a call when it ends, from its creation until the last
+// message is received, the response length is the total size of the messages received.
+func StreamClientInterceptor(opts ...Option) gogrpc.StreamClientInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, desc *gogrpc.StreamDesc, cc *gogrpc.ClientConn, method string,
+		streamer gogrpc.Streamer, callOpts ...gogrpc.CallOption) (gogrpc.ClientStream, error) {
+		if ignored(method) {
+			return streamer(ctx, desc, cc, method, callOpts...)
+		}
+		start := time.Now()
+		stream, err := streamer(ctx, desc, cc, method, callOpts...)
+		if err != nil {
+			o.report(method, start, 0, err)
+			return nil, err
+		}
+		return &reportedStream{
+			ClientStream:  stream,
+			options:       o,
+			method:        method,
+			start:         start,
+			serverStreams: desc.ServerStreams,
+		}, nil
+	}
+}
+
+type reportedStream struct {
+	gogrpc.ClientStream
+	options       *options
+	method        string
+	start         time.Time
+	serverStreams bool
+	// size is updated by RecvMsg, and read by SendMsg in another goroutine
+	size int64
+	once sync.Once
+}
+
+func (s *reportedStream) finish(err error) {
+	s.once.Do(func() {
+		s.options.report(s.method, s.start, atomic.LoadInt64(&s.size), err)
+	})
+}
+
+func (s *reportedStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	// io.EOF means the stream is broken, the status is returned by RecvMsg
+	if err != nil && err != io.EOF {
+		s.finish(err)
+	}
+	return err
+}
+
+func (s *reportedStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == io.EOF:
+		s.finish(nil)
+	case err != nil:
+		s.finish(err)
+	default:
+		atomic.AddInt64(&s.size, messageSize(m))
+		// without server streaming, the stream ends after the only response
+		if !s.serverStreams {
+			s.finish(nil)
+		}
+	}
+	return err
+}
@@ -0,0 +1,112 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/myzhan/boomer/boomertest"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// newTestConn connects to an in-process server with the health service and server reflection.
+func newTestConn(t *testing.T, opts ...gogrpc.DialOption) *gogrpc.ClientConn {
+	listener := bufconn.Listen(1024 * 1024)
+	server := gogrpc.NewServer()
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	reflection.Register(server)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	opts = append(opts,
+		gogrpc.WithTransportCredentials(insecure.NewCredentials()),
+		gogrpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}))
+	conn, err := gogrpc.NewClient("passthrough:///bufnet", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	return conn
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	recorder := boomertest.Record(t)
+	conn := newTestConn(t, gogrpc.WithUnaryInterceptor(UnaryClientInterceptor(TagStatus())))
+	client := healthpb.NewHealthClient(conn)
+
+	resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "foo"}); status.Code(err) != codes.NotFound {
+		t.Fatal("unknown services should be not found, got", err)
+	}
+
+	if len(recorder.Requests()) != 2 {
+		t.Fatal("calls should be reported, got", recorder.Requests())
+	}
+	success, failure := recorder.Requests()[0], recorder.Requests()[1]
+	if !success.Success || success.Type != "grpc" || success.Name != "/grpc.health.v1.Health/Check" {
+		t.Error("unexpected success", success)
+	}
+	if success.Length != int64(proto.Size(resp)) {
+		t.Error("the size of the reply should be reported, got", success.Length)
+	}
+	if success.Tags["status"] != "OK" {
+		t.Error("status should be tagged, got", success.Tags)
+	}
+	if failure.Success || failure.Exception != "NotFound: unknown service" || failure.Tags["status"] != "NotFound" {
+		t.Error("unexpected failure", failure)
+	}
+}
+
+func TestSuccessCodes(t *testing.T) {
+	recorder := boomertest.Record(t)
+	conn := newTestConn(t, gogrpc.WithUnaryInterceptor(UnaryClientInterceptor(SuccessCodes(codes.NotFound))))
+
+	healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{Service: "foo"})
+	if len(recorder.Requests()) != 1 || !recorder.Requests()[0].Success || recorder.Requests()[0].Tags != nil {
+		t.Error("NotFound should be successful, got", recorder.Requests())
+	}
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	recorder := boomertest.Record(t)
+	conn := newTestConn(t, gogrpc.WithStreamInterceptor(StreamClientInterceptor()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := healthpb.NewHealthClient(conn).Watch(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.Requests()) != 0 {
+		t.Fatal("streams should be reported when they end, got", recorder.Requests())
+	}
+
+	cancel()
+	if _, err := stream.Recv(); status.Code(err) != codes.Canceled {
+		t.Fatal("stream should be canceled, got", err)
+	}
+	stream.Recv()
+	if len(recorder.Requests()) != 1 {
+		t.Fatal("the stream should be reported once, got", recorder.Requests())
+	}
+	if r := recorder.Requests()[0]; r.Success || r.Name != "/grpc.health.v1.Health/Watch" || r.Exception != "Canceled: context canceled" {
+		t.Error("unexpected failure", r)
+	}
+}
@@ -0,0 +1,316 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bufbuild/protocompile"
+	gogrpc "google.golang.org/grpc"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Invoker calls unary methods by their names, with requests and responses in JSON.
+// Descriptors of services are loaded from .proto files, or from the server by reflection.
+type Invoker struct {
+	conn gogrpc.ClientConnInterface
+	// load finds the file of a service from somewhere else, and returns a copy of files with it.
+	// It's nil if all the files are loaded.
+	load func(ctx context.Context, files *protoregistry.Files, service protoreflect.FullName) (*protoregistry.Files, error)
+
+	lock sync.Mutex
+	// files and types aren't changed once they are used, loading creates new ones
+	files   *protoregistry.Files
+	types   *dynamicpb.Types
+	methods map[string]*Method
+}
+
+func newInvoker(conn gogrpc.ClientConnInterface, files *protoregistry.Files) *Invoker {
+	return &Invoker{
+		conn:    conn,
+		files:   files,
+		types:   dynamicpb.NewTypes(files),
+		methods: make(map[string]*Method),
+	}
+}
+
+// NewProtoInvoker compiles .proto files, imports are searched in importPaths,
+// well-known types like google/protobuf/empty.proto are always available.
+func NewProtoInvoker(conn gogrpc.ClientConnInterface, importPaths []string, protoFiles ...string) (*Invoker, error) {
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			ImportPaths: importPaths,
+		}),
+	}
+	compiled, err := compiler.Compile(context.Background(), protoFiles...)
+	if err != nil {
+		return nil, err
+	}
+
+	files := &protoregistry.Files{}
+	for _, fd := range compiled {
+		if err := registerFile(files, fd); err != nil {
+			return nil, err
+		}
+	}
+	return newInvoker(conn, files), nil
+}
+
+// registerFile registers a file after its imports.
+func registerFile(files *protoregistry.Files, fd protoreflect.FileDescriptor) error {
+	if _, err := files.FindFileByPath(fd.Path()); err == nil {
+		return nil
+	}
+	imports := fd.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		if err := registerFile(files, imports.Get(i).FileDescriptor); err != nil {
+			return err
+		}
+	}
+	return files.RegisterFile(fd)
+}
+
+// NewReflectionInvoker loads services from the server by reflection when they are called the first time,
+// the server should register the reflection service, like reflection.Register(server).
+func NewReflectionInvoker(conn gogrpc.ClientConnInterface) *Invoker {
+	invoker := newInvoker(conn, &protoregistry.Files{})
+	invoker.load = invoker.loadByReflection
+	return invoker
+}
+
+// loadByReflection asks the server for the file of the service, and the files it depends on.
+func (i *Invoker) loadByReflection(ctx context.Context, loaded *protoregistry.Files, service protoreflect.FullName) (*protoregistry.Files, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := reflectionpb.NewServerReflectionClient(i.conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	files := &protoregistry.Files{}
+	loaded.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		err = files.RegisterFile(fd)
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r := &reflectionLoader{
+		stream: stream,
+		files:  files,
+		protos: make(map[string]*descriptorpb.FileDescriptorProto),
+	}
+	name, err := r.fetch(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{
+			FileContainingSymbol: string(service),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := r.register(name); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+type reflectionLoader struct {
+	stream reflectionpb.ServerReflection_ServerReflectionInfoClient
+	files  *protoregistry.Files
+	// protos are the files received, not registered yet
+	protos map[string]*descriptorpb.FileDescriptorProto
+}
+
+// fetch sends the request and keeps the files in the response, it returns the name of the first one,
+// which is the file requested, the rest are files it depends on.
+func (r *reflectionLoader) fetch(req *reflectionpb.ServerReflectionRequest) (string, error) {
+	if err := r.stream.Send(req); err != nil {
+		return "", err
+	}
+	resp, err := r.stream.Recv()
+	if err != nil {
+		return "", err
+	}
+	if errResp := resp.GetErrorResponse(); errResp != nil {
+		return "", fmt.Errorf("reflection error %d: %s", errResp.ErrorCode, errResp.ErrorMessage)
+	}
+	fdResp := resp.GetFileDescriptorResponse()
+	if fdResp == nil || len(fdResp.FileDescriptorProto) == 0 {
+		return "", errors.New("no file descriptor in the reflection response")
+	}
+
+	var first string
+	for _, b := range fdResp.FileDescriptorProto {
+		fd := &descriptorpb.FileDescriptorProto{}
+		if err := proto.Unmarshal(b, fd); err != nil {
+			return "", err
+		}
+		if first == "" {
+			first = fd.GetName()
+		}
+		r.protos[fd.GetName()] = fd
+	}
+	return first, nil
+}
+
+// register registers a file after its imports, files not sent by the server are requested by their names,
+// or found in the files linked into the program, like well-known types.
+func (r *reflectionLoader) register(name string) error {
+	if _, err := r.files.FindFileByPath(name); err == nil {
+		return nil
+	}
+	fd, ok := r.protos[name]
+	if !ok {
+		if linked, err := protoregistry.GlobalFiles.FindFileByPath(name); err == nil {
+			return registerFile(r.files, linked)
+		}
+		if _, err := r.fetch(&reflectionpb.ServerReflectionRequest{
+			MessageRequest: &reflectionpb.ServerReflectionRequest_FileByFilename{
+				FileByFilename: name,
+			},
+		}); err != nil {
+			return err
+		}
+		if fd, ok = r.protos[name]; !ok {
+			return fmt.Errorf("file %s isn't found by reflection", name)
+		}
+	}
+
+	for _, dep := range fd.GetDependency() {
+		if err := r.register(dep); err != nil {
+			return err
+		}
+	}
+	file, err := protodesc.NewFile(fd, r.files)
+	if err != nil {
+		return err
+	}
+	return r.files.RegisterFile(file)
+}
+
+// Method is a unary method to call.
+type Method struct {
+	// FullMethod is like "/helloworld.Greeter/SayHello", it's the name of calls reported by the interceptors.
+	FullMethod string
+	desc       protoreflect.MethodDescriptor
+	conn       gogrpc.ClientConnInterface
+	types      *dynamicpb.Types
+}
+
+// parseMethod splits "helloworld.Greeter/SayHello", "/helloworld.Greeter/SayHello"
+// or "helloworld.Greeter.SayHello" into the service and the method.
+func parseMethod(name string) (protoreflect.FullName, protoreflect.Name, error) {
+	name = strings.TrimPrefix(name, "/")
+	i := strings.LastIndexAny(name, "/.")
+	if i <= 0 || i == len(name)-1 {
+		return "", "", fmt.Errorf("invalid method %q, it should be like package.Service/Method", name)
+	}
+	return protoreflect.FullName(name[:i]), protoreflect.Name(name[i+1:]), nil
+}
+
+// Method finds a unary method, like "helloworld.Greeter/SayHello".
+// Services are loaded without holding the lock, so a slow server doesn't block methods already found.
+func (i *Invoker) Method(ctx context.Context, name string) (*Method, error) {
+	i.lock.Lock()
+	m, ok := i.methods[name]
+	files, types := i.files, i.types
+	i.lock.Unlock()
+	if ok {
+		return m, nil
+	}
+
+	serviceName, methodName, err := parseMethod(name)
+	if err != nil {
+		return nil, err
+	}
+	d, err := files.FindDescriptorByName(serviceName)
+	if err == protoregistry.NotFound && i.load != nil {
+		loaded, loadErr := i.load(ctx, files, serviceName)
+		if loadErr != nil {
+			return nil, fmt.Errorf("failed to load service %s, %v", serviceName, loadErr)
+		}
+		i.lock.Lock()
+		// if others have loaded services in the meantime, theirs are kept, the service is loaded again next time
+		if i.files == files {
+			i.files, i.types = loaded, dynamicpb.NewTypes(loaded)
+			types = i.types
+		} else {
+			types = dynamicpb.NewTypes(loaded)
+		}
+		i.lock.Unlock()
+		files = loaded
+		d, err = files.FindDescriptorByName(serviceName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("service %s isn't found, %v", serviceName, err)
+	}
+	service, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s isn't a service", serviceName)
+	}
+	desc := service.Methods().ByName(methodName)
+	if desc == nil {
+		return nil, fmt.Errorf("method %s isn't found in service %s", methodName, serviceName)
+	}
+	if desc.IsStreamingClient() || desc.IsStreamingServer() {
+		return nil, fmt.Errorf("method %s is streaming, only unary methods are supported", name)
+	}
+
+	m = &Method{
+		FullMethod: fmt.Sprintf("/%s/%s", serviceName, methodName),
+		desc:       desc,
+		conn:       i.conn,
+		types:      types,
+	}
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if found, ok := i.methods[name]; ok {
+		return found, nil
+	}
+	i.methods[name] = m
+	return m, nil
+}
+
+// NewRequest builds a request from JSON, to be sent many times.
+func (m *Method) NewRequest(request []byte) (proto.Message, error) {
+	req := dynamicpb.NewMessage(m.desc.Input())
+	if err := (protojson.UnmarshalOptions{Resolver: m.types}).Unmarshal(request, req); err != nil {
+		return nil, fmt.Errorf("invalid request of %s, %v", m.FullMethod, err)
+	}
+	return req, nil
+}
+
+// Call calls the method with a request built by NewRequest.
+func (m *Method) Call(ctx context.Context, req proto.Message, opts ...gogrpc.CallOption) (proto.Message, error) {
+	resp := dynamicpb.NewMessage(m.desc.Output())
+	if err := m.conn.Invoke(ctx, m.FullMethod, req, resp, opts...); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Invoke calls a unary method with the request in JSON, and returns the response in JSON.
+func (i *Invoker) Invoke(ctx context.Context, method string, request []byte, opts ...gogrpc.CallOption) ([]byte, error) {
+	m, err := i.Method(ctx, method)
+	if err != nil {
+		return nil, err
+	}
+	req, err := m.NewRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.Call(ctx, req, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return protojson.MarshalOptions{Resolver: m.types}.Marshal(resp)
+}
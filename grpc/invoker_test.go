@@ -0,0 +1,136 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/myzhan/boomer/boomertest"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+func checkServing(t *testing.T, invoker *Invoker, method string) {
+	resp, err := invoker.Invoke(context.Background(), method, []byte(`{"service": ""}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(resp, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, map[string]interface{}{"status": "SERVING"}) {
+		t.Error("unexpected response", string(resp))
+	}
+}
+
+func TestReflectionInvoker(t *testing.T) {
+	recorder := boomertest.Record(t)
+	conn := newTestConn(t,
+		gogrpc.WithUnaryInterceptor(UnaryClientInterceptor()),
+		gogrpc.WithStreamInterceptor(StreamClientInterceptor()))
+	invoker := NewReflectionInvoker(conn)
+
+	checkServing(t, invoker, "grpc.health.v1.Health/Check")
+	checkServing(t, invoker, "/grpc.health.v1.Health/Check")
+	checkServing(t, invoker, "grpc.health.v1.Health.Check")
+
+	// reflection isn't reported
+	if len(recorder.Requests()) != 3 {
+		t.Fatal("calls should be reported, got", recorder.Requests())
+	}
+	for _, r := range recorder.Requests() {
+		if !r.Success || r.Name != "/grpc.health.v1.Health/Check" {
+			t.Error("unexpected call", r)
+		}
+	}
+
+	_, err := invoker.Invoke(context.Background(), "grpc.health.v1.Health/Check", []byte(`{"service": "foo"}`))
+	if status.Code(err) != codes.NotFound {
+		t.Error("status of the call should be returned, got", err)
+	}
+}
+
+func TestProtoInvoker(t *testing.T) {
+	invoker, err := NewProtoInvoker(newTestConn(t), []string{"testdata"}, "health.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkServing(t, invoker, "grpc.health.v1.Health/Check")
+
+	m, err := invoker.Method(context.Background(), "grpc.health.v1.Health/Check")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.FullMethod != "/grpc.health.v1.Health/Check" {
+		t.Error("unexpected full method", m.FullMethod)
+	}
+	req, err := m.NewRequest([]byte(`{"service": "foo"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Call(context.Background(), req); status.Code(err) != codes.NotFound {
+		t.Error("status of the call should be returned, got", err)
+	}
+
+	if _, err := NewProtoInvoker(nil, []string{"testdata"}, "missing.proto"); err == nil {
+		t.Error("missing files should fail")
+	}
+}
+
+func TestInvokerErrors(t *testing.T) {
+	invoker := NewReflectionInvoker(newTestConn(t))
+	for method, reason := range map[string]string{
+		"Check":                              "invalid method",
+		"grpc.health.v1.Health/":             "invalid method",
+		"grpc.health.v1.Missing/Check":       "failed to load",
+		"grpc.health.v1.Health/Missing":      "isn't found",
+		"grpc.health.v1.Health/Watch":        "is streaming",
+		"grpc.health.v1.HealthCheckRequest/": "invalid method",
+	} {
+		if _, err := invoker.Invoke(context.Background(), method, []byte(`{}`)); err == nil || !strings.Contains(err.Error(), reason) {
+			t.Errorf("%s should fail with %q, got %v", method, reason, err)
+		}
+	}
+
+	_, err := invoker.Invoke(context.Background(), "grpc.health.v1.Health/Check", []byte(`{"foo": 1}`))
+	if err == nil || !strings.Contains(err.Error(), "invalid request") {
+		t.Error("unknown fields should fail, got", err)
+	}
+}
+
+func TestInvokerLoadsWithoutLock(t *testing.T) {
+	invoker := NewReflectionInvoker(newTestConn(t))
+	checkServing(t, invoker, "grpc.health.v1.Health/Check")
+
+	loading, release := make(chan bool), make(chan bool)
+	load := invoker.load
+	invoker.load = func(ctx context.Context, files *protoregistry.Files, service protoreflect.FullName) (*protoregistry.Files, error) {
+		close(loading)
+		<-release
+		return load(ctx, files, service)
+	}
+	defer close(release)
+	go invoker.Method(context.Background(), "grpc.health.v1.Missing/Check")
+	<-loading
+
+	found := make(chan error, 1)
+	go func() {
+		_, err := invoker.Method(context.Background(), "grpc.health.v1.Health/Check")
+		found <- err
+	}()
+	select {
+	case err := <-found:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Error("methods already found should not wait for loading")
+	}
+}
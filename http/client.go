@@ -0,0 +1,292 @@
+// Package http wraps net/http, requests are reported to boomer automatically.
+//
+//	client := http.NewClient(nil)
+//	resp, err := client.Get("http://localhost/users/1", http.Name("/users/:id"))
+//
+// Like locust, the request type is the HTTP method, the name is the URL unless it's overridden,
+// and responses with status codes of 400 and above are failures by default.
+package http
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	nethttp "net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myzhan/boomer"
+)
+
+// Check decides if a response is successful, it returns an error as the reason of failure.
+type Check func(resp *Response) error
+
+// StatusRange checks the status code is within min and max, inclusive.
+func StatusRange(min, max int) Check {
+	return func(resp *Response) error {
+		if resp.StatusCode < min || resp.StatusCode > max {
+			return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
+
+// Status checks the status code is one of codes.
+func Status(codes ...int) Check {
+	return func(resp *Response) error {
+		for _, code := range codes {
+			if resp.StatusCode == code {
+				return nil
+			}
+		}
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+}
+
+// ParseStatus parses status codes and ranges like "200-299,304" into a check.
+func ParseStatus(spec string) (Check, error) {
+	type statusRange struct {
+		min, max int
+	}
+	var ranges []statusRange
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		bounds := strings.SplitN(part, "-", 2)
+		min, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid status code %q", part)
+		}
+		max := min
+		if len(bounds) == 2 {
+			if max, err = strconv.Atoi(bounds[1]); err != nil || max < min {
+				return nil, fmt.Errorf("invalid status range %q", part)
+			}
+		}
+		ranges = append(ranges, statusRange{min, max})
+	}
+
+	return func(resp *Response) error {
+		for _, r := range ranges {
+			if resp.StatusCode >= r.min && resp.StatusCode <= r.max {
+				return nil
+			}
+		}
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}, nil
+}
+
+// BodyContains checks the body contains substr.
+func BodyContains(substr string) Check {
+	return func(resp *Response) error {
+		if !strings.Contains(string(resp.Content), substr) {
+			return fmt.Errorf("body doesn't contain %q", substr)
+		}
+		return nil
+	}
+}
+
+// Client sends requests with an http.Client and reports them.
+type Client struct {
+	// HTTPClient sends requests, http.DefaultClient is used if it's nil.
+	HTTPClient *nethttp.Client
+	// Checks decide if responses are successful, a response fails if any of them fails.
+	Checks []Check
+	// TagStatus adds the status code to every request as the tag "status".
+	TagStatus bool
+	// TraceTiming records DNS lookup, TCP connect, TLS handshake, time to first byte
+	// and content transfer of every request as trends.
+	TraceTiming bool
+}
+
+// NewClient returns a client which considers status codes below 400 successful.
+func NewClient(httpClient *nethttp.Client) *Client {
+	return &Client{
+		HTTPClient: httpClient,
+		Checks:     []Check{StatusRange(100, 399)},
+	}
+}
+
+type requestOptions struct {
+	name          string
+	tags          boomer.Tags
+	checks        []Check
+	checksSet     bool
+	catchResponse bool
+}
+
+// Option changes how a request is reported.
+type Option func(*requestOptions)
+
+// Name overrides the name of the request, to group URLs like "/users/:id".
+func Name(name string) Option {
+	return func(o *requestOptions) {
+		o.name = name
+	}
+}
+
+// Tags adds tags to the request.
+func Tags(tags boomer.Tags) Option {
+	return func(o *requestOptions) {
+		o.tags = tags
+	}
+}
+
+// Checks replaces the checks of the client for the request.
+func Checks(checks ...Check) Option {
+	return func(o *requestOptions) {
+		o.checks = checks
+		o.checksSet = true
+	}
+}
+
+// CatchResponse leaves reporting to the caller, like catch_response of locust.
+// The request is reported when Success, Failure or Report of the response is called.
+func CatchResponse() Option {
+	return func(o *requestOptions) {
+		o.catchResponse = true
+	}
+}
+
+// Response is an http.Response with its body read.
+type Response struct {
+	*nethttp.Response
+	// Content is the body, Body of the http.Response is already closed.
+	Content []byte
+	// Elapsed is the time from sending the request until the body is read.
+	Elapsed time.Duration
+	// Timing is the breakdown of Elapsed, if TraceTiming of the client is set.
+	Timing *Timing
+
+	method string
+	name   string
+	tags   boomer.Tags
+	// checkErr is the result of the checks.
+	checkErr error
+	once     sync.Once
+}
+
+// Success reports the request as successful, even if checks failed.
+func (r *Response) Success() {
+	r.once.Do(func() {
+		boomer.RecordSuccess(r.method, r.name, elapsedMillis(r.Elapsed), int64(len(r.Content)), r.tags)
+	})
+}
+
+// Failure reports the request as failed.
+func (r *Response) Failure(reason string) {
+	r.once.Do(func() {
+		boomer.RecordFailure(r.method, r.name, elapsedMillis(r.Elapsed), reason, r.tags)
+	})
+}
+
+// Report reports the request by the checks, unless Success or Failure is called already.
+func (r *Response) Report() {
+	if r.checkErr != nil {
+		r.Failure(r.checkErr.Error())
+	} else {
+		r.Success()
+	}
+}
+
+// CheckError returns why the checks failed, or nil.
+func (r *Response) CheckError() error {
+	return r.checkErr
+}
+
+func elapsedMillis(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
+
+// Do sends a request and reports it. Without CatchResponse, it returns the response and
+// the reason of failure if the checks fail, or nil and the error if the request fails.
+func (c *Client) Do(req *nethttp.Request, opts ...Option) (*Response, error) {
+	o := &requestOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.name == "" {
+		o.name = req.URL.String()
+	}
+	checks := c.Checks
+	if o.checksSet {
+		checks = o.checks
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = nethttp.DefaultClient
+	}
+
+	var timing *Timing
+	if c.TraceTiming {
+		req, timing = WithTiming(req)
+	}
+
+	start := time.Now()
+	resp, err := httpClient.Do(req)
+	if err == nil {
+		var content []byte
+		content, err = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err == nil {
+			r := &Response{
+				Response: resp,
+				Content:  content,
+				Elapsed:  time.Since(start),
+				Timing:   timing,
+				method:   req.Method,
+				name:     o.name,
+				tags:     o.tags,
+			}
+			if timing != nil {
+				timing.Record()
+			}
+			return r, c.report(r, checks, o)
+		}
+	}
+
+	boomer.RecordFailure(req.Method, o.name, elapsedMillis(time.Since(start)), err.Error(), o.tags)
+	return nil, err
+}
+
+func (c *Client) report(r *Response, checks []Check, o *requestOptions) error {
+	if c.TagStatus {
+		tags := make(boomer.Tags, len(o.tags)+1)
+		for k, v := range o.tags {
+			tags[k] = v
+		}
+		tags["status"] = strconv.Itoa(r.StatusCode)
+		r.tags = tags
+	}
+	for _, check := range checks {
+		if err := check(r); err != nil {
+			r.checkErr = err
+			break
+		}
+	}
+	if o.catchResponse {
+		return nil
+	}
+	r.Report()
+	return r.checkErr
+}
+
+// Get sends a GET request.
+func (c *Client) Get(url string, opts ...Option) (*Response, error) {
+	req, err := nethttp.NewRequest(nethttp.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req, opts...)
+}
+
+// Post sends a POST request.
+func (c *Client) Post(url, contentType string, body io.Reader, opts ...Option) (*Response, error) {
+	req, err := nethttp.NewRequest(nethttp.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return c.Do(req, opts...)
+}
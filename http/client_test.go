@@ -0,0 +1,132 @@
+package http
+
+import (
+	"fmt"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/myzhan/boomer/boomertest"
+)
+
+func newTestServer() *httptest.Server {
+	return httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if strings.HasPrefix(r.URL.Path, "/status/") {
+			var code int
+			fmt.Sscanf(r.URL.Path, "/status/%d", &code)
+			w.WriteHeader(code)
+		}
+		fmt.Fprint(w, "hello boomer")
+	}))
+}
+
+func TestClientReport(t *testing.T) {
+	recorder := boomertest.Record(t)
+	server := newTestServer()
+	defer server.Close()
+	client := NewClient(nil)
+
+	resp, err := client.Get(server.URL+"/users/1", Name("/users/:id"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp.Content) != "hello boomer" {
+		t.Error("body should be read, got", string(resp.Content))
+	}
+	if _, err := client.Post(server.URL+"/status/503", "text/plain", strings.NewReader("")); err == nil {
+		t.Error("status code 503 should fail")
+	}
+	if _, err := client.Get("http://127.0.0.1:0/"); err == nil {
+		t.Error("request should fail")
+	}
+
+	if len(recorder.Requests()) != 3 {
+		t.Fatal("all the requests should be reported, got", recorder.Requests())
+	}
+	if r := recorder.Requests()[0]; !r.Success || r.Type != "GET" || r.Name != "/users/:id" || r.Length != 12 {
+		t.Error("request should be reported with the name, got", r)
+	}
+	if r := recorder.Requests()[1]; r.Success || r.Type != "POST" || r.Name != server.URL+"/status/503" || r.Exception != "unexpected status code 503" {
+		t.Error("request should be reported as failure, got", r)
+	}
+	if r := recorder.Requests()[2]; r.Success || r.Exception == "" {
+		t.Error("request should be reported as failure, got", r)
+	}
+}
+
+func TestClientChecks(t *testing.T) {
+	recorder := boomertest.Record(t)
+	server := newTestServer()
+	defer server.Close()
+	client := NewClient(nil)
+	client.TagStatus = true
+
+	if _, err := client.Get(server.URL+"/status/404", Checks(Status(404), BodyContains("boomer"))); err != nil {
+		t.Error("404 is expected, got", err)
+	}
+	if _, err := client.Get(server.URL, Checks(BodyContains("locust"))); err == nil {
+		t.Error("body check should fail")
+	}
+
+	if r := recorder.Requests()[0]; !r.Success || r.Tags["status"] != "404" {
+		t.Error("request should be successful with the status tag, got", r)
+	}
+	if r := recorder.Requests()[1]; r.Success || r.Exception != `body doesn't contain "locust"` {
+		t.Error("request should fail by the body check, got", r)
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	check, err := ParseStatus("200-299, 304")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for code, ok := range map[int]bool{200: true, 299: true, 304: true, 301: false, 404: false} {
+		resp := &Response{Response: &nethttp.Response{StatusCode: code}}
+		if err := check(resp); (err == nil) != ok {
+			t.Errorf("status code %d, got %v", code, err)
+		}
+	}
+
+	for _, spec := range []string{"", "abc", "299-200", "200-"} {
+		if _, err := ParseStatus(spec); err == nil {
+			t.Errorf("%q should be invalid", spec)
+		}
+	}
+}
+
+func TestClientCatchResponse(t *testing.T) {
+	recorder := boomertest.Record(t)
+	server := newTestServer()
+	defer server.Close()
+	client := NewClient(nil)
+
+	resp, err := client.Get(server.URL+"/status/500", CatchResponse())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.Requests()) != 0 {
+		t.Fatal("request should not be reported until the caller decides")
+	}
+	if resp.CheckError() == nil {
+		t.Error("checks should fail")
+	}
+	resp.Success()
+	// reported only once
+	resp.Failure("ignored")
+	resp.Report()
+
+	resp, _ = client.Get(server.URL, CatchResponse())
+	resp.Failure("wrong content")
+
+	if len(recorder.Requests()) != 2 {
+		t.Fatal("requests should be reported once, got", recorder.Requests())
+	}
+	if r := recorder.Requests()[0]; !r.Success {
+		t.Error("request should be marked successful, got", r)
+	}
+	if r := recorder.Requests()[1]; r.Success || r.Exception != "wrong content" {
+		t.Error("request should be marked failed, got", r)
+	}
+}
@@ -0,0 +1,33 @@
+package http
+
+import (
+	"math/rand"
+	"sync/atomic"
+	"text/template"
+	"time"
+)
+
+var sequence int64
+
+// TemplateFuncs are the functions in the templates of httpbench and scenarios,
+// like {"id": {{seq}}, "name": "{{randString 8}}"}.
+var TemplateFuncs = template.FuncMap{
+	// seq returns 1, 2, 3... across all the users
+	"seq": func() int64 {
+		return atomic.AddInt64(&sequence, 1)
+	},
+	"randInt": func(min, max int) int {
+		return min + rand.Intn(max-min+1)
+	},
+	"randString": func(n int) string {
+		const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = letters[rand.Intn(len(letters))]
+		}
+		return string(b)
+	},
+	"now": func() int64 {
+		return time.Now().UnixNano() / int64(time.Millisecond)
+	},
+}
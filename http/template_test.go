@@ -0,0 +1,27 @@
+package http
+
+import (
+	"bytes"
+	"testing"
+	"text/template"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	tmpl := template.Must(template.New("").Funcs(TemplateFuncs).Parse(`{{seq}} {{seq}} {{randInt 5 5}} {{len (randString 8)}}`))
+	sequence = 0
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "1 2 5 8" {
+		t.Error("unexpected result", buf.String())
+	}
+
+	buf.Reset()
+	if err := tmpl.Execute(buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "3 4 5 8"; buf.String() != expected {
+		t.Error("seq should continue across executions, got", buf.String())
+	}
+}
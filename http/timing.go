@@ -0,0 +1,118 @@
+package http
+
+import (
+	"crypto/tls"
+	nethttp "net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/myzhan/boomer"
+)
+
+// Phases of requests are recorded as trends in milliseconds, so they are aggregated like other
+// custom metrics, and included in the report. Phases skipped by reused connections aren't recorded.
+var (
+	dnsLookupTrend       = boomer.NewTrend("http_dns_lookup")
+	tcpConnectTrend      = boomer.NewTrend("http_tcp_connect")
+	tlsHandshakeTrend    = boomer.NewTrend("http_tls_handshake")
+	timeToFirstByteTrend = boomer.NewTrend("http_time_to_first_byte")
+	contentTransferTrend = boomer.NewTrend("http_content_transfer")
+)
+
+// Timing is the breakdown of a request by net/http/httptrace, zero if a phase is skipped.
+type Timing struct {
+	DNSLookup    time.Duration
+	TCPConnect   time.Duration
+	TLSHandshake time.Duration
+	// TimeToFirstByte is from sending the request until the first byte of the response.
+	TimeToFirstByte time.Duration
+	ContentTransfer time.Duration
+
+	// callbacks of httptrace may be called concurrently, like connecting to several addresses.
+	lock         sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	firstByte    time.Time
+}
+
+// WithTiming traces req, Record must be called after the body of the response is read.
+// It's for your own http.Client, Client traces requests if Timing is set.
+func WithTiming(req *nethttp.Request) (*nethttp.Request, *Timing) {
+	t := &Timing{start: time.Now()}
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.lock.Lock()
+			t.dnsStart = time.Now()
+			t.lock.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.lock.Lock()
+			t.DNSLookup = time.Since(t.dnsStart)
+			t.lock.Unlock()
+		},
+		ConnectStart: func(network, addr string) {
+			t.lock.Lock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+			t.lock.Unlock()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			t.lock.Lock()
+			if err == nil && t.TCPConnect == 0 {
+				t.TCPConnect = time.Since(t.connectStart)
+			}
+			t.lock.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			t.lock.Lock()
+			t.tlsStart = time.Now()
+			t.lock.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.lock.Lock()
+			t.TLSHandshake = time.Since(t.tlsStart)
+			t.lock.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			t.lock.Lock()
+			t.firstByte = time.Now()
+			t.TimeToFirstByte = t.firstByte.Sub(t.start)
+			t.lock.Unlock()
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), t
+}
+
+// Record records the phases as trends, the content transfer ends now.
+func (t *Timing) Record() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if !t.firstByte.IsZero() {
+		t.ContentTransfer = time.Since(t.firstByte)
+	}
+	for _, phase := range []struct {
+		trend    *boomer.Trend
+		duration time.Duration
+	}{
+		{dnsLookupTrend, t.DNSLookup},
+		{tcpConnectTrend, t.TCPConnect},
+		{tlsHandshakeTrend, t.TLSHandshake},
+	} {
+		if phase.duration > 0 {
+			phase.trend.Add(millis(phase.duration))
+		}
+	}
+	if !t.firstByte.IsZero() {
+		timeToFirstByteTrend.Add(millis(t.TimeToFirstByte))
+		contentTransferTrend.Add(millis(t.ContentTransfer))
+	}
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
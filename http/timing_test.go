@@ -0,0 +1,73 @@
+package http
+
+import (
+	"io/ioutil"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/myzhan/boomer/boomertest"
+)
+
+func TestTiming(t *testing.T) {
+	boomertest.Record(t)
+	server := httptest.NewTLSServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("hello"))
+		w.(nethttp.Flusher).Flush()
+		time.Sleep(10 * time.Millisecond)
+		w.Write([]byte(" boomer"))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client())
+	client.TraceTiming = true
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timing := resp.Timing
+	if timing == nil {
+		t.Fatal("timing should be traced")
+	}
+	if timing.TCPConnect <= 0 || timing.TLSHandshake <= 0 {
+		t.Error("new connection should be traced, got", timing)
+	}
+	if timing.TimeToFirstByte < 20*time.Millisecond || timing.TimeToFirstByte > resp.Elapsed {
+		t.Error("time to first byte should include the time of the backend, got", timing.TimeToFirstByte)
+	}
+	if timing.ContentTransfer < 10*time.Millisecond {
+		t.Error("content transfer should be traced, got", timing.ContentTransfer)
+	}
+
+	// the connection is reused
+	resp, err = client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Timing.TCPConnect != 0 || resp.Timing.TLSHandshake != 0 {
+		t.Error("reused connection should not be traced, got", resp.Timing)
+	}
+}
+
+func TestWithTiming(t *testing.T) {
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.Write([]byte("hello boomer"))
+	}))
+	defer server.Close()
+
+	req, _ := nethttp.NewRequest(nethttp.MethodGet, server.URL, nil)
+	req, timing := WithTiming(req)
+	resp, err := nethttp.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	timing.Record()
+
+	if timing.TCPConnect <= 0 || timing.TimeToFirstByte <= 0 {
+		t.Error("requests of your own client should be traced, got", timing)
+	}
+}
@@ -0,0 +1,600 @@
+package boomer
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// Master is a native Go implementation of the locust master. It talks to boomer
+// workers connected with --rpc=socket or --rpc=grpc, sends hatch, stop and quit messages, and
+// aggregates stats reported by them. It doesn't speak ZeroMQ, so locust workers can't connect to it.
+type Master struct {
+	bindHost string
+	bindPort int
+	nodeID   string
+
+	rpc        string
+	listener   net.Listener
+	tlsConfig  *tls.Config
+	grpcServer *grpc.Server
+
+	lock       sync.Mutex
+	state      string
+	numClients int
+	hatchRate  float64
+	maxRPS     int64
+	workers    map[string]*workerNode
+	stats      *requestStats
+	metrics    *customMetrics
+
+	messageHandlers map[string]func(workerID string, data map[string]interface{})
+	rendezvous      map[string]*masterRendezvous
+}
+
+type workerNode struct {
+	id        string
+	state     string
+	userCount int64
+	conn      workerConn
+	outbox    chan *message
+	// closed is set when the worker is disconnected for not taking messages
+	closed bool
+}
+
+// workerConn is a connection from a worker, over tcp socket or grpc.
+type workerConn interface {
+	readMessage() (*message, error)
+	writeMessage(msg *message) error
+	remoteAddr() string
+	Close() error
+}
+
+type socketWorkerConn struct {
+	net.Conn
+}
+
+func (c socketWorkerConn) readMessage() (*message, error) {
+	return readFrame(c.Conn)
+}
+
+func (c socketWorkerConn) writeMessage(msg *message) error {
+	return writeFrame(c.Conn, msg)
+}
+
+func (c socketWorkerConn) remoteAddr() string {
+	return c.RemoteAddr().String()
+}
+
+// WorkerInfo describes a worker connected to the master.
+type WorkerInfo struct {
+	ID        string `json:"id"`
+	State     string `json:"state"`
+	UserCount int64  `json:"user_count"`
+	Address   string `json:"address"`
+}
+
+// NewMaster returns a master that will listen on bindHost:bindPort.
+func NewMaster(bindHost string, bindPort int) *Master {
+	return &Master{
+		bindHost: bindHost,
+		bindPort: bindPort,
+		nodeID:   getNodeID(),
+		rpc:      "socket",
+		state:    stateInit,
+		workers:  make(map[string]*workerNode),
+		stats:    newRequestStats(),
+		metrics:  newCustomMetrics(),
+
+		messageHandlers: make(map[string]func(workerID string, data map[string]interface{})),
+		rendezvous:      make(map[string]*masterRendezvous),
+	}
+}
+
+// SetTLSConfig makes the master accept workers with TLS only, it must be called before Listen.
+func (m *Master) SetTLSConfig(config *tls.Config) {
+	m.tlsConfig = config
+}
+
+// SetRPC chooses how workers connect to the master, socket or grpc. It must be called before Listen.
+func (m *Master) SetRPC(rpc string) error {
+	if rpc != "socket" && rpc != "grpc" {
+		return fmt.Errorf("unknown rpc type %q, should be socket or grpc", rpc)
+	}
+	m.rpc = rpc
+	return nil
+}
+
+// Listen binds the master to its address, workers can connect after it returns.
+func (m *Master) Listen() error {
+	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", m.bindHost, m.bindPort))
+	if err != nil {
+		return err
+	}
+	if m.rpc == "grpc" {
+		m.grpcServer = newGrpcMasterServer(m)
+	} else if m.tlsConfig != nil {
+		listener = tls.NewListener(listener, m.tlsConfig)
+	}
+	m.listener = listener
+	log.Println("Master is listening on", listener.Addr())
+	return nil
+}
+
+// Addr returns the address that the master is listening on.
+func (m *Master) Addr() net.Addr {
+	return m.listener.Addr()
+}
+
+// Serve accepts connections from workers until Close is called.
+func (m *Master) Serve() error {
+	if m.grpcServer != nil {
+		return m.grpcServer.Serve(m.listener)
+	}
+	for {
+		conn, err := m.listener.Accept()
+		if err != nil {
+			return err
+		}
+		if tcpConn, ok := conn.(*net.TCPConn); ok {
+			tcpConn.SetNoDelay(true)
+		}
+		go m.handleConn(socketWorkerConn{conn})
+	}
+}
+
+// Close stops accepting workers and disconnects all the connected workers.
+func (m *Master) Close() error {
+	var err error
+	if m.grpcServer != nil {
+		m.grpcServer.Stop()
+	} else {
+		err = m.listener.Close()
+	}
+	m.lock.Lock()
+	for _, w := range m.workers {
+		w.conn.Close()
+	}
+	m.lock.Unlock()
+	return err
+}
+
+func (m *Master) handleConn(conn workerConn) {
+	var worker *workerNode
+	defer func() {
+		conn.Close()
+		if worker != nil {
+			m.removeWorker(worker)
+		}
+	}()
+
+	for {
+		msg, err := conn.readMessage()
+		if isInvalidMessage(err) {
+			log.Printf("Invalid message from %s, %v\n", conn.remoteAddr(), err)
+			continue
+		}
+		if err != nil {
+			if worker != nil {
+				log.Printf("Worker %s is disconnected, %v\n", worker.id, err)
+			} else if err != io.EOF {
+				// like failed TLS handshakes
+				log.Printf("Connection from %s is closed, %v\n", conn.remoteAddr(), err)
+			}
+			return
+		}
+		if worker == nil {
+			worker = m.addWorker(msg.NodeID, conn)
+		}
+		// custom messages are handled without holding the lock, handlers may send messages
+		if handler := m.messageHandler(msg.Type); handler != nil {
+			handler(worker.id, msg.Data)
+			continue
+		}
+		if !m.handleMessage(worker, msg) {
+			return
+		}
+	}
+}
+
+func (m *Master) addWorker(nodeID string, conn workerConn) *workerNode {
+	worker := &workerNode{
+		id:     nodeID,
+		state:  stateInit,
+		conn:   conn,
+		outbox: make(chan *message, 100),
+	}
+	go func() {
+		for msg := range worker.outbox {
+			if err := conn.writeMessage(msg); err != nil {
+				log.Printf("Error sending to worker %s: %v\n", worker.id, err)
+				conn.Close()
+				return
+			}
+		}
+	}()
+
+	m.lock.Lock()
+	if stale, ok := m.workers[nodeID]; ok {
+		// the worker reconnects before the previous connection is found broken
+		log.Printf("Worker %s reconnects, the previous connection is closed\n", nodeID)
+		delete(m.workers, nodeID)
+		close(stale.outbox)
+		stale.conn.Close()
+		m.dropRendezvous(nodeID)
+	}
+	m.workers[nodeID] = worker
+	if m.maxRPS > 0 {
+		m.shareMaxRPS()
+	}
+	m.lock.Unlock()
+
+	log.Printf("Worker %s is connected from %s\n", nodeID, conn.remoteAddr())
+	return worker
+}
+
+func (m *Master) removeWorker(worker *workerNode) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.workers[worker.id] != worker {
+		return
+	}
+	delete(m.workers, worker.id)
+	close(worker.outbox)
+	m.dropRendezvous(worker.id)
+	log.Printf("Worker %s is removed, %d workers left\n", worker.id, len(m.workers))
+
+	if m.state == stateHatching || m.state == stateRunning {
+		m.rebalance()
+	} else if m.maxRPS > 0 {
+		m.shareMaxRPS()
+	}
+}
+
+// handleMessage returns false if the worker quits.
+func (m *Master) handleMessage(worker *workerNode, msg *message) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	switch msg.Type {
+	case "client_ready":
+		worker.state = stateInit
+		worker.userCount = 0
+		if m.state == stateHatching || m.state == stateRunning {
+			// a new worker joins a running test
+			m.rebalance()
+		}
+	case "hatching":
+		worker.state = stateHatching
+	case "hatch_complete":
+		worker.state = stateRunning
+		if hatchComplete, err := decodeHatchComplete(msg.Data); err == nil {
+			worker.userCount = hatchComplete.count
+		} else {
+			log.Printf("Invalid hatch_complete message from worker %s, %v\n", worker.id, err)
+		}
+		m.updateState()
+	case "client_stopped":
+		worker.state = stateStopped
+		worker.userCount = 0
+	case "stats":
+		s, err := decodeStats(msg.Data)
+		if err != nil {
+			log.Printf("Invalid stats message from worker %s, %v\n", worker.id, err)
+			break
+		}
+		worker.userCount = s.userCount
+		if err := m.aggregate(s); err != nil {
+			log.Printf("Invalid stats message from worker %s, %v\n", worker.id, err)
+		}
+	case "rendezvous", "rendezvous_leave":
+		r, err := decodeRendezvous(msg.Data)
+		if err != nil {
+			log.Printf("Invalid %s message from worker %s, %v\n", msg.Type, worker.id, err)
+			break
+		}
+		if msg.Type == "rendezvous" {
+			m.arriveRendezvous(worker, r)
+		} else {
+			m.leaveRendezvous(worker, r)
+		}
+	case "quit":
+		log.Printf("Worker %s quits\n", worker.id)
+		return false
+	default:
+		log.Printf("Unknown message %q from worker %s\n", msg.Type, worker.id)
+	}
+	return true
+}
+
+// updateState marks the test running once all the workers are.
+func (m *Master) updateState() {
+	if m.state != stateHatching {
+		return
+	}
+	for _, w := range m.workers {
+		if w.state == stateHatching {
+			return
+		}
+	}
+	m.state = stateRunning
+	log.Println("All workers are hatched")
+}
+
+func (m *Master) aggregate(s *statsMessage) error {
+	for _, entry := range s.entries {
+		m.stats.get(entry.name, entry.method, nil).merge(entry)
+	}
+	m.stats.total.merge(s.total)
+
+	for key, statsErr := range s.errors {
+		if existing, ok := m.stats.errors[key]; ok {
+			existing.occurences += statsErr.occurences
+		} else {
+			m.stats.errors[key] = statsErr
+		}
+	}
+
+	if s.customMetrics != nil {
+		return m.metrics.mergeReport(s.customMetrics)
+	}
+	return nil
+}
+
+// Swarm starts a test, or changes the number of users of a running test.
+// Users are divided among all the connected workers.
+func (m *Master) Swarm(numClients int, hatchRate float64) error {
+	if numClients <= 0 || hatchRate <= 0 {
+		return fmt.Errorf("num_clients and hatch_rate must be positive, got %d and %v", numClients, hatchRate)
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if len(m.workers) == 0 {
+		return fmt.Errorf("no workers connected")
+	}
+	if m.state != stateHatching && m.state != stateRunning {
+		m.stats.clearAll()
+		m.metrics = newCustomMetrics()
+	}
+	m.numClients = numClients
+	m.hatchRate = hatchRate
+	m.state = stateHatching
+	m.rebalance()
+	return nil
+}
+
+// sortedWorkerIDs returns the IDs of the workers in the order that users are given to them.
+func (m *Master) sortedWorkerIDs() []string {
+	ids := make([]string, 0, len(m.workers))
+	for id := range m.workers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// rebalance sends hatch messages to all the workers, and shares the max RPS among the workers with users again.
+// It must be called with the lock held.
+func (m *Master) rebalance() {
+	if len(m.workers) == 0 {
+		return
+	}
+
+	ids := m.sortedWorkerIDs()
+	numWorkers := len(ids)
+	hatchRate := m.hatchRate / float64(numWorkers)
+	// workers with users are the first ones, test data is partitioned among them
+	hatchedWorkers := numWorkers
+	if m.numClients < numWorkers {
+		hatchedWorkers = m.numClients
+	}
+	for i, id := range ids {
+		numClients := m.numClients / numWorkers
+		if i < m.numClients%numWorkers {
+			numClients++
+		}
+		worker := m.workers[id]
+		if numClients == 0 {
+			// locust workers ignore hatch messages without users
+			if worker.state == stateHatching || worker.state == stateRunning {
+				m.send(worker, newMessage("stop", nil, m.nodeID))
+			}
+			continue
+		}
+		worker.state = stateHatching
+		m.send(worker, newMessage("hatch", map[string]interface{}{
+			"num_clients":  int64(numClients),
+			"hatch_rate":   hatchRate,
+			"worker_index": int64(i),
+			"worker_count": int64(hatchedWorkers),
+		}, m.nodeID))
+	}
+	log.Printf("Sent hatch messages to %d workers, %d users in total at the rate %v users/s\n",
+		numWorkers, m.numClients, m.hatchRate)
+	if m.maxRPS > 0 {
+		m.shareMaxRPS()
+	}
+}
+
+// SetMaxRPS sets the cluster-wide max RPS, it's shared among the workers with users, and shared again
+// when workers join or leave, 0 means unlimited. Workers adopt it without restarting users.
+// Each worker gets at least 1 RPS, so a max RPS less than the number of workers can be exceeded.
+func (m *Master) SetMaxRPS(maxRPS int64) error {
+	if maxRPS < 0 {
+		return fmt.Errorf("max RPS should not be negative, not %d", maxRPS)
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.maxRPS = maxRPS
+	m.shareMaxRPS()
+	return nil
+}
+
+// shareMaxRPS sends each worker its share of the max RPS, it must be called with the lock held.
+// During a test, only the workers given users by rebalance get a share.
+func (m *Master) shareMaxRPS() {
+	if len(m.workers) == 0 {
+		return
+	}
+
+	ids := m.sortedWorkerIDs()
+	if (m.state == stateHatching || m.state == stateRunning) && m.numClients < len(ids) {
+		ids = ids[:m.numClients]
+	}
+
+	numWorkers := int64(len(ids))
+	if m.maxRPS > 0 && m.maxRPS < numWorkers {
+		log.Printf("Max RPS %d is less than %d workers, each of them is limited to 1 RPS, %d RPS in total\n",
+			m.maxRPS, numWorkers, numWorkers)
+	}
+	for i, id := range ids {
+		share := m.maxRPS / numWorkers
+		if int64(i) < m.maxRPS%numWorkers {
+			share++
+		}
+		if m.maxRPS > 0 && share == 0 {
+			// 0 means unlimited, there are more workers than RPS
+			share = 1
+		}
+		m.send(m.workers[id], newMessage("max_rps", map[string]interface{}{
+			"max_rps": share,
+		}, m.nodeID))
+	}
+	log.Printf("Shared max RPS %d among %d workers\n", m.maxRPS, numWorkers)
+}
+
+// Stop stops the running test on all the workers.
+func (m *Master) Stop() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.broadcast(newMessage("stop", nil, m.nodeID))
+	m.state = stateStopped
+}
+
+// Quit tells all the workers to quit.
+func (m *Master) Quit() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.broadcast(newMessage("quit", nil, m.nodeID))
+	m.state = stateStopped
+}
+
+// RegisterMessageHandler registers a handler for custom messages of msgType from workers.
+// Handlers are called in the goroutine reading from the worker, they should return quickly.
+func (m *Master) RegisterMessageHandler(msgType string, handler func(workerID string, data map[string]interface{})) error {
+	if err := checkCustomMessage(msgType); err != nil {
+		return err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.messageHandlers[msgType] = handler
+	return nil
+}
+
+func (m *Master) messageHandler(msgType string) func(workerID string, data map[string]interface{}) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.messageHandlers[msgType]
+}
+
+// SendMessage sends a custom message to all the workers, like test data.
+func (m *Master) SendMessage(msgType string, data map[string]interface{}) error {
+	if err := checkCustomMessage(msgType); err != nil {
+		return err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.broadcast(newMessage(msgType, data, m.nodeID))
+	return nil
+}
+
+// ResetStats clears the aggregated stats.
+func (m *Master) ResetStats() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.stats.clearAll()
+	m.metrics = newCustomMetrics()
+}
+
+func (m *Master) broadcast(msg *message) {
+	for _, w := range m.workers {
+		m.send(w, msg)
+	}
+}
+
+// send never blocks, as it's called with the lock held. Messages like hatch and stop must not be lost,
+// so a worker too slow to take them, with its outbox full, is disconnected instead.
+func (m *Master) send(worker *workerNode, msg *message) {
+	if worker.closed {
+		return
+	}
+	select {
+	case worker.outbox <- msg:
+	default:
+		log.Printf("Worker %s is too slow to take %s message, disconnecting it\n", worker.id, msg.Type)
+		worker.closed = true
+		// closing may block until pending writes fail
+		go worker.conn.Close()
+	}
+}
+
+// Workers returns the connected workers.
+func (m *Master) Workers() []*WorkerInfo {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	workers := make([]*WorkerInfo, 0, len(m.workers))
+	for _, w := range m.workers {
+		workers = append(workers, &WorkerInfo{
+			ID:        w.id,
+			State:     w.state,
+			UserCount: w.userCount,
+			Address:   w.conn.remoteAddr(),
+		})
+	}
+	sort.Slice(workers, func(i, j int) bool {
+		return workers[i].ID < workers[j].ID
+	})
+	return workers
+}
+
+// State returns the state of the test and the number of users on all the workers.
+func (m *Master) State() (state string, userCount int64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, w := range m.workers {
+		userCount += w.userCount
+	}
+	return m.state, userCount
+}
+
+// summary returns a copy of the aggregated stats.
+func (m *Master) summary() *testSummary {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return &testSummary{
+		stats:   m.stats.snapshot(),
+		metrics: m.metrics.snapshot(),
+	}
+}
+
+// report builds a report from the aggregated stats, in the same format as --report-file.
+func (m *Master) report() *report {
+	return newReport(m.summary(), time.Now())
+}
@@ -0,0 +1,103 @@
+package boomer
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/myzhan/boomer/boomerpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+type grpcMasterServer struct {
+	boomerpb.UnimplementedMasterServer
+	master *Master
+}
+
+func newGrpcMasterServer(m *Master) *grpc.Server {
+	var opts []grpc.ServerOption
+	if m.tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(m.tlsConfig)))
+	}
+	server := grpc.NewServer(opts...)
+	boomerpb.RegisterMasterServer(server, &grpcMasterServer{master: m})
+	return server
+}
+
+// Connect serves a worker until it disconnects, or it's closed by the master.
+func (s *grpcMasterServer) Connect(stream boomerpb.Master_ConnectServer) error {
+	conn := &grpcWorkerConn{
+		sendChannel:     make(chan *boomerpb.Message),
+		closeChannel:    make(chan bool),
+		finishedChannel: make(chan bool),
+		stream:          stream,
+	}
+	if p, ok := peer.FromContext(stream.Context()); ok {
+		conn.addr = p.Addr.String()
+	}
+	defer close(conn.finishedChannel)
+
+	done := make(chan bool)
+	go func() {
+		s.master.handleConn(conn)
+		close(done)
+	}()
+
+	// messages are sent here, because the stream can't be used after Connect returns,
+	// and returning from Connect closes the stream, which stops handleConn.
+	for {
+		select {
+		case pb := <-conn.sendChannel:
+			if err := stream.Send(pb); err != nil {
+				return err
+			}
+		case <-done:
+			return nil
+		case <-conn.closeChannel:
+			return nil
+		}
+	}
+}
+
+type grpcWorkerConn struct {
+	stream boomerpb.Master_ConnectServer
+	addr   string
+
+	sendChannel     chan *boomerpb.Message
+	closeOnce       sync.Once
+	closeChannel    chan bool
+	finishedChannel chan bool
+}
+
+func (c *grpcWorkerConn) readMessage() (*message, error) {
+	pb, err := c.stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	return newMessageFromPB(pb)
+}
+
+func (c *grpcWorkerConn) writeMessage(msg *message) error {
+	pb, err := newPBMessage(msg)
+	if err != nil {
+		return err
+	}
+	select {
+	case c.sendChannel <- pb:
+		return nil
+	case <-c.finishedChannel:
+		return errors.New("worker is disconnected")
+	}
+}
+
+func (c *grpcWorkerConn) remoteAddr() string {
+	return c.addr
+}
+
+func (c *grpcWorkerConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closeChannel)
+	})
+	return nil
+}
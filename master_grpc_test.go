@@ -0,0 +1,96 @@
+package boomer
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMasterGrpc(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "boomer")
+	defer os.RemoveAll(dir)
+
+	ca := newTestCert(t, dir, "ca", nil)
+	server := newTestCert(t, dir, "master", ca)
+	serverConfig, err := NewServerTLSConfig(server.certFile, server.keyFile, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientConfig, err := NewClientTLSConfig(ca.certFile, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewMaster("127.0.0.1", 0)
+	if err := m.SetRPC("grpc"); err != nil {
+		t.Fatal(err)
+	}
+	m.SetTLSConfig(serverConfig)
+	if err := m.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	go m.Serve()
+	defer m.Close()
+
+	for len(fromMaster) > 0 {
+		<-fromMaster
+	}
+	for len(toMaster) > 0 {
+		<-toMaster
+	}
+	SetTLSConfig(clientConfig)
+	defer SetTLSConfig(nil)
+	c := newGrpcClient("127.0.0.1", m.Addr().(*net.TCPAddr).Port)
+	defer c.close()
+
+	toMaster <- newMessage("client_ready", nil, "worker1")
+	waitForWorkers(t, m, 1)
+
+	if err := m.Swarm(10, 2); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-fromMaster:
+		hatch, err := decodeHatch(msg.Data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.Type != "hatch" || hatch.numClients != 10 || hatch.hatchRate != 2 {
+			t.Error("worker should receive hatch message, got", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for hatch message")
+	}
+
+	s := newRequestStats()
+	s.logRequest("http", "foo", 100, 10, nil)
+	toMaster <- newMessage("stats", map[string]interface{}{
+		"stats":       s.serializeStats(),
+		"stats_total": s.total.getStrippedReport(),
+		"errors":      s.serializeErrors(),
+		"user_count":  int64(10),
+	}, "worker1")
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, userCount := m.State(); userCount == 10 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("stats from the worker should be aggregated")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if r := m.report(); r.Total.NumRequests != 1 || len(r.Entries) != 1 {
+		t.Error("stats from the worker should be aggregated, got", r.Total, r.Entries)
+	}
+}
+
+func TestMasterSetRPC(t *testing.T) {
+	m := NewMaster("127.0.0.1", 0)
+	if err := m.SetRPC("zeromq"); err == nil {
+		t.Error("zeromq is not supported by the master")
+	}
+}
@@ -0,0 +1,81 @@
+package boomer
+
+import (
+	"log"
+)
+
+// masterRendezvous counts users arrived at a rendezvous point across all the workers.
+type masterRendezvous struct {
+	participants int64
+	arrivals     []rendezvousArrival
+}
+
+type rendezvousArrival struct {
+	workerID string
+	id       int64
+}
+
+// arriveRendezvous releases all the users once enough arrive, it must be called with the lock held.
+func (m *Master) arriveRendezvous(worker *workerNode, r *rendezvousMessage) {
+	if r.participants <= 0 {
+		log.Printf("Invalid rendezvous message from worker %s, participants should be positive\n", worker.id)
+		return
+	}
+	point, ok := m.rendezvous[r.name]
+	if !ok {
+		point = &masterRendezvous{participants: r.participants}
+		m.rendezvous[r.name] = point
+	}
+	point.arrivals = append(point.arrivals, rendezvousArrival{worker.id, r.id})
+	if int64(len(point.arrivals)) < point.participants {
+		return
+	}
+
+	delete(m.rendezvous, r.name)
+	ids := make(map[string][]int64)
+	for _, arrival := range point.arrivals {
+		ids[arrival.workerID] = append(ids[arrival.workerID], arrival.id)
+	}
+	for workerID, workerIDs := range ids {
+		if w, ok := m.workers[workerID]; ok {
+			m.send(w, newMessage("rendezvous_release", map[string]interface{}{
+				"name": r.name,
+				"ids":  workerIDs,
+			}, m.nodeID))
+		}
+	}
+	log.Printf("Released %d users at rendezvous point %s\n", len(point.arrivals), r.name)
+}
+
+// leaveRendezvous must be called with the lock held.
+func (m *Master) leaveRendezvous(worker *workerNode, r *rendezvousMessage) {
+	point, ok := m.rendezvous[r.name]
+	if !ok {
+		return
+	}
+	m.removeArrivals(r.name, point, func(arrival rendezvousArrival) bool {
+		return arrival.workerID == worker.id && arrival.id == r.id
+	})
+}
+
+// dropRendezvous removes users of a worker when it's removed, it must be called with the lock held.
+func (m *Master) dropRendezvous(workerID string) {
+	for name, point := range m.rendezvous {
+		m.removeArrivals(name, point, func(arrival rendezvousArrival) bool {
+			return arrival.workerID == workerID
+		})
+	}
+}
+
+func (m *Master) removeArrivals(name string, point *masterRendezvous, match func(rendezvousArrival) bool) {
+	arrivals := point.arrivals[:0]
+	for _, arrival := range point.arrivals {
+		if !match(arrival) {
+			arrivals = append(arrivals, arrival)
+		}
+	}
+	point.arrivals = arrivals
+	if len(point.arrivals) == 0 {
+		delete(m.rendezvous, name)
+	}
+}
@@ -0,0 +1,370 @@
+package boomer
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestMaster(t *testing.T) *Master {
+	m := NewMaster("127.0.0.1", 0)
+	if err := m.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	go m.Serve()
+	return m
+}
+
+func waitForWorkers(t *testing.T, m *Master, n int) {
+	deadline := time.Now().Add(time.Second)
+	for len(m.Workers()) != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("there should be %d workers, not %d", n, len(m.Workers()))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMasterSwarm(t *testing.T) {
+
+	m := newTestMaster(t)
+	defer m.Close()
+
+	if err := m.Swarm(10, 4); err == nil {
+		t.Error("swarm without workers should fail")
+	}
+
+	conns := make([]net.Conn, 0)
+	for _, id := range []string{"worker1", "worker2"} {
+		conn, err := net.Dial("tcp", m.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		writeFrame(conn, newMessage("client_ready", nil, id))
+		conns = append(conns, conn)
+	}
+	waitForWorkers(t, m, 2)
+
+	if err := m.Swarm(11, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	total := int64(0)
+	for i, conn := range conns {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		msg, err := readFrame(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.Type != "hatch" {
+			t.Fatal("worker should receive hatch message, not", msg.Type)
+		}
+		numClients, _ := toInt64(msg.Data["num_clients"])
+		hatchRate, _ := toFloat64(msg.Data["hatch_rate"])
+		if hatchRate != 2 {
+			t.Error("hatch rate should be divided among workers, got", hatchRate)
+		}
+		workerIndex, _ := toInt64(msg.Data["worker_index"])
+		workerCount, _ := toInt64(msg.Data["worker_count"])
+		if workerIndex != int64(i) || workerCount != 2 {
+			t.Errorf("worker %d should be partitioned as %d of 2, got %d of %d", i, i, workerIndex, workerCount)
+		}
+		total += numClients
+	}
+	if total != 11 {
+		t.Error("users should be divided among workers, got", total)
+	}
+}
+
+func TestMasterAggregateStats(t *testing.T) {
+
+	m := newTestMaster(t)
+	defer m.Close()
+
+	for _, id := range []string{"worker1", "worker2"} {
+		conn, err := net.Dial("tcp", m.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		s := newRequestStats()
+		s.logRequest("http", "foo", 100, 10, nil)
+		s.logError("http", "foo", "error", nil)
+		data := map[string]interface{}{
+			"stats":       s.serializeStats(),
+			"stats_total": s.total.getStrippedReport(),
+			"errors":      s.serializeErrors(),
+			"user_count":  int64(5),
+		}
+		writeFrame(conn, newMessage("client_ready", nil, id))
+		writeFrame(conn, newMessage("stats", data, id))
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, userCount := m.State(); userCount == 10 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("stats from workers should be aggregated")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	r := m.report()
+	if r.Total.NumRequests != 2 || r.Total.NumFailures != 2 {
+		t.Error("total should be aggregated, got", r.Total)
+	}
+	if len(r.Entries) != 1 || r.Entries[0].NumRequests != 2 || r.Entries[0].MaxResponseTime != 100 {
+		t.Error("entries should be aggregated, got", r.Entries)
+	}
+	if len(r.Errors) != 1 || r.Errors[0].Occurrences != 2 {
+		t.Error("errors should be aggregated, got", r.Errors)
+	}
+}
+
+func TestMasterCustomMessages(t *testing.T) {
+
+	m := newTestMaster(t)
+	defer m.Close()
+
+	if err := m.SendMessage("quit", nil); err == nil {
+		t.Error("built-in messages can't be sent as custom messages")
+	}
+	// handlers may send messages
+	if err := m.RegisterMessageHandler("need_tokens", func(workerID string, data map[string]interface{}) {
+		if workerID != "worker1" {
+			t.Error("custom message should come from worker1, got", workerID)
+		}
+		m.SendMessage("tokens", map[string]interface{}{"tokens": data["count"]})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := net.Dial("tcp", m.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	writeFrame(conn, newMessage("client_ready", nil, "worker1"))
+	writeFrame(conn, newMessage("need_tokens", map[string]interface{}{"count": int64(3)}, "worker1"))
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	msg, err := readFrame(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := toInt64(msg.Data["tokens"]); msg.Type != "tokens" || n != 3 {
+		t.Error("worker should receive the custom message, got", msg)
+	}
+}
+
+func TestMasterWebSendMessage(t *testing.T) {
+
+	m := newTestMaster(t)
+	defer m.Close()
+
+	conn, err := net.Dial("tcp", m.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	writeFrame(conn, newMessage("client_ready", nil, "worker1"))
+	waitForWorkers(t, m, 1)
+
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+	for form, code := range map[string]int{
+		"type=stop":                      http.StatusBadRequest,
+		"type=params&data=[1]":           http.StatusBadRequest,
+		`type=params&data={"copies": 3}`: http.StatusOK,
+	} {
+		values, _ := url.ParseQuery(form)
+		resp, err := http.PostForm(server.URL+"/message", values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != code {
+			t.Errorf("%s should return %d, got %d", form, code, resp.StatusCode)
+		}
+	}
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	msg, err := readFrame(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := toInt64(msg.Data["copies"]); msg.Type != "params" || n != 3 {
+		t.Error("worker should receive the custom message, got", msg)
+	}
+}
+
+func TestMasterShareMaxRPS(t *testing.T) {
+
+	m := newTestMaster(t)
+	defer m.Close()
+
+	if err := m.SetMaxRPS(5); err != nil {
+		t.Fatal(err)
+	}
+
+	expectShare := func(conn net.Conn, share int64) {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		msg, err := readFrame(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n, _ := toInt64(msg.Data["max_rps"]); msg.Type != "max_rps" || n != share {
+			t.Errorf("worker should get a share of %d, got %v", share, msg)
+		}
+	}
+
+	conns := make([]net.Conn, 0)
+	for i, id := range []string{"worker1", "worker2"} {
+		conn, err := net.Dial("tcp", m.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		writeFrame(conn, newMessage("client_ready", nil, id))
+		waitForWorkers(t, m, i+1)
+		conns = append(conns, conn)
+	}
+	// shared again when worker2 joins
+	expectShare(conns[0], 5)
+	expectShare(conns[0], 3)
+	expectShare(conns[1], 2)
+
+	conns[1].Close()
+	waitForWorkers(t, m, 1)
+	expectShare(conns[0], 5)
+
+	m.SetMaxRPS(0)
+	expectShare(conns[0], 0)
+}
+
+func TestMasterWorkerReconnects(t *testing.T) {
+
+	m := newTestMaster(t)
+	defer m.Close()
+
+	conns := make([]net.Conn, 0)
+	for i := 0; i < 2; i++ {
+		conn, err := net.Dial("tcp", m.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		writeFrame(conn, newMessage("client_ready", nil, "worker1"))
+		waitForWorkers(t, m, 1)
+		conns = append(conns, conn)
+	}
+
+	conns[0].SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := readFrame(conns[0]); err == nil || isTimeout(err) {
+		t.Error("previous connection should be closed, got", err)
+	}
+	if err := m.Swarm(1, 1); err != nil {
+		t.Fatal(err)
+	}
+	conns[1].SetReadDeadline(time.Now().Add(time.Second))
+	if msg, err := readFrame(conns[1]); err != nil || msg.Type != "hatch" {
+		t.Error("new connection should receive hatch message, got", msg, err)
+	}
+}
+
+func isTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
+
+type stuckWorkerConn struct {
+	socketWorkerConn
+	closeOnce sync.Once
+	closed    chan bool
+}
+
+func (c *stuckWorkerConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+	return nil
+}
+
+func TestMasterSendToSlowWorker(t *testing.T) {
+
+	m := NewMaster("127.0.0.1", 0)
+	conn := &stuckWorkerConn{closed: make(chan bool)}
+	worker := &workerNode{id: "worker1", conn: conn, outbox: make(chan *message, 1)}
+
+	m.send(worker, newMessage("hatch", nil, m.nodeID))
+	if worker.closed {
+		t.Fatal("worker should not be disconnected while the outbox isn't full")
+	}
+	start := time.Now()
+	m.send(worker, newMessage("stop", nil, m.nodeID))
+	if !worker.closed {
+		t.Error("worker should be disconnected instead of losing the stop message")
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Error("send should not block with the lock held, took", elapsed)
+	}
+	select {
+	case <-conn.closed:
+	case <-time.After(time.Second):
+		t.Error("connection of the slow worker should be closed")
+	}
+}
+
+func TestReadFrameTooLarge(t *testing.T) {
+
+	header := []byte{0xff, 0xff, 0xff, 0xff}
+	if _, err := readFrame(bytes.NewReader(header)); err == nil || isInvalidMessage(err) {
+		t.Error("frames larger than the limit should close the connection, got", err)
+	}
+}
+
+func TestMasterShareMaxRPSAmongHatchedWorkers(t *testing.T) {
+
+	m := NewMaster("127.0.0.1", 0)
+	for _, id := range []string{"worker1", "worker2", "worker3", "worker4"} {
+		m.workers[id] = &workerNode{id: id, conn: &stuckWorkerConn{closed: make(chan bool)}, outbox: make(chan *message, 10)}
+	}
+	shares := func() []int64 {
+		result := make([]int64, 0)
+		for _, id := range m.sortedWorkerIDs() {
+			share := int64(-1)
+			for len(m.workers[id].outbox) > 0 {
+				if msg := <-m.workers[id].outbox; msg.Type == "max_rps" {
+					share, _ = toInt64(msg.Data["max_rps"])
+				}
+			}
+			result = append(result, share)
+		}
+		return result
+	}
+
+	m.SetMaxRPS(100)
+	if got := fmt.Sprint(shares()); got != "[25 25 25 25]" {
+		t.Error("max RPS should be shared among all the workers before a test, got", got)
+	}
+	if err := m.Swarm(2, 2); err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(shares()); got != "[50 50 -1 -1]" {
+		t.Error("max RPS should be shared among the workers with users, got", got)
+	}
+	m.SetMaxRPS(1)
+	if got := fmt.Sprint(shares()); got != "[1 1 -1 -1]" {
+		t.Error("each worker with users should get at least 1 RPS, got", got)
+	}
+}
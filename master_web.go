@@ -0,0 +1,138 @@
+package boomer
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// Handler returns the HTTP API of the master.
+//
+//	GET  /stats         aggregated stats, in the same format as --report-file
+//	GET  /workers       connected workers
+//	POST /swarm         start a test, form values are locust_count and hatch_rate, like locust,
+//	                    and optional max_rps, the cluster-wide max RPS
+//	POST /stop          stop the test
+//	POST /quit          tell all the workers to quit
+//	POST /stats/reset   clear the aggregated stats
+//	POST /message       send a custom message to all the workers, form values are type and data,
+//	                    a JSON object
+func (m *Master) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stats", m.handleStats)
+	mux.HandleFunc("/workers", m.handleWorkers)
+	mux.HandleFunc("/swarm", m.handleSwarm)
+	mux.HandleFunc("/stop", m.handleStop)
+	mux.HandleFunc("/quit", m.handleQuit)
+	mux.HandleFunc("/stats/reset", m.handleResetStats)
+	mux.HandleFunc("/message", m.handleSendMessage)
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeResult(w http.ResponseWriter, err error) {
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "message": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"success": true})
+}
+
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]interface{}{"success": false, "message": "method not allowed"})
+		return false
+	}
+	return true
+}
+
+func (m *Master) handleStats(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	state, userCount := m.State()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"state":      state,
+		"user_count": userCount,
+		"report":     m.report(),
+	})
+}
+
+func (m *Master) handleWorkers(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	writeJSON(w, http.StatusOK, m.Workers())
+}
+
+func (m *Master) handleSwarm(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	numClients, err := strconv.Atoi(r.FormValue("locust_count"))
+	if err != nil {
+		writeResult(w, err)
+		return
+	}
+	hatchRate, err := strconv.ParseFloat(r.FormValue("hatch_rate"), 64)
+	if err != nil {
+		writeResult(w, err)
+		return
+	}
+	if value := r.FormValue("max_rps"); value != "" {
+		maxRPS, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			writeResult(w, err)
+			return
+		}
+		if err := m.SetMaxRPS(maxRPS); err != nil {
+			writeResult(w, err)
+			return
+		}
+	}
+	writeResult(w, m.Swarm(numClients, hatchRate))
+}
+
+func (m *Master) handleStop(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	m.Stop()
+	writeResult(w, nil)
+}
+
+func (m *Master) handleQuit(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	m.Quit()
+	writeResult(w, nil)
+}
+
+func (m *Master) handleResetStats(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	m.ResetStats()
+	writeResult(w, nil)
+}
+
+func (m *Master) handleSendMessage(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	var data map[string]interface{}
+	if value := r.FormValue("data"); value != "" {
+		if err := json.Unmarshal([]byte(value), &data); err != nil {
+			writeResult(w, err)
+			return
+		}
+	}
+	writeResult(w, m.SendMessage(r.FormValue("type"), data))
+}
@@ -1,7 +1,9 @@
 package boomer
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"math"
 
 	"github.com/ugorji/go/codec"
 )
@@ -10,10 +12,15 @@ var (
 	mh codec.MsgpackHandle
 )
 
+func init() {
+	// set it once, messages are encoded and decoded concurrently by the master
+	mh.StructToArray = true
+}
+
 type message struct {
-	Type   string                 `codec: "type"`
-	Data   map[string]interface{} `codec: "data"`
-	NodeID string                 `codec: "node_id"`
+	Type   string                 `codec:"type"`
+	Data   map[string]interface{} `codec:"data"`
+	NodeID string                 `codec:"node_id"`
 }
 
 func newMessage(t string, data map[string]interface{}, nodeID string) (msg *message) {
@@ -24,23 +31,237 @@ func newMessage(t string, data map[string]interface{}, nodeID string) (msg *mess
 	}
 }
 
-func (m *message) serialize() (out []byte) {
-	mh.StructToArray = true
+func (m *message) serialize() (out []byte, err error) {
 	enc := codec.NewEncoderBytes(&out, &mh)
-	err := enc.Encode(m)
+	err = enc.Encode(m)
 	if err != nil {
-		log.Fatal("[msgpack] encode fail")
+		return nil, fmt.Errorf("[msgpack] encode fail: %v", err)
 	}
-	return
+	return out, nil
 }
 
-func newMessageFromBytes(raw []byte) *message {
-	mh.StructToArray = true
+// invalidMessageError means a message can't be decoded, but the connection is still usable.
+type invalidMessageError struct {
+	err error
+}
+
+func (e *invalidMessageError) Error() string {
+	return e.err.Error()
+}
+
+func isInvalidMessage(err error) bool {
+	_, ok := err.(*invalidMessageError)
+	return ok
+}
+
+func newMessageFromBytes(raw []byte) (*message, error) {
 	dec := codec.NewDecoderBytes(raw, &mh)
 	var newMsg = &message{}
 	err := dec.Decode(newMsg)
 	if err != nil {
-		log.Fatal("[msgpack] decode fail")
+		return nil, &invalidMessageError{fmt.Errorf("[msgpack] decode fail: %v", err)}
+	}
+	if newMsg.Type == "" {
+		return nil, &invalidMessageError{errors.New("[msgpack] message without type")}
+	}
+	return newMsg, nil
+}
+
+// Typed payloads of messages. Masters of different versions encode numbers differently,
+// so they are converted and validated here, instead of type assertions everywhere.
+
+// hatchMessage has workerIndex and workerCount from boomer's master, workerCount is 0 from locust.
+type hatchMessage struct {
+	numClients  int
+	hatchRate   float64
+	workerIndex int
+	workerCount int
+}
+
+func decodeHatch(data map[string]interface{}) (*hatchMessage, error) {
+	numClients, ok := toInt64(data["num_clients"])
+	if !ok {
+		return nil, fmt.Errorf("invalid num_clients in hatch message: %v", data["num_clients"])
+	}
+	hatchRate, ok := toFloat64(data["hatch_rate"])
+	if !ok {
+		return nil, fmt.Errorf("invalid hatch_rate in hatch message: %v", data["hatch_rate"])
+	}
+	if numClients <= 0 || numClients > math.MaxInt32 {
+		return nil, fmt.Errorf("num_clients in hatch message should be positive, not %d", numClients)
+	}
+	if hatchRate <= 0 || math.IsNaN(hatchRate) || math.IsInf(hatchRate, 0) {
+		return nil, fmt.Errorf("hatch_rate in hatch message should be positive, not %v", hatchRate)
+	}
+	hatch := &hatchMessage{
+		numClients: int(numClients),
+		hatchRate:  hatchRate,
+	}
+	if _, ok := data["worker_count"]; !ok {
+		return hatch, nil
+	}
+	workerIndex, ok := toInt64(data["worker_index"])
+	if !ok {
+		return nil, fmt.Errorf("invalid worker_index in hatch message: %v", data["worker_index"])
+	}
+	workerCount, ok := toInt64(data["worker_count"])
+	if !ok {
+		return nil, fmt.Errorf("invalid worker_count in hatch message: %v", data["worker_count"])
+	}
+	if workerCount <= 0 || workerCount > math.MaxInt32 || workerIndex < 0 || workerIndex >= workerCount {
+		return nil, fmt.Errorf("worker_index %d of %d workers in hatch message is out of range", workerIndex, workerCount)
+	}
+	hatch.workerIndex = int(workerIndex)
+	hatch.workerCount = int(workerCount)
+	return hatch, nil
+}
+
+type hatchCompleteMessage struct {
+	count int64
+}
+
+func decodeHatchComplete(data map[string]interface{}) (*hatchCompleteMessage, error) {
+	count, ok := toInt64(data["count"])
+	if !ok {
+		return nil, fmt.Errorf("invalid count in hatch_complete message: %v", data["count"])
+	}
+	return &hatchCompleteMessage{count: count}, nil
+}
+
+// maxRPSMessage is the share of the cluster-wide max RPS of a worker, 0 means unlimited.
+type maxRPSMessage struct {
+	maxRPS int64
+}
+
+func decodeMaxRPS(data map[string]interface{}) (*maxRPSMessage, error) {
+	maxRPS, ok := toInt64(data["max_rps"])
+	if !ok {
+		return nil, fmt.Errorf("invalid max_rps in max_rps message: %v", data["max_rps"])
+	}
+	if maxRPS < 0 {
+		return nil, fmt.Errorf("max_rps in max_rps message should not be negative, not %d", maxRPS)
+	}
+	return &maxRPSMessage{maxRPS: maxRPS}, nil
+}
+
+// rendezvousMessage is sent by workers when a user arrives at a rendezvous point,
+// or leaves it on timeout. id is unique on a worker.
+type rendezvousMessage struct {
+	name         string
+	participants int64
+	id           int64
+}
+
+func decodeRendezvous(data map[string]interface{}) (*rendezvousMessage, error) {
+	name, ok := toString(data["name"])
+	if !ok || name == "" {
+		return nil, fmt.Errorf("invalid name in rendezvous message: %v", data["name"])
+	}
+	id, ok := toInt64(data["id"])
+	if !ok {
+		return nil, fmt.Errorf("invalid id in rendezvous message: %v", data["id"])
+	}
+	// rendezvous_leave has no participants
+	participants, _ := toInt64(data["participants"])
+	if participants < 0 {
+		return nil, fmt.Errorf("participants in rendezvous message should not be negative, not %d", participants)
+	}
+	return &rendezvousMessage{
+		name:         name,
+		participants: participants,
+		id:           id,
+	}, nil
+}
+
+// rendezvousReleaseMessage releases the users of a worker waiting at a rendezvous point.
+type rendezvousReleaseMessage struct {
+	name string
+	ids  []int64
+}
+
+func decodeRendezvousRelease(data map[string]interface{}) (*rendezvousReleaseMessage, error) {
+	name, ok := toString(data["name"])
+	if !ok || name == "" {
+		return nil, fmt.Errorf("invalid name in rendezvous_release message: %v", data["name"])
+	}
+	ids, ok := toInt64Slice(data["ids"])
+	if !ok {
+		return nil, fmt.Errorf("invalid ids in rendezvous_release message: %v", data["ids"])
+	}
+	return &rendezvousReleaseMessage{name: name, ids: ids}, nil
+}
+
+type statsMessage struct {
+	entries       []*statsEntry
+	total         *statsEntry
+	errors        map[string]*statsError
+	userCount     int64
+	customMetrics map[string]interface{}
+}
+
+func decodeStats(data map[string]interface{}) (*statsMessage, error) {
+	s := &statsMessage{
+		errors: make(map[string]*statsError),
+	}
+
+	if data["user_count"] != nil {
+		userCount, ok := toInt64(data["user_count"])
+		if !ok {
+			return nil, fmt.Errorf("invalid user_count in stats message: %v", data["user_count"])
+		}
+		s.userCount = userCount
+	}
+
+	if data["stats"] != nil {
+		entries, ok := data["stats"].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid stats in stats message: %v", data["stats"])
+		}
+		for _, e := range entries {
+			em, ok := toMap(e)
+			if !ok {
+				return nil, fmt.Errorf("invalid stats entry: %v", e)
+			}
+			entry, err := newStatsEntryFromMap(em)
+			if err != nil {
+				return nil, err
+			}
+			s.entries = append(s.entries, entry)
+		}
+	}
+
+	total, ok := toMap(data["stats_total"])
+	if !ok {
+		return nil, fmt.Errorf("invalid stats_total in stats message: %v", data["stats_total"])
+	}
+	entry, err := newStatsEntryFromMap(total)
+	if err != nil {
+		return nil, err
+	}
+	s.total = entry
+
+	if data["errors"] != nil {
+		errs, ok := toMap(data["errors"])
+		if !ok {
+			return nil, fmt.Errorf("invalid errors in stats message: %v", data["errors"])
+		}
+		for key, e := range errs {
+			em, ok := toMap(e)
+			if !ok {
+				return nil, fmt.Errorf("invalid stats error: %v", e)
+			}
+			statsErr, err := newStatsErrorFromMap(em)
+			if err != nil {
+				return nil, err
+			}
+			s.errors[key] = statsErr
+		}
+	}
+
+	if data["custom_metrics"] != nil {
+		if s.customMetrics, ok = toMap(data["custom_metrics"]); !ok {
+			return nil, fmt.Errorf("invalid custom_metrics in stats message: %v", data["custom_metrics"])
+		}
 	}
-	return newMsg
+	return s, nil
 }
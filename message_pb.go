@@ -0,0 +1,279 @@
+package boomer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/myzhan/boomer/boomerpb"
+	"github.com/ugorji/go/codec"
+)
+
+// With --rpc=grpc, messages are converted to protobuf and back, data is decoded into
+// typed payloads, so it has the same shape on both sides as if it came from msgpack.
+// Data of custom messages is kept in msgpack.
+
+func newPBMessage(msg *message) (*boomerpb.Message, error) {
+	pb := &boomerpb.Message{
+		Type:   msg.Type,
+		NodeId: msg.NodeID,
+	}
+	if len(msg.Data) == 0 {
+		return pb, nil
+	}
+
+	switch msg.Type {
+	case "hatch":
+		hatch, err := decodeHatch(msg.Data)
+		if err != nil {
+			return nil, err
+		}
+		pb.Data = &boomerpb.Message_Hatch{Hatch: &boomerpb.Hatch{
+			NumClients:  int64(hatch.numClients),
+			HatchRate:   hatch.hatchRate,
+			WorkerIndex: int64(hatch.workerIndex),
+			WorkerCount: int64(hatch.workerCount),
+		}}
+	case "hatch_complete":
+		hatchComplete, err := decodeHatchComplete(msg.Data)
+		if err != nil {
+			return nil, err
+		}
+		pb.Data = &boomerpb.Message_HatchComplete{HatchComplete: &boomerpb.HatchComplete{
+			Count: hatchComplete.count,
+		}}
+	case "max_rps":
+		maxRPS, err := decodeMaxRPS(msg.Data)
+		if err != nil {
+			return nil, err
+		}
+		pb.Data = &boomerpb.Message_MaxRps{MaxRps: &boomerpb.MaxRPS{
+			MaxRps: maxRPS.maxRPS,
+		}}
+	case "rendezvous", "rendezvous_leave":
+		r, err := decodeRendezvous(msg.Data)
+		if err != nil {
+			return nil, err
+		}
+		pb.Data = &boomerpb.Message_Rendezvous{Rendezvous: &boomerpb.Rendezvous{
+			Name:         r.name,
+			Participants: r.participants,
+			Id:           r.id,
+		}}
+	case "rendezvous_release":
+		release, err := decodeRendezvousRelease(msg.Data)
+		if err != nil {
+			return nil, err
+		}
+		pb.Data = &boomerpb.Message_RendezvousRelease{RendezvousRelease: &boomerpb.RendezvousRelease{
+			Name: release.name,
+			Ids:  release.ids,
+		}}
+	case "stats":
+		s, err := decodeStats(msg.Data)
+		if err != nil {
+			return nil, err
+		}
+		pbStats, err := newPBStats(s)
+		if err != nil {
+			return nil, err
+		}
+		pb.Data = &boomerpb.Message_Stats{Stats: pbStats}
+	default:
+		if builtinMessages[msg.Type] {
+			return nil, fmt.Errorf("%s message has no protobuf schema for its data", msg.Type)
+		}
+		var raw []byte
+		if err := codec.NewEncoderBytes(&raw, &mh).Encode(msg.Data); err != nil {
+			return nil, fmt.Errorf("[msgpack] encode fail: %v", err)
+		}
+		pb.Data = &boomerpb.Message_CustomData{CustomData: raw}
+	}
+	return pb, nil
+}
+
+func newPBStats(s *statsMessage) (*boomerpb.Stats, error) {
+	pb := &boomerpb.Stats{
+		StatsTotal: newPBStatsEntry(s.total),
+		Errors:     make(map[string]*boomerpb.StatsError, len(s.errors)),
+		UserCount:  s.userCount,
+	}
+	for _, entry := range s.entries {
+		pb.Stats = append(pb.Stats, newPBStatsEntry(entry))
+	}
+	for key, err := range s.errors {
+		pb.Errors[key] = &boomerpb.StatsError{
+			Name:        err.name,
+			Method:      err.method,
+			Error:       err.error,
+			Occurrences: err.occurences,
+		}
+	}
+	if s.customMetrics != nil {
+		m := newCustomMetrics()
+		if err := m.mergeReport(s.customMetrics); err != nil {
+			return nil, err
+		}
+		pb.CustomMetrics = newPBCustomMetrics(m)
+	}
+	return pb, nil
+}
+
+func newPBStatsEntry(s *statsEntry) *boomerpb.StatsEntry {
+	return &boomerpb.StatsEntry{
+		Name:                 s.name,
+		Method:               s.method,
+		NumRequests:          s.numRequests,
+		NumFailures:          s.numFailures,
+		TotalResponseTime:    s.totalResponseTime,
+		MinResponseTime:      s.minResponseTime,
+		MaxResponseTime:      s.maxResponseTime,
+		TotalContentLength:   s.totalContentLength,
+		StartTime:            s.startTime,
+		LastRequestTimestamp: s.lastRequestTimestamp,
+		ResponseTimes:        s.responseTimes,
+		NumReqsPerSec:        s.numReqsPerSec,
+	}
+}
+
+func newPBCustomMetrics(m *customMetrics) *boomerpb.CustomMetrics {
+	pb := &boomerpb.CustomMetrics{
+		Counters: m.counters,
+		Gauges:   m.gauges,
+		Trends:   make(map[string]*boomerpb.Trend, len(m.trends)),
+	}
+	for name, t := range m.trends {
+		trend := &boomerpb.Trend{
+			Count: t.count,
+			Sum:   t.sum,
+			Min:   t.min,
+			Max:   t.max,
+		}
+		for value, count := range t.values {
+			trend.Values = append(trend.Values, &boomerpb.TrendValue{Value: value, Count: count})
+		}
+		pb.Trends[name] = trend
+	}
+	return pb
+}
+
+// newMessageFromPB is the reverse of newPBMessage.
+func newMessageFromPB(pb *boomerpb.Message) (*message, error) {
+	if pb.Type == "" {
+		return nil, &invalidMessageError{errors.New("[protobuf] message without type")}
+	}
+	msg := newMessage(pb.Type, nil, pb.NodeId)
+
+	switch data := pb.Data.(type) {
+	case *boomerpb.Message_Hatch:
+		msg.Data = map[string]interface{}{
+			"num_clients": data.Hatch.NumClients,
+			"hatch_rate":  data.Hatch.HatchRate,
+		}
+		if data.Hatch.WorkerCount > 0 {
+			msg.Data["worker_index"] = data.Hatch.WorkerIndex
+			msg.Data["worker_count"] = data.Hatch.WorkerCount
+		}
+	case *boomerpb.Message_HatchComplete:
+		msg.Data = map[string]interface{}{
+			"count": data.HatchComplete.Count,
+		}
+	case *boomerpb.Message_MaxRps:
+		msg.Data = map[string]interface{}{
+			"max_rps": data.MaxRps.MaxRps,
+		}
+	case *boomerpb.Message_Rendezvous:
+		msg.Data = map[string]interface{}{
+			"name":         data.Rendezvous.Name,
+			"participants": data.Rendezvous.Participants,
+			"id":           data.Rendezvous.Id,
+		}
+	case *boomerpb.Message_RendezvousRelease:
+		msg.Data = map[string]interface{}{
+			"name": data.RendezvousRelease.Name,
+			"ids":  data.RendezvousRelease.Ids,
+		}
+	case *boomerpb.Message_Stats:
+		msg.Data = newStatsDataFromPB(data.Stats)
+	case *boomerpb.Message_CustomData:
+		if err := codec.NewDecoderBytes(data.CustomData, &mh).Decode(&msg.Data); err != nil {
+			return nil, &invalidMessageError{fmt.Errorf("[msgpack] decode fail: %v", err)}
+		}
+	}
+	return msg, nil
+}
+
+func newStatsDataFromPB(pb *boomerpb.Stats) map[string]interface{} {
+	entries := make([]interface{}, 0, len(pb.Stats))
+	for _, entry := range pb.Stats {
+		entries = append(entries, newStatsEntryFromPB(entry).serialize())
+	}
+	statsErrors := make(map[string]interface{}, len(pb.Errors))
+	for key, err := range pb.Errors {
+		statsErrors[key] = (&statsError{
+			name:       err.Name,
+			method:     err.Method,
+			error:      err.Error,
+			occurences: err.Occurrences,
+		}).toMap()
+	}
+
+	data := map[string]interface{}{
+		"stats":      entries,
+		"errors":     statsErrors,
+		"user_count": pb.UserCount,
+	}
+	if pb.StatsTotal != nil {
+		data["stats_total"] = newStatsEntryFromPB(pb.StatsTotal).serialize()
+	}
+	if pb.CustomMetrics != nil {
+		data["custom_metrics"] = newCustomMetricsFromPB(pb.CustomMetrics).serialize()
+	}
+	return data
+}
+
+func newStatsEntryFromPB(pb *boomerpb.StatsEntry) *statsEntry {
+	s := &statsEntry{
+		name:                 pb.Name,
+		method:               pb.Method,
+		numRequests:          pb.NumRequests,
+		numFailures:          pb.NumFailures,
+		totalResponseTime:    pb.TotalResponseTime,
+		minResponseTime:      pb.MinResponseTime,
+		maxResponseTime:      pb.MaxResponseTime,
+		totalContentLength:   pb.TotalContentLength,
+		startTime:            pb.StartTime,
+		lastRequestTimestamp: pb.LastRequestTimestamp,
+		responseTimes:        pb.ResponseTimes,
+		numReqsPerSec:        pb.NumReqsPerSec,
+	}
+	// empty maps are decoded as nil
+	if s.responseTimes == nil {
+		s.responseTimes = make(map[int64]int64)
+	}
+	if s.numReqsPerSec == nil {
+		s.numReqsPerSec = make(map[int64]int64)
+	}
+	return s
+}
+
+func newCustomMetricsFromPB(pb *boomerpb.CustomMetrics) *customMetrics {
+	m := newCustomMetrics()
+	for name, v := range pb.Counters {
+		m.counters[name] = v
+	}
+	for name, v := range pb.Gauges {
+		m.gauges[name] = v
+	}
+	for name, t := range pb.Trends {
+		trend := newTrendStats()
+		trend.count = t.Count
+		trend.sum = t.Sum
+		trend.min = t.Min
+		trend.max = t.Max
+		for _, v := range t.Values {
+			trend.values[v.Value] += v.Count
+		}
+		m.trends[name] = trend
+	}
+	return m
+}
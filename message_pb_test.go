@@ -0,0 +1,191 @@
+package boomer
+
+import (
+	"testing"
+
+	"github.com/myzhan/boomer/boomerpb"
+	"google.golang.org/protobuf/proto"
+)
+
+// passPB sends msg through protobuf, like it's sent with --rpc=grpc.
+func passPB(t *testing.T, msg *message) *message {
+	pb, err := newPBMessage(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := proto.Marshal(pb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decodedPB := &boomerpb.Message{}
+	if err := proto.Unmarshal(raw, decodedPB); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := newMessageFromPB(decodedPB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Type != msg.Type || decoded.NodeID != msg.NodeID {
+		t.Errorf("message mismatched, %v != %v", decoded, msg)
+	}
+	return decoded
+}
+
+func TestPBHatch(t *testing.T) {
+	decoded := passPB(t, newMessage("hatch", map[string]interface{}{
+		"num_clients": int64(10),
+		"hatch_rate":  0.5,
+	}, "master"))
+
+	hatch, err := decodeHatch(decoded.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hatch.numClients != 10 || hatch.hatchRate != 0.5 || hatch.workerCount != 0 {
+		t.Error("hatch message mismatched.", hatch)
+	}
+
+	decoded = passPB(t, newMessage("hatch", map[string]interface{}{
+		"num_clients":  int64(10),
+		"hatch_rate":   0.5,
+		"worker_index": int64(0),
+		"worker_count": int64(3),
+	}, "master"))
+	hatch, err = decodeHatch(decoded.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hatch.workerIndex != 0 || hatch.workerCount != 3 {
+		t.Error("hatch message mismatched.", hatch)
+	}
+
+	decoded = passPB(t, newMessage("hatch_complete", map[string]interface{}{"count": int32(10)}, "worker"))
+	hatchComplete, err := decodeHatchComplete(decoded.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hatchComplete.count != 10 {
+		t.Error("hatch_complete message mismatched.", hatchComplete)
+	}
+
+	decoded = passPB(t, newMessage("client_ready", nil, "worker"))
+	if len(decoded.Data) != 0 {
+		t.Error("client_ready message should have no data, got", decoded.Data)
+	}
+}
+
+func TestPBStats(t *testing.T) {
+	s := newRequestStats()
+	s.logRequest("http", "foo", 100, 10, nil)
+	s.logRequest("http", "foo", 200, 10, nil)
+	s.logError("http", "foo", "error", nil)
+	m := newCustomMetrics()
+	m.log(&metricSample{metricCounter, "hits", 3})
+	m.log(&metricSample{metricGauge, "depth", 1.5})
+	m.log(&metricSample{metricTrend, "size", 120})
+	m.log(&metricSample{metricTrend, "size", 120})
+
+	decoded := passPB(t, newMessage("stats", map[string]interface{}{
+		"stats":          s.serializeStats(),
+		"stats_total":    s.total.getStrippedReport(),
+		"errors":         s.serializeErrors(),
+		"user_count":     int32(5),
+		"custom_metrics": m.serialize(),
+	}, "worker"))
+
+	stats, err := decodeStats(decoded.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.userCount != 5 || len(stats.entries) != 1 || len(stats.errors) != 1 {
+		t.Fatal("stats message mismatched.", stats)
+	}
+	entry := stats.entries[0]
+	if entry.name != "foo" || entry.numRequests != 2 || entry.maxResponseTime != 200 || entry.responseTimes[100] != 1 {
+		t.Error("stats entry mismatched.", entry)
+	}
+	if stats.total.numRequests != 2 || stats.total.numFailures != 1 {
+		t.Error("stats total mismatched.", stats.total)
+	}
+	for _, err := range stats.errors {
+		if err.error != "error" || err.occurences != 1 {
+			t.Error("stats error mismatched.", err)
+		}
+	}
+
+	metrics := newCustomMetrics()
+	if err := metrics.mergeReport(stats.customMetrics); err != nil {
+		t.Fatal(err)
+	}
+	if metrics.counters["hits"] != 3 || metrics.gauges["depth"] != 1.5 {
+		t.Error("custom metrics mismatched.", metrics.counters, metrics.gauges)
+	}
+	if trend := metrics.trends["size"]; trend == nil || trend.count != 2 || trend.values[120] != 2 {
+		t.Error("trend mismatched.", trend)
+	}
+}
+
+func TestPBWithoutSchema(t *testing.T) {
+	if _, err := newPBMessage(newMessage("stop", map[string]interface{}{"a": 1}, "")); err == nil {
+		t.Error("data without schema should be an error")
+	}
+	if _, err := newMessageFromPB(&boomerpb.Message{}); !isInvalidMessage(err) {
+		t.Error("message without type should be invalid, got", err)
+	}
+}
+
+func TestPBCustomData(t *testing.T) {
+	decoded := passPB(t, newMessage("tokens", map[string]interface{}{
+		"tokens": []string{"a", "b"},
+		"count":  int64(2),
+	}, "master"))
+
+	tokens, _ := decoded.Data["tokens"].([]interface{})
+	if len(tokens) != 2 {
+		t.Fatal("custom data mismatched.", decoded.Data)
+	}
+	if token, _ := toString(tokens[1]); token != "b" {
+		t.Error("custom data mismatched.", decoded.Data)
+	}
+	if count, _ := toInt64(decoded.Data["count"]); count != 2 {
+		t.Error("custom data mismatched.", decoded.Data)
+	}
+}
+
+func TestPBMaxRPS(t *testing.T) {
+	decoded := passPB(t, newMessage("max_rps", map[string]interface{}{"max_rps": int64(100)}, "master"))
+	maxRPS, err := decodeMaxRPS(decoded.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if maxRPS.maxRPS != 100 {
+		t.Error("max_rps message mismatched.", maxRPS)
+	}
+}
+
+func TestPBRendezvous(t *testing.T) {
+	decoded := passPB(t, newMessage("rendezvous", map[string]interface{}{
+		"name":         "sale",
+		"participants": int64(100),
+		"id":           int64(1),
+	}, "worker"))
+	r, err := decodeRendezvous(decoded.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.name != "sale" || r.participants != 100 || r.id != 1 {
+		t.Error("rendezvous message mismatched.", r)
+	}
+
+	decoded = passPB(t, newMessage("rendezvous_release", map[string]interface{}{
+		"name": "sale",
+		"ids":  []int64{1, 2},
+	}, "master"))
+	release, err := decodeRendezvousRelease(decoded.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.name != "sale" || len(release.ids) != 2 || release.ids[1] != 2 {
+		t.Error("rendezvous_release message mismatched.", release)
+	}
+}
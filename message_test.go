@@ -1,6 +1,9 @@
 package boomer
 
-import "testing"
+import (
+	"math"
+	"testing"
+)
 
 func TestEncodeAndDecode(t *testing.T) {
 
@@ -9,8 +12,14 @@ func TestEncodeAndDecode(t *testing.T) {
 	data["b"] = "hello"
 	msg := newMessage("test", data, "nodeID")
 
-	encoded := msg.serialize()
-	decoded := newMessageFromBytes(encoded)
+	encoded, err := msg.serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := newMessageFromBytes(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if msg.Type != decoded.Type {
 		t.Error("message type mismatched.")
@@ -28,3 +37,132 @@ func TestEncodeAndDecode(t *testing.T) {
 		t.Error("message data mismatched.", msg.Data, decoded.Data)
 	}
 }
+
+func TestDecodeInvalidMessage(t *testing.T) {
+	for _, raw := range [][]byte{nil, {0xc1}, {0x93, 0x01, 0x02}, {0x93, 0xa0, 0xc0, 0xc0}} {
+		msg, err := newMessageFromBytes(raw)
+		if err == nil {
+			t.Errorf("%x should be invalid, got %v", raw, msg)
+		}
+		if !isInvalidMessage(err) {
+			t.Errorf("%x: %v should be an invalidMessageError", raw, err)
+		}
+	}
+}
+
+func TestDecodeHatch(t *testing.T) {
+	// locust sends integers, or floats for hatch_rate divided among workers
+	hatch, err := decodeHatch(map[string]interface{}{
+		"num_clients": uint64(10),
+		"hatch_rate":  int64(5),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hatch.numClients != 10 || hatch.hatchRate != 5 {
+		t.Error("hatch message mismatched.", hatch)
+	}
+
+	hatch, err = decodeHatch(map[string]interface{}{
+		"num_clients": int64(3),
+		"hatch_rate":  0.5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hatch.numClients != 3 || hatch.hatchRate != 0.5 || hatch.workerCount != 0 {
+		t.Error("hatch message mismatched.", hatch)
+	}
+
+	// boomer's master sends the index of the worker
+	hatch, err = decodeHatch(map[string]interface{}{
+		"num_clients":  int64(3),
+		"hatch_rate":   0.5,
+		"worker_index": uint64(1),
+		"worker_count": int64(2),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hatch.workerIndex != 1 || hatch.workerCount != 2 {
+		t.Error("hatch message mismatched.", hatch)
+	}
+
+	invalid := []map[string]interface{}{
+		nil,
+		{"num_clients": int64(10)},
+		{"num_clients": "10", "hatch_rate": 1.0},
+		{"num_clients": int64(0), "hatch_rate": 1.0},
+		{"num_clients": int64(-1), "hatch_rate": 1.0},
+		{"num_clients": int64(10), "hatch_rate": 0.0},
+		{"num_clients": int64(10), "hatch_rate": math.NaN()},
+		{"num_clients": int64(10), "hatch_rate": math.Inf(1)},
+		{"num_clients": int64(10), "hatch_rate": 1.0, "worker_count": int64(2)},
+		{"num_clients": int64(10), "hatch_rate": 1.0, "worker_index": int64(2), "worker_count": int64(2)},
+		{"num_clients": int64(10), "hatch_rate": 1.0, "worker_index": int64(0), "worker_count": int64(0)},
+	}
+	for _, data := range invalid {
+		if hatch, err := decodeHatch(data); err == nil {
+			t.Errorf("%v should be invalid, got %v", data, hatch)
+		}
+	}
+}
+
+func TestDecodeStats(t *testing.T) {
+	if _, err := decodeStats(map[string]interface{}{}); err == nil {
+		t.Error("stats message without stats_total should be invalid")
+	}
+	if _, err := decodeStats(map[string]interface{}{"stats": "oops"}); err == nil {
+		t.Error("stats message with invalid stats should be invalid")
+	}
+
+	stats := newRequestStats()
+	entry := stats.get("http://example.com", "GET", nil)
+	entry.log(100, 1024)
+	stats.total.log(100, 1024)
+	msg := newMessage("stats", map[string]interface{}{
+		"stats":       []interface{}{entry.serialize()},
+		"stats_total": stats.total.serialize(),
+		"errors":      map[string]interface{}{},
+		"user_count":  int64(10),
+	}, "nodeID")
+
+	encoded, err := msg.serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := newMessageFromBytes(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := decodeStats(decoded.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.userCount != 10 || len(s.entries) != 1 || s.total.numRequests != 1 {
+		t.Error("stats message mismatched.", s)
+	}
+	if s.entries[0].name != "http://example.com" || s.entries[0].totalContentLength != 1024 {
+		t.Error("stats entry mismatched.", s.entries[0])
+	}
+}
+
+func FuzzNewMessageFromBytes(f *testing.F) {
+	for _, msgType := range []string{"hatch", "stats", "stop"} {
+		encoded, _ := newMessage(msgType, map[string]interface{}{
+			"num_clients": int64(10),
+			"hatch_rate":  1.5,
+		}, "nodeID").serialize()
+		f.Add(encoded)
+	}
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		msg, err := newMessageFromBytes(raw)
+		if err != nil {
+			return
+		}
+		// none of them should panic
+		decodeHatch(msg.Data)
+		decodeHatchComplete(msg.Data)
+		decodeStats(msg.Data)
+	})
+}
@@ -0,0 +1,335 @@
+package boomer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync/atomic"
+)
+
+// Besides requests, tasks can record their own metrics, like queue depth seen, cache hit ratio,
+// or bytes uploaded. They are aggregated in the stats goroutine, sent to the master as extra data
+// in every report, and included in the final report.
+
+const (
+	metricCounter = iota
+	metricGauge
+	metricTrend
+)
+
+type metricSample struct {
+	kind  int
+	name  string
+	value float64
+}
+
+// Counter is a metric that only goes up, like the number of cache hits.
+type Counter struct {
+	name string
+}
+
+// NewCounter returns a handle of the counter named name.
+func NewCounter(name string) *Counter {
+	return &Counter{name: name}
+}
+
+// Add adds delta to the counter.
+func (c *Counter) Add(delta int64) {
+	recordMetric(&metricSample{metricCounter, c.name, float64(delta)})
+}
+
+// Inc increments the counter by 1.
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Gauge is a metric that holds the last value set, like queue depth.
+type Gauge struct {
+	name string
+}
+
+// NewGauge returns a handle of the gauge named name.
+func NewGauge(name string) *Gauge {
+	return &Gauge{name: name}
+}
+
+// Set sets the current value of the gauge.
+func (g *Gauge) Set(value float64) {
+	recordMetric(&metricSample{metricGauge, g.name, value})
+}
+
+// Trend is a metric that keeps the distribution of values, like bytes uploaded per request.
+type Trend struct {
+	name string
+}
+
+// NewTrend returns a handle of the trend named name.
+func NewTrend(name string) *Trend {
+	return &Trend{name: name}
+}
+
+// Add records a value of the trend.
+func (t *Trend) Add(value float64) {
+	recordMetric(&metricSample{metricTrend, t.name, value})
+}
+
+// recordMetric follows --request-overflow like requests, dropped samples are counted as dropped requests.
+func recordMetric(sample *metricSample) {
+	select {
+	case metricChannel <- sample:
+		return
+	default:
+	}
+
+	timeout, stop := requestOverflowTimer()
+	defer stop()
+	select {
+	case metricChannel <- sample:
+	case <-timeout:
+		atomic.AddInt64(&droppedRequests, 1)
+	}
+}
+
+type trendStats struct {
+	count  int64
+	sum    float64
+	min    float64
+	max    float64
+	values map[float64]int64
+}
+
+func newTrendStats() *trendStats {
+	return &trendStats{
+		values: make(map[float64]int64),
+	}
+}
+
+func (t *trendStats) add(value float64) {
+	if t.count == 0 || value < t.min {
+		t.min = value
+	}
+	if t.count == 0 || value > t.max {
+		t.max = value
+	}
+	t.count++
+	t.sum += value
+
+	// like response times, values are rounded to keep the data sent to the master small
+	t.values[roundTrendValue(value)]++
+}
+
+func (t *trendStats) merge(other *trendStats) {
+	if other.count == 0 {
+		return
+	}
+	if t.count == 0 || other.min < t.min {
+		t.min = other.min
+	}
+	if t.count == 0 || other.max > t.max {
+		t.max = other.max
+	}
+	t.count += other.count
+	t.sum += other.sum
+	for k, v := range other.values {
+		t.values[k] += v
+	}
+}
+
+func (t *trendStats) avg() float64 {
+	if t.count == 0 {
+		return 0
+	}
+	return t.sum / float64(t.count)
+}
+
+// percentile is calculated the same way as getResponseTimePercentile.
+func (t *trendStats) percentile(percent float64) float64 {
+	values := make([]float64, 0, len(t.values))
+	for k := range t.values {
+		values = append(values, k)
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(values)))
+
+	numOfValues := int64(float64(t.count) * percent)
+	processedCount := int64(0)
+	for _, v := range values {
+		processedCount += t.values[v]
+		if t.count-processedCount <= numOfValues {
+			return v
+		}
+	}
+	return 0
+}
+
+func (t *trendStats) serialize() map[string]interface{} {
+	result := make(map[string]interface{})
+	result["count"] = t.count
+	result["sum"] = t.sum
+	result["min"] = t.min
+	result["max"] = t.max
+	result["values"] = t.values
+	return result
+}
+
+// newTrendStatsFromMap is the reverse of serialize.
+func newTrendStatsFromMap(v interface{}) (*trendStats, error) {
+	m, ok := toMap(v)
+	if !ok {
+		return nil, fmt.Errorf("%v is not a map", v)
+	}
+	t := newTrendStats()
+	if t.count, ok = toInt64(m["count"]); !ok {
+		return nil, fmt.Errorf("invalid count: %v", m["count"])
+	}
+	for key, field := range map[string]*float64{"sum": &t.sum, "min": &t.min, "max": &t.max} {
+		if *field, ok = toFloat64(m[key]); !ok {
+			return nil, fmt.Errorf("invalid %s: %v", key, m[key])
+		}
+	}
+	values, ok := m["values"].(map[interface{}]interface{})
+	if !ok {
+		if typed, isTyped := m["values"].(map[float64]int64); isTyped {
+			for k, v := range typed {
+				t.values[k] = v
+			}
+			return t, nil
+		}
+		return nil, fmt.Errorf("invalid values: %v", m["values"])
+	}
+	for k, v := range values {
+		value, ok := toFloat64(k)
+		if !ok {
+			return nil, fmt.Errorf("invalid value: %v", k)
+		}
+		count, ok := toInt64(v)
+		if !ok {
+			return nil, fmt.Errorf("invalid count of value %v: %v", k, v)
+		}
+		t.values[value] = count
+	}
+	return t, nil
+}
+
+// roundTrendValue keeps 2 significant digits, so that 147 becomes 150 and 0.1234 becomes 0.12.
+func roundTrendValue(value float64) float64 {
+	if value == 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+		return value
+	}
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(value, 'g', 2, 64), 64)
+	return rounded
+}
+
+type customMetrics struct {
+	counters map[string]int64
+	gauges   map[string]float64
+	trends   map[string]*trendStats
+}
+
+func newCustomMetrics() *customMetrics {
+	return &customMetrics{
+		counters: make(map[string]int64),
+		gauges:   make(map[string]float64),
+		trends:   make(map[string]*trendStats),
+	}
+}
+
+func (m *customMetrics) log(sample *metricSample) {
+	switch sample.kind {
+	case metricCounter:
+		m.counters[sample.name] += int64(sample.value)
+	case metricGauge:
+		m.gauges[sample.name] = sample.value
+	case metricTrend:
+		trend, ok := m.trends[sample.name]
+		if !ok {
+			trend = newTrendStats()
+			m.trends[sample.name] = trend
+		}
+		trend.add(sample.value)
+	}
+}
+
+func (m *customMetrics) isEmpty() bool {
+	return len(m.counters) == 0 && len(m.gauges) == 0 && len(m.trends) == 0
+}
+
+// snapshot returns a deep copy of m, so that it can be read outside the stats goroutine.
+func (m *customMetrics) snapshot() *customMetrics {
+	copied := newCustomMetrics()
+	for k, v := range m.counters {
+		copied.counters[k] = v
+	}
+	for k, v := range m.gauges {
+		copied.gauges[k] = v
+	}
+	for k, v := range m.trends {
+		trend := newTrendStats()
+		trend.merge(v)
+		copied.trends[k] = trend
+	}
+	return copied
+}
+
+func (m *customMetrics) serialize() map[string]interface{} {
+	trends := make(map[string]interface{}, len(m.trends))
+	for k, v := range m.trends {
+		trends[k] = v.serialize()
+	}
+
+	result := make(map[string]interface{})
+	result["counters"] = m.counters
+	result["gauges"] = m.gauges
+	result["trends"] = trends
+	return result
+}
+
+// mergeReport adds custom metrics reported by a worker to m, gauges are overwritten by the last report.
+func (m *customMetrics) mergeReport(report map[string]interface{}) error {
+	if counters, ok := toMap(report["counters"]); ok {
+		for name, v := range counters {
+			n, ok := toInt64(v)
+			if !ok {
+				return fmt.Errorf("invalid counter %s: %v", name, v)
+			}
+			m.counters[name] += n
+		}
+	}
+	if gauges, ok := toMap(report["gauges"]); ok {
+		for name, v := range gauges {
+			f, ok := toFloat64(v)
+			if !ok {
+				return fmt.Errorf("invalid gauge %s: %v", name, v)
+			}
+			m.gauges[name] = f
+		}
+	}
+	if trends, ok := toMap(report["trends"]); ok {
+		for name, v := range trends {
+			trend, err := newTrendStatsFromMap(v)
+			if err != nil {
+				return fmt.Errorf("invalid trend %s: %v", name, err)
+			}
+			if _, ok := m.trends[name]; !ok {
+				m.trends[name] = newTrendStats()
+			}
+			m.trends[name].merge(trend)
+		}
+	}
+	return nil
+}
+
+// getStrippedReport serializes m and resets counters and trends, gauges keep their last values.
+func (m *customMetrics) getStrippedReport() map[string]interface{} {
+	report := m.serialize()
+	m.counters = make(map[string]int64)
+	m.trends = make(map[string]*trendStats)
+	return report
+}
+
+var metrics = newCustomMetrics()
+
+// summaryMetrics accumulates custom metrics of the whole test.
+var summaryMetrics = newCustomMetrics()
+
+var metricChannel = make(chan *metricSample, 100)
@@ -0,0 +1,51 @@
+package boomer
+
+import "testing"
+
+func TestRoundTrendValue(t *testing.T) {
+
+	cases := map[float64]float64{
+		147:    150,
+		3432:   3400,
+		0.1234: 0.12,
+		-58760: -59000,
+		0:      0,
+	}
+	for value, expected := range cases {
+		if rounded := roundTrendValue(value); rounded != expected {
+			t.Errorf("%f should be rounded to %f, not %f", value, expected, rounded)
+		}
+	}
+}
+
+func TestCustomMetrics(t *testing.T) {
+
+	m := newCustomMetrics()
+	for i := 1; i <= 100; i++ {
+		m.log(&metricSample{metricCounter, "hits", 1})
+		m.log(&metricSample{metricGauge, "depth", float64(i)})
+		m.log(&metricSample{metricTrend, "bytes", float64(i)})
+	}
+
+	if m.counters["hits"] != 100 {
+		t.Error("counter should be 100, not", m.counters["hits"])
+	}
+	if m.gauges["depth"] != 100 {
+		t.Error("gauge should hold the last value, not", m.gauges["depth"])
+	}
+	trend := m.trends["bytes"]
+	if trend.count != 100 || trend.min != 1 || trend.max != 100 || trend.avg() != 50.5 {
+		t.Error("trend is aggregated incorrectly", trend)
+	}
+	if p := trend.percentile(0.5); p != 51 {
+		t.Error("median of trend should be 51, not", p)
+	}
+
+	m.getStrippedReport()
+	if len(m.counters) != 0 || len(m.trends) != 0 {
+		t.Error("counters and trends should be reset after report")
+	}
+	if m.gauges["depth"] != 100 {
+		t.Error("gauges should be kept after report")
+	}
+}
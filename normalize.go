@@ -0,0 +1,90 @@
+package boomer
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Error messages often embed IDs, ports or timestamps, like "dial tcp 10.0.0.5:53124: i/o timeout".
+// They are normalized before being grouped, so that the same failure is reported only once.
+
+type errorRule struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// builtinErrorRules strip UUIDs, IP addresses and ports. Ports are only stripped after hosts,
+// which are IP addresses, localhost or names with a dot, so that "code:500" is kept.
+var builtinErrorRules = []*errorRule{
+	{regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`), "<uuid>"},
+	{regexp.MustCompile(`\[[0-9a-fA-F:]*:[0-9a-fA-F:.]*\]`), "[<ip>]"},
+	{regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`), "<ip>"},
+	{regexp.MustCompile(`(\blocalhost|\b[A-Za-z0-9\-_]+(?:\.[A-Za-z0-9\-_]+)+|<ip>|\]):\d{1,5}\b`), "$1:<port>"},
+}
+
+var errorRulesLock sync.RWMutex
+var errorRules []*errorRule
+
+// AddErrorRule registers a rule to normalize error messages before they are grouped.
+// Every match of pattern is replaced with replacement, which can refer to submatches like $1.
+// Rules are applied in the order they are added, before the built-in ones.
+func AddErrorRule(pattern string, replacement string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	errorRulesLock.Lock()
+	errorRules = append(errorRules, &errorRule{re, replacement})
+	errorRulesLock.Unlock()
+	return nil
+}
+
+func normalizeError(err string) string {
+	errorRulesLock.RLock()
+	for _, rule := range errorRules {
+		err = rule.pattern.ReplaceAllString(err, rule.replacement)
+	}
+	errorRulesLock.RUnlock()
+
+	if *normalizeErrors {
+		for _, rule := range builtinErrorRules {
+			err = rule.pattern.ReplaceAllString(err, rule.replacement)
+		}
+	}
+	return err
+}
+
+// errorRuleFlag adds rules from repeated --error-rule flags like "pattern=>replacement".
+type errorRuleFlag []string
+
+func (f *errorRuleFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *errorRuleFlag) Set(value string) error {
+	parts := strings.SplitN(value, "=>", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("error rule should be like pattern=>replacement, not %q", value)
+	}
+	if err := AddErrorRule(parts[0], parts[1]); err != nil {
+		return err
+	}
+	*f = append(*f, value)
+	return nil
+}
+
+// Errors beyond maxErrorKeys distinct keys in a report are counted in this bucket.
+const otherErrors = "other errors"
+
+var normalizeErrors *bool
+var maxErrorKeys *int
+var errorRuleFlags errorRuleFlag
+
+func init() {
+	normalizeErrors = flag.Bool("normalize-errors", true, "Strip IP addresses, ports and UUIDs from error messages before grouping them.")
+	maxErrorKeys = flag.Int("max-error-keys", 100, "Max distinct errors in a report, the rest are counted as \""+otherErrors+"\". 0 means no limit.")
+	flag.Var(&errorRuleFlags, "error-rule", "Regex rule to normalize error messages, like 'order [0-9]+=>order <id>'. Can be repeated.")
+}
@@ -0,0 +1,61 @@
+package boomer
+
+import "testing"
+
+func TestNormalizeError(t *testing.T) {
+
+	cases := map[string]string{
+		"dial tcp 10.0.0.5:53124: i/o timeout":                                 "dial tcp <ip>:<port>: i/o timeout",
+		"read tcp 127.0.0.1:5555->10.0.0.1:80: read: connection reset by peer": "read tcp <ip>:<port>-><ip>:<port>: read: connection reset by peer",
+		"dial tcp [::1]:8080: connect: connection refused":                     "dial tcp [<ip>]:<port>: connect: connection refused",
+		"Get http://localhost:8089/users/0b6e6a4e-3c1f-4e1a-9b4e-2f1d2c3b4a59": "Get http://localhost:<port>/users/<uuid>",
+		"unexpected status code 500":                                           "unexpected status code 500",
+		"Post https://api.example.com:443/orders: code:500, retry:3":           "Post https://api.example.com:<port>/orders: code:500, retry:3",
+	}
+	for origin, expected := range cases {
+		if normalized := normalizeError(origin); normalized != expected {
+			t.Errorf("%q should be normalized to %q, not %q", origin, expected, normalized)
+		}
+	}
+}
+
+func TestAddErrorRule(t *testing.T) {
+
+	defer func() {
+		errorRules = nil
+	}()
+
+	if err := AddErrorRule("(", ""); err == nil {
+		t.Error("invalid pattern should be rejected")
+	}
+	if err := AddErrorRule(`order [0-9]+`, "order <id>"); err != nil {
+		t.Fatal(err)
+	}
+	if normalized := normalizeError("order 42 not found"); normalized != "order <id> not found" {
+		t.Error("order 42 not found is normalized incorrectly,", normalized)
+	}
+}
+
+func TestMaxErrorKeys(t *testing.T) {
+
+	s := newRequestStats()
+	for i := 0; i < *maxErrorKeys+10; i++ {
+		s.logError("http", "foo", "error #"+formatFloat(float64(i)), nil)
+	}
+
+	if len(s.errors) != *maxErrorKeys+1 {
+		t.Errorf("there should be %d distinct errors, not %d", *maxErrorKeys+1, len(s.errors))
+	}
+	other := s.errors[MD5(otherErrors, otherErrors, otherErrors)]
+	if other == nil || other.occurences != 10 {
+		t.Error("errors beyond the limit should be counted as", otherErrors)
+	}
+
+	// new names should not add keys either
+	for i := 0; i < 50; i++ {
+		s.logError("http", "bar #"+formatFloat(float64(i)), "error", nil)
+	}
+	if len(s.errors) != *maxErrorKeys+1 || other.occurences != 60 {
+		t.Errorf("there should still be %d distinct errors, not %d", *maxErrorKeys+1, len(s.errors))
+	}
+}
@@ -0,0 +1,81 @@
+package boomer
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rateLimiter limits how many times tasks run per second, 0 means unlimited.
+// The limit can be changed at any time, like when the master shares a cluster-wide target
+// among workers, running users adopt it without being restarted.
+type rateLimiter struct {
+	maxRPS int64
+	// tokens left in the current second
+	tokens int64
+
+	lock sync.RWMutex
+	// refilled is closed and replaced when tokens are refilled, to wake up waiting users.
+	refilled chan bool
+}
+
+func newRateLimiter(maxRPS int64) *rateLimiter {
+	return &rateLimiter{
+		maxRPS:   maxRPS,
+		tokens:   maxRPS,
+		refilled: make(chan bool),
+	}
+}
+
+func (l *rateLimiter) getMaxRPS() int64 {
+	return atomic.LoadInt64(&l.maxRPS)
+}
+
+func (l *rateLimiter) setMaxRPS(maxRPS int64) {
+	if atomic.SwapInt64(&l.maxRPS, maxRPS) != maxRPS {
+		l.refill()
+	}
+}
+
+func (l *rateLimiter) refill() {
+	atomic.StoreInt64(&l.tokens, atomic.LoadInt64(&l.maxRPS))
+	l.lock.Lock()
+	close(l.refilled)
+	l.refilled = make(chan bool)
+	l.lock.Unlock()
+}
+
+// acquire waits for a token, it returns false if quit is closed while waiting.
+func (l *rateLimiter) acquire(quit chan bool) bool {
+	for {
+		if atomic.LoadInt64(&l.maxRPS) <= 0 {
+			return true
+		}
+		// before taking a token, so a refill in between isn't missed
+		l.lock.RLock()
+		refilled := l.refilled
+		l.lock.RUnlock()
+		if atomic.AddInt64(&l.tokens, -1) >= 0 {
+			return true
+		}
+		select {
+		case <-refilled:
+		case <-quit:
+			return false
+		}
+	}
+}
+
+// start refills tokens every second until stop is closed.
+func (l *rateLimiter) start(stop chan bool) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.refill()
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package boomer
+
+import (
+	"testing"
+	"time"
+)
+
+func acquireAsync(l *rateLimiter, quit chan bool) chan bool {
+	acquired := make(chan bool, 1)
+	go func() {
+		acquired <- l.acquire(quit)
+	}()
+	return acquired
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(2)
+	quit := make(chan bool)
+	if !l.acquire(quit) || !l.acquire(quit) {
+		t.Fatal("tokens should be acquired within max RPS")
+	}
+
+	acquired := acquireAsync(l, quit)
+	select {
+	case <-acquired:
+		t.Fatal("max RPS is reached, users should wait")
+	case <-time.After(20 * time.Millisecond):
+	}
+	l.refill()
+	if !<-acquired || !l.acquire(quit) {
+		t.Error("tokens should be acquired after refilled")
+	}
+
+	close(quit)
+	if l.acquire(quit) {
+		t.Error("waiting users should quit")
+	}
+}
+
+func TestRateLimiterSetMaxRPS(t *testing.T) {
+	l := newRateLimiter(1)
+	quit := make(chan bool)
+	l.acquire(quit)
+
+	acquired := acquireAsync(l, quit)
+	// adopted without waiting for the next second
+	l.setMaxRPS(0)
+	select {
+	case ok := <-acquired:
+		if !ok {
+			t.Error("token should be acquired when unlimited")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiting users should adopt the new max RPS")
+	}
+
+	l.setMaxRPS(3)
+	for i := 0; i < 3; i++ {
+		if !l.acquire(quit) {
+			t.Fatal("tokens should be acquired within the new max RPS")
+		}
+	}
+	if l.getMaxRPS() != 3 {
+		t.Error("max RPS should be 3, got", l.getMaxRPS())
+	}
+}
+
+func TestWorkerMaxRPSFromMaster(t *testing.T) {
+	defer func(old int64) {
+		maxRPS = old
+	}(maxRPS)
+	maxRPS = 5
+
+	master, err := NewTestMaster()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer master.Close()
+	if err := master.StartWorker(&Task{Name: "foo", Weight: 1, Fn: func() {}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := master.Expect("client_ready", time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		share, limit int64
+	}{
+		{3, 3},
+		// --max-rps of the worker is still respected
+		{10, 5},
+		{0, 5},
+	} {
+		master.Send("max_rps", map[string]interface{}{"max_rps": c.share})
+		deadline := time.Now().Add(time.Second)
+		for master.runner.rateLimiter.getMaxRPS() != c.limit {
+			if time.Now().After(deadline) {
+				t.Fatalf("max RPS should be %d with a share of %d, got %d", c.limit, c.share, master.runner.rateLimiter.getMaxRPS())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+// Package raw load-tests custom protocols over TCP and UDP, requests are reported to boomer automatically.
+//
+//	client := &raw.Client{
+//		Network: "tcp",
+//		Address: "localhost:9000",
+//		Framer:  raw.LengthPrefixed(4, binary.BigEndian),
+//	}
+//	resp, err := client.Request("login", message)
+//
+// The request type is the network, like "tcp" or "udp", the name is given by the caller. Connecting is
+// reported as "tcp_connect" or "udp_connect", named by the name of the client. Requests fail if there is
+// no response within the timeout, or the connection is broken.
+package raw
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/myzhan/boomer"
+)
+
+var (
+	// ErrTimeout is returned by Request if there is no response within the timeout.
+	ErrTimeout = errors.New("timeout")
+	// ErrClosed is returned by Request after the client is closed.
+	ErrClosed = errors.New("client is closed")
+	// ErrNoFramer is returned by Request if a TCP client has no Framer.
+	ErrNoFramer = errors.New("Framer is required by tcp")
+)
+
+// Client sends requests to a server over a pool of connections, it's usually owned by a user.
+type Client struct {
+	// Network is "tcp" or "udp", or their variants like "tcp4".
+	Network string
+	// Address is like "localhost:9000".
+	Address string
+	// Name is the name of the client in the reports of connecting, it's the address by default.
+	Name string
+	// Framer splits the stream into messages, it's required by TCP and ignored by UDP.
+	Framer Framer
+	// Timeout of connecting and requests, 10 seconds by default.
+	Timeout time.Duration
+	// PoolSize is the max number of connections, 1 by default. Without Correlate, a connection is
+	// used by one request at a time, requests wait for connections if they are all in use.
+	PoolSize int
+	// Correlate returns the ID of a message, responses are matched to requests by their IDs,
+	// and requests are sent on a connection without waiting for the responses of previous ones.
+	// Without it, the next message received on the connection is the response.
+	Correlate func(message []byte) string
+	// BufferSize is the max size of datagrams received by UDP, 64KB by default.
+	BufferSize int
+
+	once   sync.Once
+	lock   sync.Mutex
+	closed bool
+	// pool has PoolSize slots, a slot is nil until its connection is opened
+	pool chan *conn
+}
+
+func (c *Client) name() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.Address
+}
+
+func (c *Client) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return 10 * time.Second
+}
+
+func (c *Client) isUDP() bool {
+	return len(c.Network) >= 3 && c.Network[:3] == "udp"
+}
+
+func elapsedMillis(start time.Time) int64 {
+	return int64(time.Since(start) / time.Millisecond)
+}
+
+func (c *Client) init() {
+	c.once.Do(func() {
+		size := c.PoolSize
+		if size <= 0 {
+			size = 1
+		}
+		c.pool = make(chan *conn, size)
+		for i := 0; i < size; i++ {
+			c.pool <- nil
+		}
+	})
+}
+
+// get takes a connection from the pool, it's opened if the slot is empty, or the connection is broken.
+func (c *Client) get(deadline time.Time) (*conn, error) {
+	if c.Framer == nil && !c.isUDP() {
+		return nil, ErrNoFramer
+	}
+	c.init()
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	var cn *conn
+	select {
+	case cn = <-c.pool:
+	case <-timer.C:
+		return nil, ErrTimeout
+	}
+	if cn != nil && !cn.broken() {
+		return cn, nil
+	}
+	if cn != nil {
+		cn.Close()
+	}
+
+	c.lock.Lock()
+	closed := c.closed
+	c.lock.Unlock()
+	if closed {
+		c.pool <- nil
+		return nil, ErrClosed
+	}
+
+	start := time.Now()
+	netConn, err := net.DialTimeout(c.Network, c.Address, time.Until(deadline))
+	if err != nil {
+		boomer.RecordFailure(c.Network+"_connect", c.name(), elapsedMillis(start), err.Error(), nil)
+		c.pool <- nil
+		return nil, err
+	}
+	boomer.RecordSuccess(c.Network+"_connect", c.name(), elapsedMillis(start), 0, nil)
+	return newConn(c, netConn), nil
+}
+
+// put returns the connection to the pool, broken connections are closed.
+func (c *Client) put(cn *conn) {
+	c.lock.Lock()
+	closed := c.closed
+	c.lock.Unlock()
+	if cn != nil && (closed || cn.broken()) {
+		cn.Close()
+		cn = nil
+	}
+	c.pool <- cn
+}
+
+// Request sends a message and waits for the response, the result is reported with the name.
+func (c *Client) Request(name string, message []byte) ([]byte, error) {
+	start := time.Now()
+	deadline := start.Add(c.timeout())
+	resp, err := c.request(message, deadline)
+	if err != nil {
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			err = ErrTimeout
+		}
+		boomer.RecordFailure(c.Network, name, elapsedMillis(start), err.Error(), nil)
+		return nil, err
+	}
+	boomer.RecordSuccess(c.Network, name, elapsedMillis(start), int64(len(resp)), nil)
+	return resp, nil
+}
+
+func (c *Client) request(message []byte, deadline time.Time) ([]byte, error) {
+	if c.Correlate != nil {
+		return c.correlatedRequest(message, deadline)
+	}
+
+	cn, err := c.get(deadline)
+	if err != nil {
+		return nil, err
+	}
+	cn.SetDeadline(deadline)
+	if err := cn.write(message); err != nil {
+		cn.Close()
+		c.put(nil)
+		return nil, err
+	}
+	resp, err := cn.read()
+	if err != nil {
+		// a late response would be taken as the response of the next request
+		cn.Close()
+		c.put(nil)
+		return nil, err
+	}
+	c.put(cn)
+	return resp, nil
+}
+
+func (c *Client) correlatedRequest(message []byte, deadline time.Time) ([]byte, error) {
+	id := c.Correlate(message)
+	if id == "" {
+		return nil, errors.New("no ID in the request")
+	}
+
+	cn, err := c.get(deadline)
+	if err != nil {
+		return nil, err
+	}
+	response, err := cn.expect(id)
+	if err != nil {
+		c.put(cn)
+		return nil, err
+	}
+	cn.SetWriteDeadline(deadline)
+	if err := cn.write(message); err != nil {
+		cn.Close()
+		c.put(nil)
+		return nil, err
+	}
+	// the connection is shared by other requests while waiting
+	c.put(cn)
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case resp := <-response:
+		return resp, nil
+	case <-cn.done:
+		// the response may arrive right before the connection is broken
+		select {
+		case resp := <-response:
+			return resp, nil
+		default:
+		}
+		return nil, cn.err
+	case <-timer.C:
+		cn.forget(id)
+		return nil, ErrTimeout
+	}
+}
+
+// Close closes the connections, including the ones in use when they are returned.
+func (c *Client) Close() {
+	c.init()
+	c.lock.Lock()
+	c.closed = true
+	c.lock.Unlock()
+
+	// empty slots are put back, requests after closing fail instead of waiting
+	n := 0
+	for drained := false; !drained; {
+		select {
+		case cn := <-c.pool:
+			if cn != nil {
+				cn.Close()
+			}
+			n++
+		default:
+			drained = true
+		}
+	}
+	for i := 0; i < n; i++ {
+		c.pool <- nil
+	}
+}
+
+type conn struct {
+	net.Conn
+	client *Client
+	reader *bufio.Reader
+
+	// with Correlate, responses are read by a goroutine
+	lock    sync.Mutex
+	pending map[string]chan []byte
+	// done is closed when the goroutine exits, because of err
+	done chan struct{}
+	err  error
+}
+
+func newConn(c *Client, netConn net.Conn) *conn {
+	cn := &conn{
+		Conn:   netConn,
+		client: c,
+		reader: bufio.NewReader(netConn),
+	}
+	if c.Correlate != nil {
+		cn.pending = make(map[string]chan []byte)
+		cn.done = make(chan struct{})
+		go cn.receive()
+	}
+	return cn
+}
+
+func (cn *conn) read() ([]byte, error) {
+	if cn.client.isUDP() {
+		size := cn.client.BufferSize
+		if size <= 0 {
+			size = 64 * 1024
+		}
+		buf := make([]byte, size)
+		n, err := cn.Conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+	return cn.client.Framer.ReadFrame(cn.reader)
+}
+
+func (cn *conn) write(message []byte) error {
+	if cn.client.isUDP() {
+		_, err := cn.Conn.Write(message)
+		return err
+	}
+	return cn.client.Framer.WriteFrame(cn.Conn, message)
+}
+
+// broken tells if the goroutine reading responses has exited.
+func (cn *conn) broken() bool {
+	if cn.done == nil {
+		return false
+	}
+	select {
+	case <-cn.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (cn *conn) receive() {
+	for {
+		message, err := cn.read()
+		if err != nil {
+			cn.lock.Lock()
+			cn.err = err
+			cn.pending = nil
+			cn.lock.Unlock()
+			close(cn.done)
+			cn.Close()
+			return
+		}
+		id := cn.client.Correlate(message)
+		cn.lock.Lock()
+		response, ok := cn.pending[id]
+		delete(cn.pending, id)
+		cn.lock.Unlock()
+		// unexpected messages are dropped
+		if ok {
+			response <- message
+		}
+	}
+}
+
+// expect registers the ID of a request before it's sent.
+func (cn *conn) expect(id string) (chan []byte, error) {
+	cn.lock.Lock()
+	defer cn.lock.Unlock()
+	if cn.pending == nil {
+		return nil, cn.err
+	}
+	if _, ok := cn.pending[id]; ok {
+		return nil, errors.New("duplicate ID " + id)
+	}
+	response := make(chan []byte, 1)
+	cn.pending[id] = response
+	return response, nil
+}
+
+func (cn *conn) forget(id string) {
+	cn.lock.Lock()
+	defer cn.lock.Unlock()
+	delete(cn.pending, id)
+}
@@ -0,0 +1,211 @@
+package raw
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/myzhan/boomer/boomertest"
+)
+
+var lineFramer = Delimited([]byte("\n"))
+
+// newTCPServer echoes lines, but ignores "slow", closes the connection on "close",
+// and answers "pair" lines two at a time in reverse order.
+func newTCPServer(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		listener.Close()
+	})
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				var pair [][]byte
+				for {
+					message, err := lineFramer.ReadFrame(r)
+					if err != nil {
+						return
+					}
+					switch {
+					case bytes.Contains(message, []byte("slow")):
+					case bytes.Contains(message, []byte("close")):
+						return
+					case bytes.Contains(message, []byte("pair")):
+						pair = append(pair, message)
+						if len(pair) == 2 {
+							lineFramer.WriteFrame(conn, pair[1])
+							lineFramer.WriteFrame(conn, pair[0])
+							pair = nil
+						}
+					default:
+						lineFramer.WriteFrame(conn, message)
+					}
+				}
+			}()
+		}
+	}()
+	return listener
+}
+
+func TestTCPRequest(t *testing.T) {
+	recorder := boomertest.Record(t)
+	listener := newTCPServer(t)
+	client := &Client{
+		Network: "tcp",
+		Address: listener.Addr().String(),
+		Name:    "echo",
+		Framer:  lineFramer,
+		Timeout: 100 * time.Millisecond,
+	}
+	defer client.Close()
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Request("hello", []byte("hello"))
+		if err != nil || string(resp) != "hello" {
+			t.Fatal("response should be echoed, got", string(resp), err)
+		}
+	}
+	if _, err := client.Request("slow", []byte("slow")); err != ErrTimeout {
+		t.Error("request should time out, got", err)
+	}
+	if _, err := client.Request("close", []byte("close")); err == nil {
+		t.Error("request should fail when the connection is closed")
+	}
+	if _, err := client.Request("hello", []byte("hello")); err != nil {
+		t.Error("a new connection should be opened, got", err)
+	}
+
+	want := []string{
+		"tcp_connect echo",
+		"tcp hello",
+		"tcp hello",
+		"tcp slow: timeout",
+		"tcp_connect echo",
+		"tcp close: EOF",
+		"tcp_connect echo",
+		"tcp hello",
+	}
+	got := recorder.Requests()
+	if len(got) != len(want) {
+		t.Fatal("unexpected records", got)
+	}
+	for i := range want {
+		if got[i].String() != want[i] {
+			t.Errorf("record %d should be %v, got %v", i, want[i], got[i])
+		}
+	}
+
+	client.Close()
+	if _, err := client.Request("hello", []byte("hello")); err != ErrClosed {
+		t.Error("requests after closing should fail, got", err)
+	}
+
+	unframed := &Client{Network: "tcp", Address: listener.Addr().String()}
+	if _, err := unframed.Request("hello", []byte("hello")); err != ErrNoFramer {
+		t.Error("TCP requests without a framer should fail, got", err)
+	}
+}
+
+func TestCorrelatedRequest(t *testing.T) {
+	recorder := boomertest.Record(t)
+	listener := newTCPServer(t)
+	client := &Client{
+		Network: "tcp",
+		Address: listener.Addr().String(),
+		Framer:  lineFramer,
+		Timeout: time.Second,
+		Correlate: func(message []byte) string {
+			return strings.SplitN(string(message), ":", 2)[0]
+		},
+	}
+	defer client.Close()
+
+	// both requests are sent on the only connection, the responses come in reverse order
+	var wg sync.WaitGroup
+	for _, message := range []string{"1:pair", "2:pair"} {
+		wg.Add(1)
+		go func(message string) {
+			defer wg.Done()
+			resp, err := client.Request("pair", []byte(message))
+			if err != nil || string(resp) != message {
+				t.Errorf("response of %s should be matched, got %s, %v", message, resp, err)
+			}
+		}(message)
+		time.Sleep(10 * time.Millisecond)
+	}
+	wg.Wait()
+
+	if _, err := client.Request("close", []byte("3:close")); err == nil {
+		t.Error("request should fail when the connection is closed")
+	}
+	if _, err := client.Request("hello", []byte("hello")); err != nil {
+		t.Error("a new connection should be opened, got", err)
+	}
+
+	connects := 0
+	for _, r := range recorder.Requests() {
+		if r.Type == "tcp_connect" {
+			connects++
+		}
+	}
+	if connects != 2 {
+		t.Error("there should be 2 connections, got", recorder.Requests())
+	}
+}
+
+func TestUDPRequest(t *testing.T) {
+	recorder := boomertest.Record(t)
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := server.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if string(buf[:n]) != "slow" {
+				server.WriteTo(buf[:n], addr)
+			}
+		}
+	}()
+
+	client := &Client{
+		Network:  "udp",
+		Address:  server.LocalAddr().String(),
+		Timeout:  100 * time.Millisecond,
+		PoolSize: 2,
+	}
+	user := NewUser(client, func(c *Client) {
+		if resp, err := c.Request("ping", []byte("ping")); err != nil || string(resp) != "ping" {
+			t.Error("response should be echoed, got", string(resp), err)
+		}
+	})
+	user.OnStart()
+	user.Run()
+	if _, err := client.Request("slow", []byte("slow")); err != ErrTimeout {
+		t.Error("request should time out, got", err)
+	}
+	user.OnStop()
+
+	got := recorder.Requests()
+	if len(got) != 4 || got[1].String() != "udp ping" || got[3].String() != "udp slow: timeout" {
+		t.Error("unexpected records", got)
+	}
+}
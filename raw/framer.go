@@ -0,0 +1,155 @@
+package raw
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Framer splits a stream into messages, like TCP. Datagrams of UDP are messages already.
+type Framer interface {
+	// ReadFrame reads the next message.
+	ReadFrame(r *bufio.Reader) ([]byte, error)
+	// WriteFrame writes a message, with a single write.
+	WriteFrame(w io.Writer, message []byte) error
+}
+
+// maxFrameSize keeps a corrupted stream from allocating too much memory.
+const maxFrameSize = 64 << 20
+
+var errFrameTooLarge = errors.New("frame is too large")
+
+type lengthPrefixed struct {
+	size  int
+	order binary.ByteOrder
+	max   int
+}
+
+// LengthPrefixed frames a message with its length in size bytes, 1, 2, 4 or 8, the length excludes itself.
+func LengthPrefixed(size int, order binary.ByteOrder) Framer {
+	switch size {
+	case 1, 2, 4, 8:
+	default:
+		panic(fmt.Sprintf("size of the length prefix should be 1, 2, 4 or 8, not %d", size))
+	}
+	return &lengthPrefixed{size, order, maxFrameSize}
+}
+
+func (f *lengthPrefixed) ReadFrame(r *bufio.Reader) ([]byte, error) {
+	header := make([]byte, f.size)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	var length uint64
+	switch f.size {
+	case 1:
+		length = uint64(header[0])
+	case 2:
+		length = uint64(f.order.Uint16(header))
+	case 4:
+		length = uint64(f.order.Uint32(header))
+	case 8:
+		length = f.order.Uint64(header)
+	}
+	if length > uint64(f.max) {
+		return nil, errFrameTooLarge
+	}
+	message := make([]byte, length)
+	if _, err := io.ReadFull(r, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func (f *lengthPrefixed) WriteFrame(w io.Writer, message []byte) error {
+	if f.size < 8 && uint64(len(message)) >= 1<<(8*uint(f.size)) {
+		return fmt.Errorf("message of %d bytes can't be framed with a %d-byte length", len(message), f.size)
+	}
+	frame := make([]byte, f.size+len(message))
+	switch f.size {
+	case 1:
+		frame[0] = byte(len(message))
+	case 2:
+		f.order.PutUint16(frame, uint16(len(message)))
+	case 4:
+		f.order.PutUint32(frame, uint32(len(message)))
+	case 8:
+		f.order.PutUint64(frame, uint64(len(message)))
+	}
+	copy(frame[f.size:], message)
+	_, err := w.Write(frame)
+	return err
+}
+
+type delimited struct {
+	delimiter []byte
+	max       int
+}
+
+// Delimited frames a message with the delimiter after it, like "\r\n", the delimiter is stripped on reading.
+func Delimited(delimiter []byte) Framer {
+	if len(delimiter) == 0 {
+		panic("delimiter can't be empty")
+	}
+	return &delimited{delimiter, maxFrameSize}
+}
+
+func (f *delimited) ReadFrame(r *bufio.Reader) ([]byte, error) {
+	last := f.delimiter[len(f.delimiter)-1]
+	var message []byte
+	for {
+		chunk, err := r.ReadSlice(last)
+		message = append(message, chunk...)
+		if len(message) > f.max+len(f.delimiter) {
+			return nil, errFrameTooLarge
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if bytes.HasSuffix(message, f.delimiter) {
+			return message[:len(message)-len(f.delimiter)], nil
+		}
+	}
+}
+
+func (f *delimited) WriteFrame(w io.Writer, message []byte) error {
+	frame := make([]byte, 0, len(message)+len(f.delimiter))
+	frame = append(frame, message...)
+	frame = append(frame, f.delimiter...)
+	_, err := w.Write(frame)
+	return err
+}
+
+type fixedSize struct {
+	size int
+}
+
+// FixedSize frames messages of the same size, messages of other sizes can't be written.
+func FixedSize(size int) Framer {
+	if size <= 0 {
+		panic(fmt.Sprintf("size should be positive, not %d", size))
+	}
+	return &fixedSize{size}
+}
+
+func (f *fixedSize) ReadFrame(r *bufio.Reader) ([]byte, error) {
+	message := make([]byte, f.size)
+	if _, err := io.ReadFull(r, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func (f *fixedSize) WriteFrame(w io.Writer, message []byte) error {
+	if len(message) != f.size {
+		return fmt.Errorf("message should be %d bytes, not %d", f.size, len(message))
+	}
+	_, err := w.Write(message)
+	return err
+}
@@ -0,0 +1,73 @@
+package raw
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestFramers(t *testing.T) {
+	messages := [][]byte{[]byte("hello"), {}, []byte("a\rb\nc"), bytes.Repeat([]byte("x"), 5000)}
+	for name, framer := range map[string]Framer{
+		"uint8":     LengthPrefixed(1, binary.BigEndian),
+		"uint16":    LengthPrefixed(2, binary.BigEndian),
+		"uint32":    LengthPrefixed(4, binary.LittleEndian),
+		"uint64":    LengthPrefixed(8, binary.BigEndian),
+		"delimited": Delimited([]byte("\r\n")),
+	} {
+		buf := &bytes.Buffer{}
+		var written [][]byte
+		for _, message := range messages {
+			if framer.WriteFrame(buf, message) == nil {
+				written = append(written, message)
+			}
+		}
+		r := bufio.NewReaderSize(buf, 16)
+		for _, message := range written {
+			frame, err := framer.ReadFrame(r)
+			if err != nil {
+				t.Fatal(name, err)
+			}
+			if !bytes.Equal(frame, message) {
+				t.Errorf("%s: frame should be %q, got %q", name, message, frame)
+			}
+		}
+	}
+
+	if err := LengthPrefixed(1, binary.BigEndian).WriteFrame(&bytes.Buffer{}, make([]byte, 256)); err == nil {
+		t.Error("message longer than the length prefix can hold should fail")
+	}
+	if err := LengthPrefixed(2, binary.BigEndian).WriteFrame(&bytes.Buffer{}, make([]byte, 256)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFixedSize(t *testing.T) {
+	framer := FixedSize(4)
+	buf := &bytes.Buffer{}
+	if err := framer.WriteFrame(buf, []byte("abc")); err == nil {
+		t.Error("message of another size should fail")
+	}
+	framer.WriteFrame(buf, []byte("abcd"))
+	framer.WriteFrame(buf, []byte("efgh"))
+	r := bufio.NewReader(buf)
+	for _, want := range []string{"abcd", "efgh"} {
+		if frame, err := framer.ReadFrame(r); err != nil || string(frame) != want {
+			t.Errorf("frame should be %s, got %s, %v", want, frame, err)
+		}
+	}
+}
+
+func TestMaxFrameSize(t *testing.T) {
+	buf := &bytes.Buffer{}
+	LengthPrefixed(4, binary.BigEndian).WriteFrame(buf, make([]byte, 11))
+	if _, err := (&lengthPrefixed{4, binary.BigEndian, 10}).ReadFrame(bufio.NewReader(buf)); err != errFrameTooLarge {
+		t.Error("frames larger than the max size should fail, got", err)
+	}
+	buf.Reset()
+	buf.WriteString("01234567890123456789\n")
+	if _, err := (&delimited{[]byte("\n"), 10}).ReadFrame(bufio.NewReaderSize(buf, 16)); err != errFrameTooLarge {
+		t.Error("frames larger than the max size should fail, got", err)
+	}
+}
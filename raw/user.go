@@ -0,0 +1,29 @@
+package raw
+
+import (
+	"github.com/myzhan/boomer"
+)
+
+type user struct {
+	client *Client
+	run    func(client *Client)
+}
+
+// NewUser returns a user with its own client, connections of the client are opened on demand,
+// and closed in OnStop. run is called repeatedly with the client.
+func NewUser(client *Client, run func(client *Client)) boomer.User {
+	return &user{
+		client: client,
+		run:    run,
+	}
+}
+
+func (u *user) OnStart() {}
+
+func (u *user) Run() {
+	u.run(u.client)
+}
+
+func (u *user) OnStop() {
+	u.client.Close()
+}
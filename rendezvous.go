@@ -0,0 +1,184 @@
+package boomer
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Rendezvous points make users wait for each other to fire an action at the same instant,
+// like checkouts of a flash sale. Rendezvous counts users on this worker, ClusterRendezvous
+// counts users across all the workers with the native master.
+// How long users waited is recorded as requests of type "rendezvous", timeouts as failures.
+
+// ErrRendezvousTimeout is returned if not enough users arrive before timeout.
+var ErrRendezvousTimeout = errors.New("timeout waiting for users at the rendezvous point")
+
+// ErrRendezvousCanceled is returned if users are stopped while waiting.
+var ErrRendezvousCanceled = errors.New("rendezvous is canceled, users are stopped")
+
+type rendezvousWaiter struct {
+	name string
+	// id of users waiting for the master
+	id      int64
+	release chan error
+}
+
+var rendezvousLock sync.Mutex
+
+// localRendezvous are users waiting at points on this worker, by name.
+var localRendezvous = make(map[string][]*rendezvousWaiter)
+
+// clusterRendezvous are users waiting for the master, by id.
+var clusterRendezvous = make(map[int64]*rendezvousWaiter)
+var lastRendezvousID int64
+
+func newRendezvousWaiter(name string) *rendezvousWaiter {
+	return &rendezvousWaiter{
+		name:    name,
+		release: make(chan error, 1),
+	}
+}
+
+// Rendezvous blocks until participants users on this worker arrive at the rendezvous point name.
+// All the users of a name should have the same participants.
+func Rendezvous(name string, participants int, timeout time.Duration) error {
+	if participants <= 0 {
+		return fmt.Errorf("participants should be positive, not %d", participants)
+	}
+
+	w := newRendezvousWaiter(name)
+	rendezvousLock.Lock()
+	waiters := append(localRendezvous[name], w)
+	if len(waiters) >= participants {
+		delete(localRendezvous, name)
+		for _, waiter := range waiters {
+			waiter.release <- nil
+		}
+	} else {
+		localRendezvous[name] = waiters
+	}
+	rendezvousLock.Unlock()
+
+	return w.wait(timeout, func() bool {
+		waiters := localRendezvous[name]
+		for i, waiter := range waiters {
+			if waiter == w {
+				localRendezvous[name] = append(waiters[:i:i], waiters[i+1:]...)
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// ClusterRendezvous blocks until participants users across all the workers arrive at the rendezvous point name.
+// It needs the native master, users only wait until timeout with a locust master.
+func ClusterRendezvous(name string, participants int, timeout time.Duration) error {
+	if participants <= 0 {
+		return fmt.Errorf("participants should be positive, not %d", participants)
+	}
+
+	w := newRendezvousWaiter(name)
+	w.id = atomic.AddInt64(&lastRendezvousID, 1)
+	rendezvousLock.Lock()
+	clusterRendezvous[w.id] = w
+	rendezvousLock.Unlock()
+
+	err := sendWorkerMessage("rendezvous", map[string]interface{}{
+		"name":         name,
+		"participants": int64(participants),
+		"id":           w.id,
+	})
+	if err != nil {
+		rendezvousLock.Lock()
+		delete(clusterRendezvous, w.id)
+		rendezvousLock.Unlock()
+		return err
+	}
+
+	return w.wait(timeout, func() bool {
+		if clusterRendezvous[w.id] != w {
+			return false
+		}
+		delete(clusterRendezvous, w.id)
+		go w.leave()
+		return true
+	})
+}
+
+// leave tells the master the user doesn't wait any more.
+func (w *rendezvousWaiter) leave() {
+	err := sendWorkerMessage("rendezvous_leave", map[string]interface{}{
+		"name": w.name,
+		"id":   w.id,
+	})
+	if err != nil {
+		log.Printf("Failed to leave rendezvous point %s, %v\n", w.name, err)
+	}
+}
+
+// wait records how long the user waited. On timeout, leave is called with the lock held,
+// it returns false if the user is released at the same time.
+func (w *rendezvousWaiter) wait(timeout time.Duration, leave func() bool) error {
+	start := time.Now()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case err = <-w.release:
+	case <-timer.C:
+		rendezvousLock.Lock()
+		left := leave()
+		rendezvousLock.Unlock()
+		if left {
+			err = ErrRendezvousTimeout
+		} else {
+			err = <-w.release
+		}
+	}
+
+	elapsed := int64(time.Since(start) / time.Millisecond)
+	if err != nil {
+		RecordFailure("rendezvous", w.name, elapsed, err.Error(), nil)
+	} else {
+		RecordSuccess("rendezvous", w.name, elapsed, 0, nil)
+	}
+	return err
+}
+
+// releaseRendezvous releases users of a rendezvous_release message.
+func releaseRendezvous(ids []int64) {
+	rendezvousLock.Lock()
+	defer rendezvousLock.Unlock()
+
+	for _, id := range ids {
+		// users may have left on timeout
+		if w, ok := clusterRendezvous[id]; ok {
+			delete(clusterRendezvous, id)
+			w.release <- nil
+		}
+	}
+}
+
+// cancelRendezvous releases all the waiting users with ErrRendezvousCanceled, when users are stopped.
+func cancelRendezvous() {
+	rendezvousLock.Lock()
+	defer rendezvousLock.Unlock()
+
+	for name, waiters := range localRendezvous {
+		for _, w := range waiters {
+			w.release <- ErrRendezvousCanceled
+		}
+		delete(localRendezvous, name)
+	}
+	for id, w := range clusterRendezvous {
+		w.release <- ErrRendezvousCanceled
+		delete(clusterRendezvous, id)
+		go w.leave()
+	}
+}
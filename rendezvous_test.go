@@ -0,0 +1,121 @@
+package boomer
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestRendezvous(t *testing.T) {
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			errs <- Rendezvous("local", 3, time.Second)
+		}()
+	}
+	for i := 0; i < 3; i++ {
+		if err := <-errs; err != nil {
+			t.Error("users should be released together, got", err)
+		}
+	}
+
+	if err := Rendezvous("alone", 2, 20*time.Millisecond); err != ErrRendezvousTimeout {
+		t.Error("user should wait until timeout, got", err)
+	}
+	rendezvousLock.Lock()
+	left := len(localRendezvous["alone"])
+	rendezvousLock.Unlock()
+	if left != 0 {
+		t.Error("user should leave the rendezvous point on timeout")
+	}
+}
+
+func TestRendezvousCanceled(t *testing.T) {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- Rendezvous("cancel", 2, time.Second)
+	}()
+	for {
+		rendezvousLock.Lock()
+		n := len(localRendezvous["cancel"])
+		rendezvousLock.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	cancelRendezvous()
+	if err := <-errs; err != ErrRendezvousCanceled {
+		t.Error("waiting users should be canceled, got", err)
+	}
+}
+
+func TestClusterRendezvous(t *testing.T) {
+	m := newTestMaster(t)
+	defer m.Close()
+
+	for len(fromMaster) > 0 {
+		<-fromMaster
+	}
+	addr := m.Addr().(*net.TCPAddr)
+	r := newRunner(nil, newSocketClient(addr.IP.String(), addr.Port))
+	r.getReady()
+	defer r.close()
+
+	// another worker
+	conn, err := net.Dial("tcp", m.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	writeFrame(conn, newMessage("client_ready", nil, "worker"))
+	writeFrame(conn, newMessage("rendezvous", map[string]interface{}{
+		"name":         "sale",
+		"participants": int64(2),
+		"id":           int64(7),
+	}, "worker"))
+	waitForWorkers(t, m, 2)
+
+	if err := ClusterRendezvous("sale", 2, time.Second); err != nil {
+		t.Fatal("users across workers should be released together, got", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	msg, err := readFrame(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release, err := decodeRendezvousRelease(msg.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != "rendezvous_release" || len(release.ids) != 1 || release.ids[0] != 7 {
+		t.Error("the other worker should be released, got", msg)
+	}
+
+	if err := ClusterRendezvous("slow", 2, 20*time.Millisecond); err != ErrRendezvousTimeout {
+		t.Error("user should wait until timeout, got", err)
+	}
+	// the user leaves, and users of a disconnected worker are dropped
+	writeFrame(conn, newMessage("rendezvous", map[string]interface{}{
+		"name":         "gone",
+		"participants": int64(2),
+		"id":           int64(8),
+	}, "worker"))
+	time.Sleep(20 * time.Millisecond)
+	conn.Close()
+	waitForWorkers(t, m, 1)
+	deadline := time.Now().Add(time.Second)
+	for {
+		m.lock.Lock()
+		n := len(m.rendezvous)
+		m.lock.Unlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("rendezvous points should be empty, got", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
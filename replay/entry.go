@@ -0,0 +1,256 @@
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Entry is a recorded request.
+type Entry struct {
+	// Offset is the time since the first request of the recording.
+	Offset time.Duration
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+}
+
+// Load loads a HAR file, or an access log if it isn't JSON. URLs are rebased on baseURL, like
+// "http://staging:8080", it's required by access logs, which don't have the scheme and host.
+func Load(path, baseURL string) ([]*Entry, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*Entry
+	if bytes.HasPrefix(bytes.TrimSpace(content), []byte("{")) {
+		entries, err = parseHAR(content, baseURL)
+	} else {
+		entries, err = parseAccessLog(bytes.NewReader(content), baseURL)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return entries, nil
+}
+
+// LoadHAR loads a HAR file, exported by browsers or proxies. URLs are rebased on baseURL unless it's empty.
+func LoadHAR(path, baseURL string) ([]*Entry, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := parseHAR(content, baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return entries, nil
+}
+
+// LoadAccessLog loads an access log of nginx or Apache, in the combined or common log format.
+// URLs are the paths in the log based on baseURL, like "http://localhost:8080".
+func LoadAccessLog(path, baseURL string) ([]*Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries, err := parseAccessLog(file, baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return entries, nil
+}
+
+// skippedHeaders are set by the client, or only valid in the recorded connection.
+var skippedHeaders = map[string]bool{
+	"Host":              true,
+	"Content-Length":    true,
+	"Connection":        true,
+	"Keep-Alive":        true,
+	"Transfer-Encoding": true,
+	"Upgrade":           true,
+}
+
+type har struct {
+	Log struct {
+		Entries []struct {
+			StartedDateTime time.Time `json:"startedDateTime"`
+			Request         struct {
+				Method  string `json:"method"`
+				URL     string `json:"url"`
+				Headers []struct {
+					Name  string `json:"name"`
+					Value string `json:"value"`
+				} `json:"headers"`
+				PostData *struct {
+					MimeType string `json:"mimeType"`
+					Text     string `json:"text"`
+				} `json:"postData"`
+			} `json:"request"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+func parseHAR(content []byte, baseURL string) ([]*Entry, error) {
+	var h har
+	if err := json.Unmarshal(content, &h); err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	var times []time.Time
+	for i, e := range h.Log.Entries {
+		u, err := rebase(e.Request.URL, baseURL)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %v", i, err)
+		}
+		entry := &Entry{
+			Method: e.Request.Method,
+			URL:    u,
+			Header: make(http.Header),
+		}
+		for _, header := range e.Request.Headers {
+			name := http.CanonicalHeaderKey(header.Name)
+			// pseudo headers of HTTP/2, like ":authority"
+			if strings.HasPrefix(name, ":") || skippedHeaders[name] {
+				continue
+			}
+			entry.Header.Add(name, header.Value)
+		}
+		if data := e.Request.PostData; data != nil {
+			entry.Body = []byte(data.Text)
+			if entry.Header.Get("Content-Type") == "" && data.MimeType != "" {
+				entry.Header.Set("Content-Type", data.MimeType)
+			}
+		}
+		entries = append(entries, entry)
+		times = append(times, e.StartedDateTime)
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("no requests")
+	}
+	setOffsets(entries, times)
+	return entries, nil
+}
+
+// setOffsets sets the offsets of entries from their times, and sorts them by the offsets.
+func setOffsets(entries []*Entry, times []time.Time) {
+	var first time.Time
+	for i, t := range times {
+		if i == 0 || t.Before(first) {
+			first = t
+		}
+	}
+	for i, entry := range entries {
+		entry.Offset = times[i].Sub(first)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Offset < entries[j].Offset
+	})
+}
+
+// rebase replaces the scheme and host of rawURL with the ones of baseURL, and prepends its path.
+func rebase(rawURL, baseURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if baseURL != "" {
+		base, err := url.Parse(baseURL)
+		if err != nil {
+			return "", err
+		}
+		u.Scheme = base.Scheme
+		u.Host = base.Host
+		u.User = base.User
+		u.Path = strings.TrimSuffix(base.Path, "/") + u.Path
+		if u.RawPath != "" {
+			u.RawPath = strings.TrimSuffix(base.EscapedPath(), "/") + u.RawPath
+		}
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("%q should be an absolute URL, or based on a base URL", rawURL)
+	}
+	return u.String(), nil
+}
+
+// accessLogPattern matches the common log format, and the combined log format with the referer and user agent.
+var accessLogPattern = regexp.MustCompile(
+	`^\S+ \S+ \S+ \[([^\]]+)\] "((?:[^"\\]|\\.)*)" (?:\d{3}|-) (?:\d+|-)(?: "((?:[^"\\]|\\.)*)" "((?:[^"\\]|\\.)*)")?`)
+
+const accessLogTime = "02/Jan/2006:15:04:05 -0700"
+
+func parseAccessLog(r io.Reader, baseURL string) ([]*Entry, error) {
+	if baseURL == "" {
+		return nil, errors.New("base URL is required by access logs")
+	}
+
+	var entries []*Entry
+	var times []time.Time
+	skipped := 0
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		match := accessLogPattern.FindStringSubmatch(line)
+		if match == nil {
+			skipped++
+			continue
+		}
+		t, err := time.Parse(accessLogTime, match[1])
+		// requests like "-" or garbage from scanners are skipped
+		request := strings.Fields(match[2])
+		if err != nil || len(request) != 3 || !strings.HasPrefix(request[1], "/") {
+			skipped++
+			continue
+		}
+		u, err := rebase(request[1], baseURL)
+		if err != nil {
+			skipped++
+			continue
+		}
+
+		entry := &Entry{
+			Method: request[0],
+			URL:    u,
+			Header: make(http.Header),
+		}
+		if referer := match[3]; referer != "" && referer != "-" {
+			entry.Header.Set("Referer", referer)
+		}
+		if userAgent := match[4]; userAgent != "" && userAgent != "-" {
+			entry.Header.Set("User-Agent", userAgent)
+		}
+		entries = append(entries, entry)
+		times = append(times, t)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if skipped > 0 {
+		log.Printf("Skipped %d lines of the access log which are not valid requests\n", skipped)
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("no requests")
+	}
+	setOffsets(entries, times)
+	return entries, nil
+}
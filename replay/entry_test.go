@@ -0,0 +1,88 @@
+package replay
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadHAR(t *testing.T) {
+	entries, err := Load("testdata/shop.har", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatal("there should be 2 entries, got", len(entries))
+	}
+
+	// entries are sorted by their time
+	get, post := entries[0], entries[1]
+	if get.Method != "GET" || get.URL != "https://shop.example.com/api/products/42" || get.Offset != 0 {
+		t.Error("first entry mismatched", get)
+	}
+	if get.Header.Get("Cookie") != "session=abc" || get.Body != nil {
+		t.Error("recorded headers should be kept", get.Header)
+	}
+	if post.Method != "POST" || post.Offset != 250*time.Millisecond || string(post.Body) != `{"sku": 1234}` {
+		t.Error("second entry mismatched", post)
+	}
+	if post.Header.Get("Content-Type") != "application/json" || post.Header.Get("Content-Length") != "" || len(post.Header) != 2 {
+		t.Error("pseudo and hop-by-hop headers should be skipped, got", post.Header)
+	}
+
+	entries, err = LoadHAR("testdata/shop.har", "http://localhost:8080/staging/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries[1].URL != "http://localhost:8080/staging/api/cart/items?session=abc" {
+		t.Error("URL should be rebased, got", entries[1].URL)
+	}
+}
+
+func TestLoadAccessLog(t *testing.T) {
+	if _, err := Load("testdata/access.log", ""); err == nil {
+		t.Error("access log without base URL should fail")
+	}
+	entries, err := LoadAccessLog("testdata/access.log", "http://localhost:8080")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatal("invalid lines should be skipped, got", len(entries))
+	}
+
+	first := entries[0]
+	if first.Method != "GET" || first.URL != "http://localhost:8080/products/42?ref=home" || first.Offset != 0 {
+		t.Error("first entry mismatched", first)
+	}
+	if first.Header.Get("Referer") != "https://shop.example.com/" || first.Header.Get("User-Agent") != "Mozilla/5.0 (X11; Linux x86_64)" {
+		t.Error("referer and user agent should be kept, got", first.Header)
+	}
+	if entries[1].Method != "POST" || entries[1].Offset != 2*time.Second {
+		t.Error("second entry mismatched", entries[1])
+	}
+	// the common log format has no referer and user agent
+	if entries[2].URL != "http://localhost:8080/health" || len(entries[2].Header) != 0 {
+		t.Error("third entry mismatched", entries[2])
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"empty.har":   `{"log": {"entries": []}}`,
+		"invalid.har": `{"log": `,
+		"empty.log":   "garbage\n",
+	} {
+		path := filepath.Join(dir, name)
+		ioutil.WriteFile(path, []byte(content), 0644)
+		if _, err := Load(path, "http://localhost"); err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("%s should be invalid, got %v", name, err)
+		}
+	}
+	if _, err := LoadHAR("testdata/shop.har", "://oops"); err == nil {
+		t.Error("invalid base URL should fail")
+	}
+}
@@ -0,0 +1,190 @@
+// Package replay generates load from recorded traffic, like HAR files or access logs of nginx and Apache.
+//
+//	entries, err := replay.Load("access.log", "http://staging:8080")
+//	replayer := replay.NewReplayer(entries)
+//	replayer.Speed = 2
+//	boomer.Run(replayer.Task())
+//
+// The users send the recorded requests in turn. By default, each request is sent at its recorded time
+// since the replay starts, scaled by Speed, and the recording is replayed in a loop. Requests are reported
+// by the http package, named by their URLs with IDs in paths collapsed, like "http://staging:8080/users/:id".
+package replay
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myzhan/boomer"
+	boomerhttp "github.com/myzhan/boomer/http"
+)
+
+// Rule groups URLs into names, every match of the pattern in the path of a URL is replaced.
+type Rule struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// NewRule creates a rule, replacement can refer to submatches like $1.
+func NewRule(pattern, replacement string) (*Rule, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &Rule{re, replacement}, nil
+}
+
+// builtinRules replace whole segments of paths.
+var builtinRules = []*Rule{
+	{regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`), ":uuid"},
+	{regexp.MustCompile(`^\d+$`), ":id"},
+	{regexp.MustCompile(`^[0-9a-fA-F]{16,}$`), ":hash"},
+}
+
+// maxLag is how late a request can be. If the users can't keep up, the replay is delayed instead of
+// sending the late requests in a burst.
+const maxLag = time.Second
+
+// Replayer replays recorded requests, it's shared by the users of its task.
+type Replayer struct {
+	// Client sends the requests, http.NewClient(nil) by default.
+	Client *boomerhttp.Client
+	// Speed scales the recorded timing, 1 keeps it, 2 is twice as fast. With 0, requests are
+	// sent as fast as the users and the rate limiter allow.
+	Speed float64
+	// Header is set on every request, replacing the recorded one, like a fresh Authorization.
+	Header http.Header
+	// Rules group URLs into names, they are applied in order, before the built-in rules replacing
+	// numbers, UUIDs and long hex strings in paths with ":id", ":uuid" and ":hash".
+	Rules []*Rule
+
+	entries []*Entry
+	// names are the names of entries, they are created by the first request
+	names     []string
+	namesOnce sync.Once
+	// cycle is the time of a loop, the next loop starts one average interval after the last request
+	cycle time.Duration
+
+	lock  sync.Mutex
+	start time.Time
+	next  int
+	loops int
+}
+
+// NewReplayer creates a replayer of the entries, keeping the recorded timing.
+func NewReplayer(entries []*Entry) *Replayer {
+	r := &Replayer{
+		Client:  boomerhttp.NewClient(nil),
+		Speed:   1,
+		entries: entries,
+	}
+	if n := len(entries); n > 1 {
+		last := entries[n-1].Offset
+		r.cycle = last + last/time.Duration(n-1)
+	}
+	if r.cycle <= 0 {
+		r.cycle = time.Second
+	}
+	return r
+}
+
+// Task returns a task of the replay.
+func (r *Replayer) Task() *boomer.Task {
+	return &boomer.Task{
+		Name:   "replay",
+		Weight: 1,
+		Fn:     r.Run,
+	}
+}
+
+// Name returns the name of a URL, without the query, and with the path grouped by the rules.
+func (r *Replayer) Name(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	path := u.EscapedPath()
+	for _, rule := range r.Rules {
+		path = rule.pattern.ReplaceAllString(path, rule.replacement)
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		for _, rule := range builtinRules {
+			if rule.pattern.MatchString(segment) {
+				segments[i] = rule.replacement
+				break
+			}
+		}
+	}
+	u.RawQuery = ""
+	u.Fragment = ""
+	u.Path = ""
+	u.RawPath = ""
+	return u.String() + strings.Join(segments, "/")
+}
+
+// schedule takes the next entry, and the time to send it.
+func (r *Replayer) schedule() (int, time.Time) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := time.Now()
+	if r.start.IsZero() {
+		r.start = now
+	}
+	i, loops := r.next, r.loops
+	r.next++
+	if r.next == len(r.entries) {
+		r.next = 0
+		r.loops++
+	}
+
+	if r.Speed <= 0 {
+		return i, now
+	}
+	offset := time.Duration(float64(time.Duration(loops)*r.cycle+r.entries[i].Offset) / r.Speed)
+	due := r.start.Add(offset)
+	if lag := now.Sub(due); lag > maxLag {
+		r.start = r.start.Add(lag)
+		due = now
+	}
+	return i, due
+}
+
+// Run sends the next request, after waiting until its time. It's the Fn of the task.
+func (r *Replayer) Run() {
+	if len(r.entries) == 0 {
+		return
+	}
+	r.namesOnce.Do(func() {
+		r.names = make([]string, len(r.entries))
+		for i, entry := range r.entries {
+			r.names[i] = r.Name(entry.URL)
+		}
+	})
+
+	i, due := r.schedule()
+	if wait := time.Until(due); wait > 0 && !boomer.Sleep(wait) {
+		// stopped while waiting
+		return
+	}
+
+	entry := r.entries[i]
+	request, err := http.NewRequest(entry.Method, entry.URL, bytes.NewReader(entry.Body))
+	if err != nil {
+		log.Printf("Invalid request %s %s, %v\n", entry.Method, entry.URL, err)
+		return
+	}
+	for k, v := range entry.Header {
+		request.Header[k] = v
+	}
+	for k, v := range r.Header {
+		request.Header[k] = v
+	}
+	r.Client.Do(request, boomerhttp.Name(r.names[i]))
+}
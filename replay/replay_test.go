@@ -0,0 +1,143 @@
+package replay
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestName(t *testing.T) {
+	r := NewReplayer(nil)
+	for rawURL, name := range map[string]string{
+		"http://localhost/users/42/orders/7?page=2":                        "http://localhost/users/:id/orders/:id",
+		"http://localhost/files/0123456789abcdef0123/raw":                  "http://localhost/files/:hash/raw",
+		"https://shop/cart/3f2b8c1e-1a2b-4c3d-8e9f-0a1b2c3d4e5f":           "https://shop/cart/:uuid",
+		"http://localhost/v2/api":                                          "http://localhost/v2/api",
+		"http://localhost/":                                                "http://localhost/",
+		"http://localhost/2024/05/01#top":                                  "http://localhost/:id/:id/:id",
+		"http://localhost/users/alice":                                     "http://localhost/users/alice",
+		"http://localhost/a%2Fb/1":                                         "http://localhost/a%2Fb/:id",
+		"http://localhost/products/cafebabe":                               "http://localhost/products/cafebabe",
+		"http://localhost/products/cafebabecafebabe":                       "http://localhost/products/:hash",
+		"http://localhost/3f2b8c1e-1a2b-4c3d-8e9f-0a1b2c3d4e5f/items/12/x": "http://localhost/:uuid/items/:id/x",
+	} {
+		if got := r.Name(rawURL); got != name {
+			t.Errorf("name of %s should be %s, got %s", rawURL, name, got)
+		}
+	}
+
+	rule, err := NewRule(`^/users/[^/]+`, "/users/:name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Rules = []*Rule{rule}
+	if got := r.Name("http://localhost/users/alice/posts/1"); got != "http://localhost/users/:name/posts/:id" {
+		t.Error("rules should be applied before the built-in ones, got", got)
+	}
+	if _, err := NewRule("(", ""); err == nil {
+		t.Error("invalid pattern should fail")
+	}
+}
+
+type received struct {
+	at     time.Time
+	method string
+	path   string
+	header http.Header
+}
+
+func newTestServer(t *testing.T) (*httptest.Server, func() []received) {
+	var lock sync.Mutex
+	var requests []received
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		requests = append(requests, received{time.Now(), r.Method, r.URL.Path, r.Header})
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []received {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]received(nil), requests...)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	server, requests := newTestServer(t)
+	entries, err := LoadHAR("testdata/shop.har", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewReplayer(entries)
+	r.Speed = 5
+	r.Header = http.Header{"Cookie": {"session=fresh"}}
+
+	// 250ms apart at 5x speed, and the next loop starts another 50ms later
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		r.Run()
+	}
+	got := requests()
+	if len(got) != 3 {
+		t.Fatal("requests should be sent in a loop, got", got)
+	}
+	if got[0].method != "GET" || got[1].method != "POST" || got[2].method != "GET" || got[1].path != "/api/cart/items" {
+		t.Error("requests should be sent in the recorded order, got", got)
+	}
+	if got[0].header.Get("Cookie") != "session=fresh" || got[0].header.Get("Accept") != "application/json" {
+		t.Error("header of the replayer should replace the recorded one, got", got[0].header)
+	}
+	if d := got[1].at.Sub(start); d < 40*time.Millisecond || d > 500*time.Millisecond {
+		t.Error("second request should be sent 50ms later, got", d)
+	}
+	if d := got[2].at.Sub(start); d < 90*time.Millisecond || d > 500*time.Millisecond {
+		t.Error("next loop should start 100ms later, got", d)
+	}
+}
+
+func TestReplayAsFastAsPossible(t *testing.T) {
+	server, requests := newTestServer(t)
+	entries, err := LoadAccessLog("testdata/access.log", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewReplayer(entries)
+	r.Speed = 0
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 3; j++ {
+				r.Run()
+			}
+		}()
+	}
+	wg.Wait()
+	if d := time.Since(start); d > time.Second {
+		t.Error("requests should be sent without waiting, took", d)
+	}
+	if n := len(requests()); n != 12 {
+		t.Error("every run should send a request, got", n)
+	}
+}
+
+func TestReplayLag(t *testing.T) {
+	r := NewReplayer([]*Entry{{Offset: 0}, {Offset: 10 * time.Second}})
+
+	// users are too slow, the replay is delayed instead of bursting
+	r.schedule()
+	r.start = r.start.Add(-time.Minute)
+	i, due := r.schedule()
+	if i != 1 || time.Until(due) > 0 {
+		t.Error("late request should be sent now, got", i, due)
+	}
+	i, due = r.schedule()
+	if wait := time.Until(due); i != 0 || wait < 9*time.Second || wait > 11*time.Second {
+		t.Error("next loop should start 10s after the delayed request, got", i, wait)
+	}
+}
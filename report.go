@@ -0,0 +1,299 @@
+package boomer
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"log"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// Percentiles of response times included in the final report, the same as locust.
+var reportPercentiles = []float64{0.5, 0.66, 0.75, 0.8, 0.9, 0.95, 0.98, 0.99, 1.0}
+
+// ErrThresholdsFailed is returned by Run if any threshold fails.
+var ErrThresholdsFailed = errors.New("thresholds failed")
+
+type reportEntry struct {
+	Method             string           `json:"method"`
+	Name               string           `json:"name"`
+	Tags               Tags             `json:"tags,omitempty"`
+	NumRequests        int64            `json:"num_requests"`
+	NumFailures        int64            `json:"num_failures"`
+	FailRatio          float64          `json:"fail_ratio"`
+	AvgResponseTime    float64          `json:"avg_response_time"`
+	MinResponseTime    int64            `json:"min_response_time"`
+	MaxResponseTime    int64            `json:"max_response_time"`
+	MedianResponseTime int64            `json:"median_response_time"`
+	Percentiles        map[string]int64 `json:"percentiles"`
+	RPS                float64          `json:"rps"`
+	TotalContentLength int64            `json:"total_content_length"`
+}
+
+type reportError struct {
+	Method      string `json:"method"`
+	Name        string `json:"name"`
+	Error       string `json:"error"`
+	Occurrences int64  `json:"occurrences"`
+}
+
+type reportTrend struct {
+	Count       int64              `json:"count"`
+	Avg         float64            `json:"avg"`
+	Min         float64            `json:"min"`
+	Max         float64            `json:"max"`
+	Percentiles map[string]float64 `json:"percentiles"`
+}
+
+type reportMetrics struct {
+	Counters map[string]int64        `json:"counters"`
+	Gauges   map[string]float64      `json:"gauges"`
+	Trends   map[string]*reportTrend `json:"trends"`
+}
+
+type reportSecond struct {
+	Time               time.Time `json:"time"`
+	Requests           int64     `json:"requests"`
+	Failures           int64     `json:"failures"`
+	MedianResponseTime int64     `json:"median_response_time"`
+	P95ResponseTime    int64     `json:"p95_response_time"`
+	P99ResponseTime    int64     `json:"p99_response_time"`
+}
+
+type reportTimeSeries struct {
+	Method  string          `json:"method"`
+	Name    string          `json:"name"`
+	Tags    Tags            `json:"tags,omitempty"`
+	Seconds []*reportSecond `json:"seconds"`
+}
+
+type reportTimeline struct {
+	Total        *reportTimeSeries   `json:"total"`
+	Entries      []*reportTimeSeries `json:"entries"`
+	WorstSeconds []*reportSecond     `json:"worst_seconds"`
+}
+
+type report struct {
+	StartTime  time.Time          `json:"start_time"`
+	EndTime    time.Time          `json:"end_time"`
+	Duration   float64            `json:"duration"`
+	Config     map[string]string  `json:"config"`
+	Total      *reportEntry       `json:"total"`
+	Entries    []*reportEntry     `json:"entries"`
+	Errors     []*reportError     `json:"errors"`
+	Metrics    *reportMetrics     `json:"metrics"`
+	Timeline   *reportTimeline    `json:"timeline,omitempty"`
+	Thresholds []*thresholdResult `json:"thresholds"`
+	Passed     bool               `json:"passed"`
+}
+
+func newReportEntry(entry *statsEntry, duration float64) *reportEntry {
+	e := &reportEntry{
+		Method:             entry.method,
+		Name:               entry.name,
+		Tags:               entry.tags,
+		NumRequests:        entry.numRequests,
+		NumFailures:        entry.numFailures,
+		FailRatio:          entryMetric(entry, "fail_ratio", duration),
+		AvgResponseTime:    entryMetric(entry, "avg", duration),
+		MinResponseTime:    entry.minResponseTime,
+		MaxResponseTime:    entry.maxResponseTime,
+		MedianResponseTime: entry.getResponseTimePercentile(0.5),
+		Percentiles:        make(map[string]int64, len(reportPercentiles)),
+		RPS:                entryMetric(entry, "rps", duration),
+		TotalContentLength: entry.totalContentLength,
+	}
+	for _, p := range reportPercentiles {
+		e.Percentiles[formatPercentile(p)] = entry.getResponseTimePercentile(p)
+	}
+	return e
+}
+
+// formatPercentile converts 0.99 to "p99", 0.999 to "p99.9".
+func formatPercentile(p float64) string {
+	return "p" + formatFloat(p*100)
+}
+
+func newReportMetrics(m *customMetrics) *reportMetrics {
+	r := &reportMetrics{
+		Counters: m.counters,
+		Gauges:   m.gauges,
+		Trends:   make(map[string]*reportTrend, len(m.trends)),
+	}
+	for name, trend := range m.trends {
+		t := &reportTrend{
+			Count:       trend.count,
+			Avg:         trend.avg(),
+			Min:         trend.min,
+			Max:         trend.max,
+			Percentiles: make(map[string]float64, len(reportPercentiles)),
+		}
+		for _, p := range reportPercentiles {
+			t.Percentiles[formatPercentile(p)] = trend.percentile(p)
+		}
+		r.Trends[name] = t
+	}
+	return r
+}
+
+func newReportSeconds(buckets []*timeBucket) []*reportSecond {
+	seconds := make([]*reportSecond, 0, len(buckets))
+	for _, b := range buckets {
+		seconds = append(seconds, &reportSecond{
+			Time:               time.Unix(b.Second, 0),
+			Requests:           b.Requests,
+			Failures:           b.Failures,
+			MedianResponseTime: b.percentile(0.5),
+			P95ResponseTime:    b.percentile(0.95),
+			P99ResponseTime:    b.percentile(0.99),
+		})
+	}
+	return seconds
+}
+
+func newReportTimeline(t *timelineSnapshot) *reportTimeline {
+	r := &reportTimeline{
+		Total: &reportTimeSeries{
+			Name:    "Total",
+			Seconds: newReportSeconds(t.total),
+		},
+		Entries:      make([]*reportTimeSeries, 0, len(t.entries)),
+		WorstSeconds: newReportSeconds(worstSeconds(t.total, 10)),
+	}
+	for _, ts := range t.entries {
+		r.Entries = append(r.Entries, &reportTimeSeries{
+			Method:  ts.method,
+			Name:    ts.name,
+			Tags:    ts.tags,
+			Seconds: newReportSeconds(ts.buckets),
+		})
+	}
+	sort.Slice(r.Entries, func(i, j int) bool {
+		if r.Entries[i].Name == r.Entries[j].Name {
+			return r.Entries[i].Method < r.Entries[j].Method
+		}
+		return r.Entries[i].Name < r.Entries[j].Name
+	})
+	return r
+}
+
+// sensitiveFlagPattern matches flags of secrets, like --zmq-plain-password and --bearer-token.
+var sensitiveFlagPattern = regexp.MustCompile(`password|token|auth|key|secret`)
+
+// configValue returns the value of a flag in the report, secrets are redacted.
+func configValue(f *flag.Flag) string {
+	value := f.Value.String()
+	if value != "" && sensitiveFlagPattern.MatchString(f.Name) {
+		return "******"
+	}
+	return value
+}
+
+// newReport builds the final report from everything accumulated during the whole test.
+func newReport(summary *testSummary, endTime time.Time) *report {
+	s := summary.stats
+	startTime := time.Unix(s.startTime, 0)
+	duration := endTime.Sub(startTime).Seconds()
+
+	r := &report{
+		StartTime: startTime,
+		EndTime:   endTime,
+		Duration:  duration,
+		Config:    make(map[string]string),
+		Total:     newReportEntry(s.total, duration),
+		Entries:   make([]*reportEntry, 0, len(s.entries)),
+		Errors:    make([]*reportError, 0, len(s.errors)),
+		Metrics:   newReportMetrics(summary.metrics),
+		Passed:    true,
+	}
+
+	if summary.timeline != nil {
+		r.Timeline = newReportTimeline(summary.timeline)
+	}
+
+	flag.VisitAll(func(f *flag.Flag) {
+		r.Config[f.Name] = configValue(f)
+	})
+
+	for _, entry := range s.entries {
+		r.Entries = append(r.Entries, newReportEntry(entry, duration))
+	}
+	sort.Slice(r.Entries, func(i, j int) bool {
+		if r.Entries[i].Name == r.Entries[j].Name {
+			return r.Entries[i].Method < r.Entries[j].Method
+		}
+		return r.Entries[i].Name < r.Entries[j].Name
+	})
+
+	for _, err := range s.errors {
+		r.Errors = append(r.Errors, &reportError{
+			Method:      err.method,
+			Name:        err.name,
+			Error:       err.error,
+			Occurrences: err.occurences,
+		})
+	}
+	sort.Slice(r.Errors, func(i, j int) bool {
+		return r.Errors[i].Occurrences > r.Errors[j].Occurrences
+	})
+
+	for _, t := range thresholds {
+		result := t.evaluate(s, duration)
+		r.Thresholds = append(r.Thresholds, result)
+		if !result.Passed {
+			r.Passed = false
+		}
+	}
+
+	return r
+}
+
+func (r *report) writeFile(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// finishReport evaluates thresholds against the stats of the whole test, writes the report
+// if --report-file is specified, and returns ErrThresholdsFailed if any threshold fails.
+func finishReport() error {
+	if *reportFile == "" && len(thresholds) == 0 {
+		return nil
+	}
+
+	r := newReport(getSummary(), time.Now())
+
+	for _, result := range r.Thresholds {
+		if result.Error != "" {
+			log.Printf("Threshold %q failed, %s\n", result.Expression, result.Error)
+		} else if result.Passed {
+			log.Printf("Threshold %q passed, actual value is %s\n", result.Expression, formatFloat(result.Actual))
+		} else {
+			log.Printf("Threshold %q failed, actual value is %s\n", result.Expression, formatFloat(result.Actual))
+		}
+	}
+
+	if *reportFile != "" {
+		if err := r.writeFile(*reportFile); err != nil {
+			log.Println("Failed to write report:", err)
+		} else {
+			log.Println("Report is written to", *reportFile)
+		}
+	}
+
+	if !r.Passed {
+		return ErrThresholdsFailed
+	}
+	return nil
+}
+
+var reportFile *string
+
+func init() {
+	reportFile = flag.String("report-file", "", "Write a JSON report of the whole test to this file on exit.")
+}
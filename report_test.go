@@ -0,0 +1,38 @@
+package boomer
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReportRedactsSecrets(t *testing.T) {
+	defer func(password string) {
+		*zmqPlainPassword = password
+	}(*zmqPlainPassword)
+	*zmqPlainPassword = "hunter2"
+
+	summary := &testSummary{
+		stats:   newRequestStats(),
+		metrics: newCustomMetrics(),
+	}
+	r := newReport(summary, time.Now())
+	if r.Config["zmq-plain-password"] != "******" {
+		t.Error("password should be redacted, got", r.Config["zmq-plain-password"])
+	}
+	if r.Config["zmq-curve-server-key"] != "" {
+		t.Error("empty values should be kept, got", r.Config["zmq-curve-server-key"])
+	}
+	if r.Config["master-port"] != "5557" {
+		t.Error("other flags should be kept, got", r.Config["master-port"])
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "hunter2") {
+		t.Error("password should never appear in the report")
+	}
+}
@@ -3,8 +3,9 @@ package boomer
 import (
 	"fmt"
 	"log"
-	"os"
+	"math"
 	"runtime/debug"
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -14,6 +15,7 @@ const (
 	stateHatching = "hatching"
 	stateRunning  = "running"
 	stateStopped  = "stopped"
+	stateQuitting = "quitting"
 )
 
 const (
@@ -27,16 +29,40 @@ type Task struct {
 	Weight int
 	Fn     func()
 	Name   string
+	// NewUser creates a user for each user hatched, Fn is ignored if it's set.
+	NewUser func() User
 }
 
 type runner struct {
-	tasks       []*Task
-	numClients  int32
-	hatchRate   int
-	stopChannel chan bool
-	state       string
-	client      client
-	nodeID      string
+	tasks        []*Task
+	numClients   int32
+	hatchRate    int
+	stopChannel  chan bool
+	closeChannel chan bool
+	closeOnce    sync.Once
+	clientOnce   sync.Once
+	// users counts the hatching goroutine and the users it spawned.
+	users sync.WaitGroup
+	// reporting is the goroutine forwarding stats to the master.
+	reporting sync.WaitGroup
+	stateLock sync.Mutex
+	state     string
+	client    client
+	nodeID    string
+	// rateLimiter starts with --max-rps, the master may change it.
+	rateLimiter *rateLimiter
+	// onQuit is called when the master asks the worker to quit.
+	onQuit func()
+}
+
+func newRunner(tasks []*Task, client client) *runner {
+	return &runner{
+		tasks:        tasks,
+		client:       client,
+		nodeID:       getNodeID(),
+		closeChannel: make(chan bool),
+		rateLimiter:  newRateLimiter(maxRPS),
+	}
 }
 
 func (r *runner) safeRun(fn func()) {
@@ -52,6 +78,7 @@ func (r *runner) safeRun(fn func()) {
 }
 
 func (r *runner) spawnGoRoutines(spawnCount int, quit chan bool) {
+	defer r.users.Done()
 
 	log.Println("Hatching and swarming", spawnCount, "clients at the rate", r.hatchRate, "clients/s...")
 
@@ -79,26 +106,11 @@ func (r *runner) spawnGoRoutines(spawnCount int, quit chan bool) {
 					time.Sleep(1 * time.Second)
 				}
 				atomic.AddInt32(&r.numClients, 1)
-				go func(fn func()) {
-					for {
-						select {
-						case <-quit:
-							return
-						default:
-							if maxRPSEnabled {
-								token := atomic.AddInt64(&maxRPSThreshold, -1)
-								if token < 0 {
-									// max RPS is reached, wait until next second
-									<-maxRPSControlChannel
-								} else {
-									r.safeRun(fn)
-								}
-							} else {
-								r.safeRun(fn)
-							}
-						}
-					}
-				}(task.Fn)
+				r.users.Add(1)
+				go func(task *Task) {
+					defer r.users.Done()
+					r.runUser(task, quit)
+				}(task)
 			}
 
 		}
@@ -109,7 +121,22 @@ func (r *runner) spawnGoRoutines(spawnCount int, quit chan bool) {
 
 }
 
+// startHatching publishes "boomer:hatch" with spawnCount and hatchRate after users start hatching.
 func (r *runner) startHatching(spawnCount int, hatchRate int) {
+	if r.hatch(spawnCount, hatchRate) {
+		Events.Publish("boomer:hatch", spawnCount, hatchRate)
+	}
+}
+
+func (r *runner) hatch(spawnCount int, hatchRate int) bool {
+
+	r.stateLock.Lock()
+	defer r.stateLock.Unlock()
+
+	if r.state == stateQuitting {
+		log.Println("Worker is shutting down, hatch message is ignored")
+		return false
+	}
 
 	if r.state != stateRunning && r.state != stateHatching {
 		clearStatsChannel <- true
@@ -120,99 +147,234 @@ func (r *runner) startHatching(spawnCount int, hatchRate int) {
 		// stop previous goroutines without blocking
 		// those goroutines will exit when r.safeRun returns
 		close(r.stopChannel)
+		cancelRendezvous()
 	}
 
 	r.stopChannel = make(chan bool)
+	setSleepStop(r.stopChannel)
 	r.state = stateHatching
 
 	r.hatchRate = hatchRate
-	r.numClients = 0
+	atomic.StoreInt32(&r.numClients, 0)
+	r.users.Add(1)
 	go r.spawnGoRoutines(spawnCount, r.stopChannel)
+	return true
 }
 
 func (r *runner) hatchComplete() {
 
 	data := make(map[string]interface{})
-	data["count"] = r.numClients
-	toMaster <- newMessage("hatch_complete", data, r.nodeID)
+	data["count"] = atomic.LoadInt32(&r.numClients)
+	sendToMaster(newMessage("hatch_complete", data, r.nodeID))
 
-	r.state = stateRunning
+	r.stateLock.Lock()
+	if r.state == stateHatching {
+		r.state = stateRunning
+	}
+	r.stateLock.Unlock()
 }
 
 func (r *runner) onQuiting() {
-	toMaster <- newMessage("quit", nil, r.nodeID)
+	sendToMaster(newMessage("quit", nil, r.nodeID))
 }
 
+// stop publishes "boomer:stop" if users are stopped.
 func (r *runner) stop() {
+	if r.stopUsers() {
+		Events.Publish("boomer:stop")
+	}
+}
+
+func (r *runner) stopUsers() bool {
+
+	r.stateLock.Lock()
+	defer r.stateLock.Unlock()
 
 	if r.state == stateRunning || r.state == stateHatching {
 		close(r.stopChannel)
+		cancelRendezvous()
 		r.state = stateStopped
 		log.Println("Recv stop message from master, all the goroutines are stopped")
+		return true
 	}
+	return false
+
+}
 
+// close stops all the goroutines of the runner and closes its client.
+func (r *runner) close() {
+	r.stop()
+	r.closeOnce.Do(func() { close(r.closeChannel) })
+	r.clientOnce.Do(r.client.close)
+	clearWorkerNodeID(r.nodeID)
+}
+
+// shutdown stops the users and waits for them to finish their current tasks, sends the final stats,
+// and closes the client. The quit message is sent to master, unless it's the master asking to quit.
+func (r *runner) shutdown(sendQuit bool) {
+	r.stateLock.Lock()
+	if r.state == stateRunning || r.state == stateHatching {
+		close(r.stopChannel)
+		cancelRendezvous()
+	}
+	r.state = stateQuitting
+	r.stateLock.Unlock()
+
+	if !r.waitForUsers(*shutdownTimeout) {
+		log.Println("Timeout waiting for users to finish their tasks")
+	}
+
+	// stop reporting, and send the rest in order
+	r.closeOnce.Do(func() { close(r.closeChannel) })
+	r.reporting.Wait()
+	for len(messageToRunner) > 0 {
+		r.sendStats(<-messageToRunner)
+	}
+	r.sendStats(flushStats())
+
+	if sendQuit {
+		// drop the notification left by a previous runner
+		select {
+		case <-disconnectedFromMaster:
+		default:
+		}
+		r.onQuiting()
+		select {
+		case <-disconnectedFromMaster:
+		case <-time.After(*masterSendTimeout):
+			log.Println("Timeout waiting for quit message to be sent to master")
+		}
+	}
+	r.clientOnce.Do(r.client.close)
+	clearWorkerNodeID(r.nodeID)
+}
+
+func (r *runner) waitForUsers(timeout time.Duration) bool {
+	done := make(chan bool)
+	go func() {
+		r.users.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (r *runner) sendStats(data map[string]interface{}) {
+	data["user_count"] = atomic.LoadInt32(&r.numClients)
+	sendToMaster(newMessage("stats", data, r.nodeID))
+}
+
+func (r *runner) onMessage(msg *message) {
+	switch msg.Type {
+	case "hatch":
+		hatch, err := decodeHatch(msg.Data)
+		if err != nil {
+			log.Println("Invalid hatch message from master,", err)
+			return
+		}
+		if hatch.workerCount > 0 {
+			setWorkerPartition(hatch.workerIndex, hatch.workerCount)
+		}
+		sendToMaster(newMessage("hatching", nil, r.nodeID))
+		// hatch rate is divided among workers by the master, it may be less than 1
+		r.startHatching(hatch.numClients, int(math.Ceil(hatch.hatchRate)))
+	case "stop":
+		r.stop()
+		sendToMaster(newMessage("client_stopped", nil, r.nodeID))
+		sendToMaster(newMessage("client_ready", nil, r.nodeID))
+	case "max_rps":
+		maxRPSMessage, err := decodeMaxRPS(msg.Data)
+		if err != nil {
+			log.Println("Invalid max_rps message from master,", err)
+			return
+		}
+		r.setMasterMaxRPS(maxRPSMessage.maxRPS)
+	case "rendezvous_release":
+		release, err := decodeRendezvousRelease(msg.Data)
+		if err != nil {
+			log.Println("Invalid rendezvous_release message from master,", err)
+			return
+		}
+		releaseRendezvous(release.ids)
+	case "quit":
+		log.Println("Got quit message from master, shutting down...")
+		if r.onQuit != nil {
+			r.onQuit()
+		}
+	default:
+		if handler := getMessageHandler(msg.Type); handler != nil {
+			handler(msg.Data)
+			return
+		}
+		log.Printf("Unknown message %q from master is ignored\n", msg.Type)
+	}
+}
+
+// setMasterMaxRPS adopts the share of the cluster-wide max RPS, --max-rps of the worker is still respected.
+func (r *runner) setMasterMaxRPS(share int64) {
+	limit := share
+	if maxRPS > 0 && (limit == 0 || maxRPS < limit) {
+		limit = maxRPS
+	}
+	if limit > 0 {
+		log.Println("Max RPS is set by master to", share, "the limit is", limit)
+	} else {
+		log.Println("Max RPS is unlimited by master")
+	}
+	r.rateLimiter.setMaxRPS(limit)
 }
 
 func (r *runner) getReady() {
 
 	r.state = stateInit
+	setWorkerNodeID(r.nodeID)
 
 	// read message from master
 	go func() {
 		for {
-			msg := <-fromMaster
-			switch msg.Type {
-			case "hatch":
-				toMaster <- newMessage("hatching", nil, r.nodeID)
-				rate, _ := msg.Data["hatch_rate"]
-				clients, _ := msg.Data["num_clients"]
-				hatchRate := int(rate.(float64))
-				workers := 0
-				if _, ok := clients.(uint64); ok {
-					workers = int(clients.(uint64))
-				} else {
-					workers = int(clients.(int64))
-				}
-				if workers == 0 || hatchRate == 0 {
-					log.Printf("Invalid hatch message from master, num_clients is %d, hatch_rate is %d\n",
-						workers, hatchRate)
-				} else {
-					r.startHatching(workers, hatchRate)
-				}
-			case "stop":
-				r.stop()
-				toMaster <- newMessage("client_stopped", nil, r.nodeID)
-				toMaster <- newMessage("client_ready", nil, r.nodeID)
-			case "quit":
-				log.Println("Got quit message from master, shutting down...")
-				os.Exit(0)
+			select {
+			case msg := <-fromMaster:
+				r.onMessage(msg)
+			case <-r.closeChannel:
+				return
 			}
 		}
 	}()
 
 	// tell master, I'm ready
-	toMaster <- newMessage("client_ready", nil, r.nodeID)
+	sendToMaster(newMessage("client_ready", nil, r.nodeID))
 
 	// report to master
+	r.reporting.Add(1)
 	go func() {
+		defer r.reporting.Done()
 		for {
 			select {
 			case data := <-messageToRunner:
-				data["user_count"] = r.numClients
-				toMaster <- newMessage("stats", data, r.nodeID)
+				data["user_count"] = atomic.LoadInt32(&r.numClients)
+				msg := newMessage("stats", data, r.nodeID)
+				select {
+				case toMaster <- msg:
+					continue
+				default:
+				}
+				// stats are never dropped, while waiting here, new stats are coalesced by the stats goroutine
+				atomic.AddInt64(&delayedReports, 1)
+				select {
+				case toMaster <- msg:
+				case <-r.closeChannel:
+					sendToMaster(msg)
+					return
+				}
+			case <-r.closeChannel:
+				return
 			}
 		}
 	}()
 
-	if maxRPSEnabled {
-		go func() {
-			for {
-				atomic.StoreInt64(&maxRPSThreshold, maxRPS)
-				time.Sleep(1 * time.Second)
-				// use channel to broadcast
-				close(maxRPSControlChannel)
-				maxRPSControlChannel = make(chan bool)
-			}
-		}()
-	}
+	go r.rateLimiter.start(r.closeChannel)
 }
@@ -0,0 +1,75 @@
+package scenario
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPath is a subset of JSONPath, like $.items[0].id or $['first name'], without wildcards and filters.
+// Its elements are names of objects, or indexes of arrays.
+type jsonPath []interface{}
+
+func parseJSONPath(path string) (jsonPath, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("JSONPath %q should start with $", path)
+	}
+	p := jsonPath{}
+	rest := path[1:]
+	for rest != "" {
+		switch {
+		case rest[0] == '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			name := rest[1 : end+1]
+			if name == "" || name == "*" {
+				return nil, fmt.Errorf("invalid JSONPath %q", path)
+			}
+			p = append(p, name)
+			rest = rest[end+1:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSONPath %q", path)
+			}
+			inside := rest[1:end]
+			if len(inside) >= 2 && (inside[0] == '\'' || inside[0] == '"') && inside[len(inside)-1] == inside[0] {
+				p = append(p, inside[1:len(inside)-1])
+			} else if index, err := strconv.Atoi(inside); err == nil && index >= 0 {
+				p = append(p, index)
+			} else {
+				return nil, fmt.Errorf("invalid JSONPath %q", path)
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid JSONPath %q", path)
+		}
+	}
+	return p, nil
+}
+
+// lookup returns the value at the path of a decoded JSON document.
+func (p jsonPath) lookup(document interface{}) (interface{}, bool) {
+	value := document
+	for _, element := range p {
+		switch key := element.(type) {
+		case string:
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if value, ok = object[key]; !ok {
+				return nil, false
+			}
+		case int:
+			array, ok := value.([]interface{})
+			if !ok || key >= len(array) {
+				return nil, false
+			}
+			value = array[key]
+		}
+	}
+	return value, true
+}
@@ -0,0 +1,41 @@
+package scenario
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONPath(t *testing.T) {
+	var document interface{}
+	json.Unmarshal([]byte(`{"items": [{"id": 1, "tags": ["a", "b"]}], "first name": "Ada", "ok": true}`), &document)
+
+	for path, want := range map[string]string{
+		"$":                  `{"first name":"Ada","items":[{"id":1,"tags":["a","b"]}],"ok":true}`,
+		"$.items[0].id":      "1",
+		"$.items[0].tags[1]": "b",
+		"$['first name']":    "Ada",
+		`$["items"][0].tags`: `["a","b"]`,
+		"$.ok":               "true",
+	} {
+		p, err := parseJSONPath(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		value, ok := p.lookup(document)
+		if !ok || stringify(value) != want {
+			t.Errorf("%s should be %s, got %v", path, want, value)
+		}
+	}
+
+	for _, path := range []string{"$.items[1]", "$.items.id", "$.ok.value", "$.missing"} {
+		p, _ := parseJSONPath(path)
+		if value, ok := p.lookup(document); ok {
+			t.Errorf("%s should not be found, got %v", path, value)
+		}
+	}
+	for _, path := range []string{"items", "$.", "$.items[", "$.items[-1]", "$.items[*]", "$..id", "$x"} {
+		if _, err := parseJSONPath(path); err == nil {
+			t.Errorf("%s should be invalid", path)
+		}
+	}
+}
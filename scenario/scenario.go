@@ -0,0 +1,414 @@
+// Package scenario runs load tests declared in YAML or JSON files, so tests can be written without Go.
+//
+//	host: http://localhost:8080
+//	data:
+//	  accounts: {file: accounts.csv, strategy: unique-per-user}
+//	users:
+//	  - name: shopper
+//	    wait: 1s-3s
+//	    data: [accounts]
+//	    on_start:
+//	      - request: {method: POST, url: /login, body: '{"username": "{{.accounts.username}}"}'}
+//	        extract: {token: {jsonpath: $.token}}
+//	    steps:
+//	      - weight: 3
+//	        request: {url: /products, headers: {Authorization: 'Bearer {{.token}}'}}
+//	        assert: {status: 200-299, jsonpath: {'$.items[0].id': ""}}
+//
+// Each kind of users is a task weighted by its weight. Each user runs on_start once, then picks one of
+// the steps by their weights repeatedly, waiting between them, and runs on_stop when it's stopped.
+// A step with steps runs them in order. URLs, bodies and headers are text/template templates with the
+// variables, the records drawn from data, and the values extracted by previous steps of the user.
+// Requests are reported by the http package, named by the name of the step, or its URL template.
+package scenario
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/myzhan/boomer"
+	boomerhttp "github.com/myzhan/boomer/http"
+	"gopkg.in/yaml.v3"
+)
+
+// Scenario is a load test.
+type Scenario struct {
+	// Host is prepended to URLs starting with "/", like "http://localhost:8080".
+	Host string `yaml:"host"`
+	// Headers are sent with every request.
+	Headers map[string]string `yaml:"headers"`
+	// Variables are available to every user, like {{.category}}.
+	Variables map[string]string `yaml:"variables"`
+	// Timeout of each request, 10 seconds by default.
+	Timeout time.Duration `yaml:"timeout"`
+	// Data are feeders by their names.
+	Data map[string]*Data `yaml:"data"`
+	// Users are the kinds of users.
+	Users []*User `yaml:"users"`
+
+	feeders   map[string]*boomer.Feeder
+	transport *http.Transport
+}
+
+// Data is a CSV or JSON-lines file of test data, the records are available as {{.name.field}}.
+type Data struct {
+	// File is relative to the scenario file, it's JSON lines if it ends with .jsonl or .json.
+	File string `yaml:"file"`
+	// Strategy is sequential, circular, random or unique-per-user, circular by default.
+	Strategy string `yaml:"strategy"`
+	// Partition partitions the records across workers, see boomer.PartitionByWorker.
+	Partition bool `yaml:"partition"`
+}
+
+// User is a kind of users.
+type User struct {
+	Name string `yaml:"name"`
+	// Weight of the task, 1 by default.
+	Weight int `yaml:"weight"`
+	// Wait is the time between steps, like "1s", or a random time in a range like "1s-3s".
+	Wait string `yaml:"wait"`
+	// Data are drawn once by each user, and released when it's stopped.
+	Data    []string `yaml:"data"`
+	OnStart []*Step  `yaml:"on_start"`
+	Steps   []*Step  `yaml:"steps"`
+	OnStop  []*Step  `yaml:"on_stop"`
+
+	waitMin, waitMax time.Duration
+	totalWeight      int
+}
+
+// Step is a request, or steps run in order.
+type Step struct {
+	// Name is the name of the request in the stats, it's the URL template by default.
+	Name string `yaml:"name"`
+	// Weight of the step in the steps of the user, 1 by default.
+	Weight int `yaml:"weight"`
+	// Data are drawn each time the step runs, and released when it finishes.
+	Data    []string              `yaml:"data"`
+	Request *Request              `yaml:"request"`
+	Extract map[string]*Extractor `yaml:"extract"`
+	Assert  *Assert               `yaml:"assert"`
+	Steps   []*Step               `yaml:"steps"`
+
+	url     *template.Template
+	body    *template.Template
+	headers map[string]*template.Template
+	checks  []boomerhttp.Check
+}
+
+// Request is an HTTP request.
+type Request struct {
+	// Method is GET by default.
+	Method  string            `yaml:"method"`
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
+}
+
+// Extractor saves a value of the response as a variable of the user, by one of JSONPath, Regex or Header.
+type Extractor struct {
+	// JSONPath is like "$.items[0].id", a subset of JSONPath with names and indexes.
+	JSONPath string `yaml:"jsonpath"`
+	// Regex extracts the first submatch, or the whole match without submatches.
+	Regex  string `yaml:"regex"`
+	Header string `yaml:"header"`
+
+	path  jsonPath
+	regex *regexp.Regexp
+}
+
+// Assert decides if a response is successful, status codes below 400 are successful by default.
+type Assert struct {
+	// Status is like "200" or "200-299,304".
+	Status       string `yaml:"status"`
+	BodyContains string `yaml:"body_contains"`
+	// JSONPath are expected values by JSONPath, they are templates, "" only checks the value exists.
+	JSONPath map[string]string `yaml:"jsonpath"`
+
+	paths    map[string]jsonPath
+	expected map[string]*template.Template
+}
+
+// Load loads a scenario from a YAML or JSON file.
+func Load(path string) (*Scenario, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := Parse(content, filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return s, nil
+}
+
+// Parse parses a scenario in YAML or JSON, files of data are relative to dir.
+func Parse(content []byte, dir string) (*Scenario, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	s := &Scenario{}
+	if err := decoder.Decode(s); err != nil {
+		return nil, err
+	}
+	if err := s.compile(dir); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+var strategies = map[string]boomer.Strategy{
+	"":                boomer.Circular,
+	"sequential":      boomer.Sequential,
+	"circular":        boomer.Circular,
+	"random":          boomer.Random,
+	"unique-per-user": boomer.UniquePerUser,
+}
+
+func (s *Scenario) compile(dir string) error {
+	if s.Timeout <= 0 {
+		s.Timeout = 10 * time.Second
+	}
+	s.Host = strings.TrimSuffix(s.Host, "/")
+
+	s.feeders = make(map[string]*boomer.Feeder)
+	for name, data := range s.Data {
+		strategy, ok := strategies[data.Strategy]
+		if !ok {
+			return fmt.Errorf("data %s: unknown strategy %q", name, data.Strategy)
+		}
+		var options []boomer.FeederOption
+		if data.Partition {
+			options = append(options, boomer.PartitionByWorker())
+		}
+		file := data.File
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		var feeder *boomer.Feeder
+		var err error
+		if ext := filepath.Ext(file); ext == ".jsonl" || ext == ".json" {
+			feeder, err = boomer.NewJSONLinesFeeder(file, strategy, options...)
+		} else {
+			feeder, err = boomer.NewCSVFeeder(file, strategy, options...)
+		}
+		if err != nil {
+			return fmt.Errorf("data %s: %v", name, err)
+		}
+		s.feeders[name] = feeder
+	}
+
+	if len(s.Users) == 0 {
+		return errors.New("no users")
+	}
+	for i, u := range s.Users {
+		if u.Name == "" {
+			u.Name = fmt.Sprintf("user%d", i+1)
+		}
+		if err := s.compileUser(u); err != nil {
+			return fmt.Errorf("user %s: %v", u.Name, err)
+		}
+	}
+
+	s.transport = http.DefaultTransport.(*http.Transport).Clone()
+	s.transport.MaxIdleConnsPerHost = 2000
+	return nil
+}
+
+func (s *Scenario) compileUser(u *User) error {
+	if u.Weight < 0 {
+		return fmt.Errorf("weight should not be negative, not %d", u.Weight)
+	}
+	if u.Weight == 0 {
+		u.Weight = 1
+	}
+	var err error
+	if u.waitMin, u.waitMax, err = parseWait(u.Wait); err != nil {
+		return err
+	}
+	if err := s.checkData(u.Data); err != nil {
+		return err
+	}
+	if len(u.Steps) == 0 {
+		return errors.New("no steps")
+	}
+	for _, steps := range [][]*Step{u.OnStart, u.Steps, u.OnStop} {
+		for _, step := range steps {
+			if err := s.compileStep(step); err != nil {
+				return err
+			}
+		}
+	}
+	for _, step := range u.Steps {
+		u.totalWeight += step.Weight
+	}
+	return nil
+}
+
+// parseWait parses "1s", or "1s-3s".
+func parseWait(wait string) (min, max time.Duration, err error) {
+	if wait == "" {
+		return 0, 0, nil
+	}
+	bounds := strings.SplitN(wait, "-", 2)
+	if min, err = time.ParseDuration(strings.TrimSpace(bounds[0])); err != nil {
+		return 0, 0, fmt.Errorf("invalid wait %q", wait)
+	}
+	max = min
+	if len(bounds) == 2 {
+		if max, err = time.ParseDuration(strings.TrimSpace(bounds[1])); err != nil || max < min {
+			return 0, 0, fmt.Errorf("invalid wait %q", wait)
+		}
+	}
+	if min < 0 {
+		return 0, 0, fmt.Errorf("invalid wait %q", wait)
+	}
+	return min, max, nil
+}
+
+func (s *Scenario) checkData(names []string) error {
+	for _, name := range names {
+		if s.feeders[name] == nil {
+			return fmt.Errorf("unknown data %q", name)
+		}
+	}
+	return nil
+}
+
+func (s *Scenario) compileStep(step *Step) (err error) {
+	defer func() {
+		if err != nil && step.Name != "" {
+			err = fmt.Errorf("step %s: %v", step.Name, err)
+		}
+	}()
+
+	if step.Weight < 0 {
+		return fmt.Errorf("weight should not be negative, not %d", step.Weight)
+	}
+	if step.Weight == 0 {
+		step.Weight = 1
+	}
+	if err := s.checkData(step.Data); err != nil {
+		return err
+	}
+	if (step.Request == nil) == (len(step.Steps) == 0) {
+		return errors.New("a step should have either a request or steps")
+	}
+	for _, sub := range step.Steps {
+		if err := s.compileStep(sub); err != nil {
+			return err
+		}
+	}
+	if step.Request == nil {
+		if step.Extract != nil || step.Assert != nil {
+			return errors.New("extract and assert need a request")
+		}
+		return nil
+	}
+
+	r := step.Request
+	if r.Method == "" {
+		r.Method = http.MethodGet
+	}
+	r.Method = strings.ToUpper(r.Method)
+	if r.URL == "" {
+		return errors.New("no URL in the request")
+	}
+	if strings.HasPrefix(r.URL, "/") && s.Host == "" {
+		return fmt.Errorf("host is required by %s", r.URL)
+	}
+	if step.Name == "" {
+		step.Name = r.URL
+	}
+	if step.url, err = parseTemplate(r.URL); err != nil {
+		return err
+	}
+	if r.Body != "" {
+		if step.body, err = parseTemplate(r.Body); err != nil {
+			return err
+		}
+	}
+	step.headers = make(map[string]*template.Template)
+	for name, value := range r.Headers {
+		if step.headers[name], err = parseTemplate(value); err != nil {
+			return err
+		}
+	}
+
+	for name, extractor := range step.Extract {
+		if err := extractor.compile(); err != nil {
+			return fmt.Errorf("extract %s: %v", name, err)
+		}
+	}
+	step.checks = []boomerhttp.Check{boomerhttp.StatusRange(100, 399)}
+	if a := step.Assert; a != nil {
+		if a.Status != "" {
+			check, err := boomerhttp.ParseStatus(a.Status)
+			if err != nil {
+				return err
+			}
+			step.checks[0] = check
+		}
+		if a.BodyContains != "" {
+			step.checks = append(step.checks, boomerhttp.BodyContains(a.BodyContains))
+		}
+		a.paths = make(map[string]jsonPath)
+		a.expected = make(map[string]*template.Template)
+		for path, expected := range a.JSONPath {
+			if a.paths[path], err = parseJSONPath(path); err != nil {
+				return err
+			}
+			if a.expected[path], err = parseTemplate(expected); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (e *Extractor) compile() (err error) {
+	kinds := 0
+	for _, kind := range []string{e.JSONPath, e.Regex, e.Header} {
+		if kind != "" {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return errors.New("one of jsonpath, regex and header should be given")
+	}
+	if e.JSONPath != "" {
+		e.path, err = parseJSONPath(e.JSONPath)
+	}
+	if e.Regex != "" {
+		e.regex, err = regexp.Compile(e.Regex)
+	}
+	return err
+}
+
+// parseTemplate parses a template, missing variables are errors instead of "<no value>".
+func parseTemplate(text string) (*template.Template, error) {
+	return template.New("").Funcs(boomerhttp.TemplateFuncs).Option("missingkey=error").Parse(text)
+}
+
+// Tasks returns a task for each kind of users.
+func (s *Scenario) Tasks() []*boomer.Task {
+	tasks := make([]*boomer.Task, 0, len(s.Users))
+	for _, u := range s.Users {
+		u := u
+		tasks = append(tasks, &boomer.Task{
+			Name:   u.Name,
+			Weight: u.Weight,
+			NewUser: func() boomer.User {
+				return newVirtualUser(s, u)
+			},
+		})
+	}
+	return tasks
+}
@@ -0,0 +1,74 @@
+package scenario
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	s, err := Load("testdata/shop.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Timeout != 3*time.Second || s.Headers["Accept"] != "application/json" || len(s.feeders) != 2 {
+		t.Error("scenario mismatched", s)
+	}
+
+	shopper := s.Users[0]
+	if shopper.waitMin != 10*time.Millisecond || shopper.waitMax != 20*time.Millisecond || shopper.totalWeight != 4 {
+		t.Error("user mismatched", shopper)
+	}
+	login := shopper.OnStart[0]
+	if login.Request.Method != "POST" || login.Extract["token"].path == nil || len(login.checks) != 1 {
+		t.Error("login step mismatched", login)
+	}
+	checkout := shopper.Steps[1]
+	if checkout.Weight != 1 || len(checkout.Steps) != 2 || len(checkout.Steps[1].checks) != 2 {
+		t.Error("checkout step mismatched", checkout)
+	}
+	if name := checkout.Steps[0].Name; name != "/cart" {
+		t.Error("name of a step should be its URL template by default, got", name)
+	}
+
+	tasks := s.Tasks()
+	if len(tasks) != 2 || tasks[0].Name != "shopper" || tasks[0].Weight != 3 || tasks[1].Weight != 1 || tasks[1].NewUser == nil {
+		t.Error("each kind of users should be a task", tasks)
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	s, err := Parse([]byte(`{
+		"users": [{"steps": [{"request": {"url": "http://localhost/{{randInt 1 9}}"}, "assert": {"status": 200}}]}]
+	}`), ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Users[0].Name != "user1" || s.Users[0].Steps[0].Assert.Status != "200" || s.Timeout != 10*time.Second {
+		t.Error("scenario mismatched", s.Users[0])
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for content, reason := range map[string]string{
+		`users: []`: "no users",
+		`users: [{steps: [{request: {url: /}}]}]`:                                               "host is required",
+		`users: [{steps: []}]`:                                                                  "no steps",
+		`users: [{stepz: []}]`:                                                                  "field stepz not found",
+		`users: [{wait: 3s-1s, steps: [{request: {url: http://x/}}]}]`:                          "invalid wait",
+		`users: [{data: [nope], steps: [{request: {url: http://x/}}]}]`:                         "unknown data",
+		`users: [{steps: [{name: a}]}]`:                                                         "either a request or steps",
+		`users: [{steps: [{request: {url: "http://x/{{.a"}}]}]`:                                 "unclosed action",
+		`users: [{steps: [{request: {url: http://x/}, assert: {status: ok}}]}]`:                 "invalid status",
+		`users: [{steps: [{request: {url: http://x/}, extract: {a: {}}}]}]`:                     "one of jsonpath",
+		`users: [{steps: [{request: {url: http://x/}, extract: {a: {jsonpath: a}}}]}]`:          "should start with $",
+		`users: [{steps: [{steps: [{request: {url: http://x/}}], extract: {a: {header: x}}}]}]`: "need a request",
+		`data: {a: {file: missing.csv}}`:                                                        "missing.csv",
+		`data: {a: {file: x.csv, strategy: no}}`:                                                "unknown strategy",
+	} {
+		_, err := Parse([]byte(content), "testdata")
+		if err == nil || !strings.Contains(err.Error(), reason) {
+			t.Errorf("%s should be invalid by %q, got %v", content, reason, err)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package scenario
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/myzhan/boomer"
+	boomerhttp "github.com/myzhan/boomer/http"
+)
+
+// virtualUser runs the steps of a kind of users, with its own cookies and variables.
+type virtualUser struct {
+	scenario *Scenario
+	spec     *User
+	client   *boomerhttp.Client
+	vars     map[string]interface{}
+	// records are drawn on start, and released on stop
+	records map[string]*boomer.Record
+	ready   bool
+}
+
+func newVirtualUser(s *Scenario, spec *User) *virtualUser {
+	jar, _ := cookiejar.New(nil)
+	u := &virtualUser{
+		scenario: s,
+		spec:     spec,
+		client: boomerhttp.NewClient(&http.Client{
+			Transport: s.transport,
+			Jar:       jar,
+			Timeout:   s.Timeout,
+		}),
+		vars:    make(map[string]interface{}),
+		records: make(map[string]*boomer.Record),
+	}
+	for name, value := range s.Variables {
+		u.vars[name] = value
+	}
+	return u
+}
+
+func (u *virtualUser) OnStart() {
+	for _, name := range u.spec.Data {
+		record, err := u.scenario.feeders[name].Next()
+		if err != nil {
+			log.Printf("User %s can't start without data %s, %v\n", u.spec.Name, name, err)
+			return
+		}
+		u.records[name] = record
+		u.vars[name] = record.Fields()
+	}
+	for _, step := range u.spec.OnStart {
+		if err := u.runStep(step); err != nil {
+			return
+		}
+	}
+	u.ready = true
+}
+
+func (u *virtualUser) Run() {
+	if !u.ready {
+		// users which failed to start are idle
+		boomer.Sleep(time.Second)
+		return
+	}
+	u.runStep(u.pickStep())
+	u.wait()
+}
+
+func (u *virtualUser) OnStop() {
+	if u.ready {
+		for _, step := range u.spec.OnStop {
+			if err := u.runStep(step); err != nil {
+				break
+			}
+		}
+	}
+	for name, record := range u.records {
+		u.scenario.feeders[name].Release(record)
+	}
+}
+
+func (u *virtualUser) pickStep() *Step {
+	n := rand.Intn(u.spec.totalWeight)
+	for _, step := range u.spec.Steps {
+		if n < step.Weight {
+			return step
+		}
+		n -= step.Weight
+	}
+	return u.spec.Steps[len(u.spec.Steps)-1]
+}
+
+func (u *virtualUser) wait() {
+	d := u.spec.waitMin
+	if u.spec.waitMax > u.spec.waitMin {
+		d += time.Duration(rand.Int63n(int64(u.spec.waitMax - u.spec.waitMin)))
+	}
+	if d > 0 {
+		// returns early if the user is stopped, so on_stop runs before the shutdown timeout
+		boomer.Sleep(d)
+	}
+}
+
+// runStep runs a step, steps in order stop at the first failure.
+func (u *virtualUser) runStep(step *Step) error {
+	for _, name := range step.Data {
+		record, err := u.scenario.feeders[name].Next()
+		if err != nil {
+			boomer.RecordFailure("data", name, 0, err.Error(), nil)
+			return err
+		}
+		// records of a step are held until the step finishes, unlike records of the user
+		defer u.scenario.feeders[name].Release(record)
+		u.vars[name] = record.Fields()
+	}
+	for _, sub := range step.Steps {
+		if err := u.runStep(sub); err != nil {
+			return err
+		}
+	}
+	if step.Request == nil {
+		return nil
+	}
+
+	request, err := u.newRequest(step)
+	if err != nil {
+		boomer.RecordFailure(step.Request.Method, step.Name, 0, err.Error(), nil)
+		return err
+	}
+	resp, err := u.client.Do(request, boomerhttp.Name(step.Name), boomerhttp.Checks(step.checks...), boomerhttp.CatchResponse())
+	if err != nil {
+		return err
+	}
+	if err := u.verify(step, resp); err != nil {
+		resp.Failure(err.Error())
+		return err
+	}
+	resp.Success()
+	return nil
+}
+
+func (u *virtualUser) render(t *template.Template) (string, error) {
+	buf := &bytes.Buffer{}
+	if err := t.Execute(buf, u.vars); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (u *virtualUser) newRequest(step *Step) (*http.Request, error) {
+	url, err := u.render(step.url)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(url, "/") {
+		url = u.scenario.Host + url
+	}
+	var body io.Reader
+	if step.body != nil {
+		content, err := u.render(step.body)
+		if err != nil {
+			return nil, err
+		}
+		body = strings.NewReader(content)
+	}
+	request, err := http.NewRequest(step.Request.Method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range u.scenario.Headers {
+		request.Header.Set(name, value)
+	}
+	for name, t := range step.headers {
+		value, err := u.render(t)
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set(name, value)
+	}
+	return request, nil
+}
+
+// verify checks the response by the assertions, and saves the extracted values if they are all found.
+func (u *virtualUser) verify(step *Step, resp *boomerhttp.Response) error {
+	if err := resp.CheckError(); err != nil {
+		return err
+	}
+
+	var document interface{}
+	var documentErr error
+	parsed := false
+	parse := func() (interface{}, error) {
+		if !parsed {
+			decoder := json.NewDecoder(bytes.NewReader(resp.Content))
+			decoder.UseNumber()
+			if documentErr = decoder.Decode(&document); documentErr != nil {
+				documentErr = fmt.Errorf("response is not JSON, %v", documentErr)
+			}
+			parsed = true
+		}
+		return document, documentErr
+	}
+
+	if a := step.Assert; a != nil {
+		for path, t := range a.expected {
+			doc, err := parse()
+			if err != nil {
+				return err
+			}
+			value, ok := a.paths[path].lookup(doc)
+			if !ok {
+				return fmt.Errorf("%s is not found", path)
+			}
+			expected, err := u.render(t)
+			if err != nil {
+				return err
+			}
+			if actual := stringify(value); expected != "" && actual != expected {
+				return fmt.Errorf("%s should be %s, not %s", path, expected, actual)
+			}
+		}
+	}
+
+	extracted := make(map[string]string, len(step.Extract))
+	for name, e := range step.Extract {
+		switch {
+		case e.path != nil:
+			doc, err := parse()
+			if err != nil {
+				return err
+			}
+			value, ok := e.path.lookup(doc)
+			if !ok {
+				return fmt.Errorf("%s of %s is not found", e.JSONPath, name)
+			}
+			extracted[name] = stringify(value)
+		case e.regex != nil:
+			match := e.regex.FindSubmatch(resp.Content)
+			if match == nil {
+				return fmt.Errorf("%s of %s is not found", e.Regex, name)
+			}
+			if len(match) > 1 {
+				extracted[name] = string(match[1])
+			} else {
+				extracted[name] = string(match[0])
+			}
+		default:
+			value := resp.Header.Get(e.Header)
+			if value == "" {
+				return fmt.Errorf("header %s of %s is not found", e.Header, name)
+			}
+			extracted[name] = value
+		}
+	}
+	for name, value := range extracted {
+		u.vars[name] = value
+	}
+	return nil
+}
+
+// stringify returns strings as they are, and other values in JSON.
+func stringify(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	b, _ := json.Marshal(value)
+	return string(b)
+}
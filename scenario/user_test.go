@@ -0,0 +1,245 @@
+package scenario
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/myzhan/boomer"
+	"github.com/myzhan/boomer/boomertest"
+)
+
+// newShop serves the API of testdata/shop.yaml, and returns the requests it received.
+func newShop(t *testing.T) (*httptest.Server, func() []string) {
+	var lock sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		lock.Lock()
+		requests = append(requests, fmt.Sprintf("%s %s %s %s", r.Method, r.URL.RequestURI(), r.Header.Get("Authorization"), body))
+		lock.Unlock()
+
+		switch {
+		case r.URL.Path == "/login":
+			fmt.Fprint(w, `{"token": "t-`+strings.Split(string(body), `"`)[3]+`"}`)
+		case r.Header.Get("Authorization") == "" && r.URL.Path != "/logout":
+			w.WriteHeader(http.StatusUnauthorized)
+		case strings.HasPrefix(r.URL.Path, "/products/"):
+			fmt.Fprint(w, `{"name": "book"}`)
+		case r.URL.Path == "/cart":
+			fmt.Fprint(w, `<div id="cart-42"></div>`)
+		case r.URL.Path == "/cart/42/checkout":
+			fmt.Fprint(w, "ordered")
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]string(nil), requests...)
+	}
+}
+
+func TestVirtualUser(t *testing.T) {
+	server, requests := newShop(t)
+	s, err := Load("testdata/shop.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Host = server.URL
+
+	u := newVirtualUser(s, s.Users[0])
+	u.OnStart()
+	if !u.ready || u.vars["token"] != "t-alice" {
+		t.Fatal("user should log in with the first account, got", requests())
+	}
+	// the checkout step runs its steps in order
+	if err := u.runStep(s.Users[0].Steps[1]); err != nil {
+		t.Error(err)
+	}
+	if err := u.runStep(s.Users[0].Steps[0]); err != nil {
+		t.Error(err)
+	}
+	for i := 0; i < 5; i++ {
+		u.Run()
+	}
+	u.OnStop()
+
+	got := requests()
+	want := []string{
+		`POST /login  {"username": "alice", "password": "secret1"}`,
+		`POST /cart Bearer t-alice `,
+		`POST /cart/42/checkout Bearer t-alice `,
+		`GET /products/7?currency=EUR Bearer t-alice `,
+	}
+	if len(got) < 10 {
+		t.Fatal("requests should be sent on start, by steps and on stop, got", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("request %d should be %s, got %s", i, want[i], got[i])
+		}
+	}
+	if last := got[len(got)-1]; last != "POST /logout  " {
+		t.Error("user should log out on stop, got", last)
+	}
+
+	// the account is released on stop
+	another := newVirtualUser(s, s.Users[0])
+	another.OnStart()
+	if another.vars["token"] != "t-bob" {
+		t.Error("another user should take another account, got", another.vars["token"])
+	}
+	another.OnStop()
+	third := newVirtualUser(s, s.Users[0])
+	third.OnStart()
+	if third.vars["token"] != "t-alice" {
+		t.Error("released account should be taken, got", third.vars["token"])
+	}
+}
+
+func TestVirtualUserFailures(t *testing.T) {
+	recorder := boomertest.Record(t)
+	server, requests := newShop(t)
+	s, err := Parse([]byte(`
+host: `+server.URL+`
+headers: {Authorization: Bearer x}
+data:
+  accounts: {file: accounts.csv, strategy: sequential}
+users:
+  - data: [accounts]
+    steps:
+      - name: unauthorized
+        request: {url: /cart, headers: {Authorization: ""}}
+      - request: {url: /products/1}
+        extract: {missing: {jsonpath: $.price}, name: {jsonpath: $.name}}
+      - request: {url: "/products/{{.missing}}"}
+      - data: [accounts]
+        request: {url: /products/2}
+      - request: {url: /products/3}
+        assert: {jsonpath: {$.name: '{{.accounts.username}}'}}
+`), "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := newVirtualUser(s, s.Users[0])
+	u.OnStart()
+
+	steps := s.Users[0].Steps
+	if err := u.runStep(steps[0]); err == nil || err.Error() != "unexpected status code 401" {
+		t.Error("status code should be checked, got", err)
+	}
+	if err := u.runStep(steps[1]); err == nil || u.vars["name"] != nil {
+		t.Error("values should not be extracted if any of them is missing, got", err, u.vars)
+	}
+	if err := u.runStep(steps[2]); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Error("missing variables should fail, got", err)
+	}
+	if err := u.runStep(steps[3]); err != nil {
+		t.Error("the second account should be drawn, got", err)
+	}
+	if err := u.runStep(steps[3]); err == nil {
+		t.Error("sequential data should be exhausted")
+	}
+	if err := u.runStep(steps[4]); err == nil || err.Error() != "$.name should be bob, not book" {
+		t.Error("JSONPath should be checked, got", err)
+	}
+	var failures []string
+	for _, r := range recorder.Failures() {
+		failures = append(failures, r.Type+" "+r.Name)
+	}
+	want := "[GET unauthorized GET /products/1 GET /products/{{.missing}} data accounts GET /products/3]"
+	if fmt.Sprint(failures) != want {
+		t.Error("failures should be reported, including those before requests, got", failures)
+	}
+	if n := len(requests()); n != 4 {
+		t.Error("4 requests should be sent, got", n)
+	}
+
+	u = newVirtualUser(s, s.Users[0])
+	if u.OnStart(); u.ready {
+		t.Error("user should not be ready without data")
+	}
+}
+
+func TestVirtualUserStepData(t *testing.T) {
+	boomertest.Record(t)
+	server, requests := newShop(t)
+	s, err := Parse([]byte(`
+host: `+server.URL+`
+data:
+  accounts: {file: accounts.csv, strategy: unique-per-user}
+users:
+  - steps:
+      - data: [accounts]
+        request: {url: "/products/{{.accounts.username}}", headers: {Authorization: Bearer x}}
+`), "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := newVirtualUser(s, s.Users[0])
+	u.OnStart()
+	for i := 0; i < 5; i++ {
+		if err := u.runStep(s.Users[0].Steps[0]); err != nil {
+			t.Fatal("records of a step should be released after the step, got", err)
+		}
+	}
+	if n := len(requests()); n != 5 {
+		t.Error("5 requests should be sent, got", n)
+	}
+}
+
+func TestVirtualUserStopDuringWait(t *testing.T) {
+	boomertest.Record(t)
+	server, requests := newShop(t)
+	s, err := Parse([]byte(`
+host: `+server.URL+`
+headers: {Authorization: Bearer x}
+users:
+  - wait: 30s
+    steps:
+      - request: {url: /products/1}
+    on_stop:
+      - request: {method: POST, url: /logout}
+`), "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	master, err := boomer.NewTestMaster()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer master.Close()
+	if err := master.StartWorker(s.Tasks()...); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := master.Expect("client_ready", time.Second); err != nil {
+		t.Fatal(err)
+	}
+	master.Hatch(1, 10)
+	if _, err := master.Expect("hatch_complete", time.Second); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for len(requests()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the user should send a request before waiting")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	master.Stop()
+	deadline = time.Now().Add(time.Second)
+	for !strings.HasPrefix(requests()[len(requests())-1], "POST /logout") {
+		if time.Now().After(deadline) {
+			t.Fatal("on_stop should run promptly when the user is stopped while waiting, got", requests())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,159 @@
+package boomer
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"sync"
+)
+
+// Control traffic between the master and workers can be encrypted and authenticated.
+// --rpc=socket uses TLS, and --rpc=zeromq uses the PLAIN or CURVE mechanism of ZeroMQ.
+
+var useTLS *bool
+var tlsCAFile *string
+var tlsCertFile *string
+var tlsKeyFile *string
+var tlsServerName *string
+var tlsInsecureSkipVerify *bool
+
+var zmqSecurity *string
+var zmqPlainUsername *string
+var zmqPlainPassword *string
+var zmqCurveServerKey *string
+var zmqCurveCertFile *string
+
+var (
+	clientTLSConfig     *tls.Config
+	clientTLSConfigLock sync.Mutex
+)
+
+// SetTLSConfig makes workers connect to the master with TLS when --rpc=socket is used,
+// instead of configuring it with the --tls-* flags.
+func SetTLSConfig(config *tls.Config) {
+	clientTLSConfigLock.Lock()
+	clientTLSConfig = config
+	clientTLSConfigLock.Unlock()
+}
+
+// getClientTLSConfig returns nil if TLS is not enabled.
+func getClientTLSConfig() (*tls.Config, error) {
+	clientTLSConfigLock.Lock()
+	config := clientTLSConfig
+	clientTLSConfigLock.Unlock()
+	if config != nil {
+		return config, nil
+	}
+	if !*useTLS {
+		return nil, nil
+	}
+
+	config, err := NewClientTLSConfig(*tlsCAFile, *tlsCertFile, *tlsKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	config.ServerName = *tlsServerName
+	config.InsecureSkipVerify = *tlsInsecureSkipVerify
+	return config, nil
+}
+
+// NewClientTLSConfig returns a TLS config that verifies the master with the CA in caFile,
+// or the system roots if caFile is empty. certFile and keyFile are the optional client certificate.
+func NewClientTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if caFile != "" {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// NewServerTLSConfig returns a TLS config of the master. If clientCAFile is not empty,
+// workers must present a certificate signed by it.
+func NewServerTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the server certificate: %v", err)
+	}
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+	if clientCAFile != "" {
+		pool, err := loadCertPool(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+	return pool, nil
+}
+
+const (
+	zmqSecurityNull  = "null"
+	zmqSecurityPlain = "plain"
+	zmqSecurityCurve = "curve"
+)
+
+// validateZmqSecurity checks that the --zmq-* flags are complete for the chosen mechanism.
+func validateZmqSecurity() error {
+	switch *zmqSecurity {
+	case zmqSecurityNull:
+		return nil
+	case zmqSecurityPlain:
+		if *zmqPlainUsername == "" {
+			return errors.New("--zmq-plain-username is required by PLAIN security")
+		}
+		return nil
+	case zmqSecurityCurve:
+		// z85 encoded keys are 40 characters
+		if len(*zmqCurveServerKey) != 40 {
+			return errors.New("--zmq-curve-server-key should be the 40 characters z85 public key of the master")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown zeromq security mechanism %q, should be null, plain or curve", *zmqSecurity)
+}
+
+func init() {
+	useTLS = flag.Bool("tls", false, "Connect to the master with TLS, only for --rpc=socket.")
+	tlsCAFile = flag.String("tls-ca-file", "", "CA certificates to verify the master with. Defaults to the system roots.")
+	tlsCertFile = flag.String("tls-cert-file", "", "Client certificate presented to the master, optional.")
+	tlsKeyFile = flag.String("tls-key-file", "", "Private key of --tls-cert-file.")
+	tlsServerName = flag.String("tls-server-name", "", "Name in the certificate of the master. Defaults to --master-host.")
+	tlsInsecureSkipVerify = flag.Bool("tls-insecure-skip-verify", false, "Don't verify the certificate of the master. Only for testing.")
+
+	zmqSecurity = flag.String("zmq-security", zmqSecurityNull, "Security mechanism of --rpc=zeromq, null, plain or curve. plain and curve need boomer built with goczmq.")
+	zmqPlainUsername = flag.String("zmq-plain-username", "", "Username of PLAIN security.")
+	zmqPlainPassword = flag.String("zmq-plain-password", "", "Password of PLAIN security.")
+	zmqCurveServerKey = flag.String("zmq-curve-server-key", "", "Z85 encoded public key of the master for CURVE security.")
+	zmqCurveCertFile = flag.String("zmq-curve-cert-file", "", "Certificate file of the worker for CURVE security, as saved by zcert. Defaults to a new key pair.")
+}
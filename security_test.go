@@ -0,0 +1,242 @@
+package boomer
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+// newTestCert writes a certificate for 127.0.0.1 to dir, it's self-signed if parent is nil.
+func newTestCert(t *testing.T, dir, name string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signerCert, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signerCert, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &testCert{
+		cert:     cert,
+		key:      key,
+		certFile: filepath.Join(dir, name+".crt"),
+		keyFile:  filepath.Join(dir, name+".key"),
+	}
+	ioutil.WriteFile(c.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(c.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	return c
+}
+
+func newTLSTestMaster(t *testing.T, dir string) (*Master, *testCert) {
+	ca := newTestCert(t, dir, "ca", nil)
+	server := newTestCert(t, dir, "master", ca)
+	config, err := NewServerTLSConfig(server.certFile, server.keyFile, ca.certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewMaster("127.0.0.1", 0)
+	m.SetTLSConfig(config)
+	if err := m.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	go m.Serve()
+	return m, ca
+}
+
+func TestSocketTLS(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "boomer")
+	defer os.RemoveAll(dir)
+
+	m, ca := newTLSTestMaster(t, dir)
+	defer m.Close()
+
+	worker := newTestCert(t, dir, "worker", ca)
+	config, err := NewClientTLSConfig(ca.certFile, worker.certFile, worker.keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := dialMaster(m.Addr().String(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	writeFrame(conn, newMessage("client_ready", nil, "worker1"))
+	waitForWorkers(t, m, 1)
+
+	if err := m.Swarm(10, 1); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	msg, err := readFrame(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != "hatch" {
+		t.Error("worker should receive hatch message, not", msg.Type)
+	}
+}
+
+func TestSocketTLSRejected(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "boomer")
+	defer os.RemoveAll(dir)
+
+	m, ca := newTLSTestMaster(t, dir)
+	defer m.Close()
+
+	// the master is not trusted
+	config, err := NewClientTLSConfig("", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dialMaster(m.Addr().String(), config); err == nil {
+		t.Error("certificate of the master should be verified")
+	}
+
+	// the worker has no certificate, with TLS 1.3 it's rejected after the handshake
+	config, err = NewClientTLSConfig(ca.certFile, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := dialMaster(m.Addr().String(), config)
+	if err == nil {
+		defer conn.Close()
+		writeFrame(conn, newMessage("client_ready", nil, "worker1"))
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, err = readFrame(conn)
+		if netErr, ok := err.(net.Error); err == nil || ok && netErr.Timeout() {
+			t.Error("worker without certificate should be rejected, got", err)
+		}
+	}
+	if len(m.Workers()) != 0 {
+		t.Error("worker without certificate should not be added")
+	}
+
+	// plain connections are not accepted either
+	conn, err = dialMaster(m.Addr().String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	writeFrame(conn, newMessage("client_ready", nil, "worker2"))
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	// the master may answer with a TLS alert before closing the connection
+	_, err = ioutil.ReadAll(conn)
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		t.Error("plain connection should be closed by the master")
+	}
+	if len(m.Workers()) != 0 {
+		t.Error("plain connection should not be added")
+	}
+}
+
+func TestClientTLSConfigFromFlags(t *testing.T) {
+	defer SetTLSConfig(nil)
+
+	config, err := getClientTLSConfig()
+	if err != nil || config != nil {
+		t.Error("TLS should be disabled by default, got", config, err)
+	}
+
+	*useTLS = true
+	*tlsCAFile = "/nonexistent/ca.crt"
+	defer func() {
+		*useTLS = false
+		*tlsCAFile = ""
+	}()
+	if _, err := getClientTLSConfig(); err == nil {
+		t.Error("missing CA file should be an error")
+	}
+
+	*tlsCAFile = ""
+	config, err = getClientTLSConfig()
+	if err != nil || config == nil {
+		t.Fatal("TLS should be enabled by --tls, got", config, err)
+	}
+
+	custom := &tls.Config{ServerName: "custom"}
+	SetTLSConfig(custom)
+	if config, _ := getClientTLSConfig(); config != custom {
+		t.Error("config set by SetTLSConfig should be used")
+	}
+}
+
+func TestValidateZmqSecurity(t *testing.T) {
+	defer func() {
+		*zmqSecurity = zmqSecurityNull
+		*zmqPlainUsername = ""
+		*zmqCurveServerKey = ""
+	}()
+
+	if err := validateZmqSecurity(); err != nil {
+		t.Error(err)
+	}
+
+	*zmqSecurity = "tls"
+	if err := validateZmqSecurity(); err == nil {
+		t.Error("unknown mechanism should be an error")
+	}
+
+	*zmqSecurity = zmqSecurityPlain
+	if err := validateZmqSecurity(); err == nil {
+		t.Error("PLAIN without username should be an error")
+	}
+	*zmqPlainUsername = "boomer"
+	if err := validateZmqSecurity(); err != nil {
+		t.Error(err)
+	}
+
+	*zmqSecurity = zmqSecurityCurve
+	*zmqCurveServerKey = "too short"
+	if err := validateZmqSecurity(); err == nil {
+		t.Error("invalid server key should be an error")
+	}
+	*zmqCurveServerKey = "rq:rM>}U?@Lns47E1%kR.o@n%FcmmsL/@{H8]yf7"
+	if err := validateZmqSecurity(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,156 @@
+package boomer
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// With --standalone, boomer runs without a master, it hatches --clients users at --hatch-rate,
+// runs for --run-time or until stopped, and prints stats to the console like the web UI of locust.
+
+var standalone *bool
+var standaloneClients *int
+var standaloneHatchRate *int
+var runTime *time.Duration
+
+// standaloneOutput is where stats are printed, it's replaced in tests.
+var standaloneOutput io.Writer = os.Stdout
+
+// standaloneClient takes the place of the master.
+type standaloneClient struct {
+	closeChannel chan bool
+	lastReport   time.Time
+}
+
+func newStandaloneClient() *standaloneClient {
+	c := &standaloneClient{
+		closeChannel: make(chan bool),
+		lastReport:   time.Now(),
+	}
+	go c.recv()
+	go c.send()
+	return c
+}
+
+// recv does nothing, there are no messages from the master.
+func (c *standaloneClient) recv() {}
+
+func (c *standaloneClient) send() {
+	for {
+		select {
+		case msg := <-toMaster:
+			c.handleMessage(msg)
+		case <-c.closeChannel:
+			return
+		}
+	}
+}
+
+func (c *standaloneClient) close() {
+	close(c.closeChannel)
+}
+
+func (c *standaloneClient) handleMessage(msg *message) {
+	switch msg.Type {
+	case "hatch_complete":
+		if hatchComplete, err := decodeHatchComplete(msg.Data); err == nil {
+			log.Printf("All %d clients hatched\n", hatchComplete.count)
+		}
+	case "stats":
+		s, err := decodeStats(msg.Data)
+		if err != nil {
+			log.Println("Invalid stats,", err)
+			return
+		}
+		now := time.Now()
+		printStats(standaloneOutput, s, now.Sub(c.lastReport))
+		c.lastReport = now
+	case "quit":
+		notifyDisconnected()
+	}
+}
+
+// printStats prints the stats of an interval as a table.
+func printStats(w io.Writer, s *statsMessage, interval time.Duration) {
+	sort.Slice(s.entries, func(i, j int) bool {
+		if s.entries[i].name != s.entries[j].name {
+			return s.entries[i].name < s.entries[j].name
+		}
+		return s.entries[i].method < s.entries[j].method
+	})
+
+	const format = "%-8s %-40s %8d %8d %8d %8d %8d %8d %8.1f\n"
+	fmt.Fprintf(w, "%-8s %-40s %8s %8s %8s %8s %8s %8s %8s\n",
+		"Type", "Name", "# reqs", "# fails", "Avg", "Min", "Max", "Median", "req/s")
+	printEntry := func(method, name string, e *statsEntry) {
+		var avg, rps float64
+		if e.numRequests > 0 {
+			avg = float64(e.totalResponseTime) / float64(e.numRequests)
+		}
+		if interval > 0 {
+			rps = float64(e.numRequests) / interval.Seconds()
+		}
+		fmt.Fprintf(w, format, method, name, e.numRequests, e.numFailures, int64(avg),
+			e.minResponseTime, e.maxResponseTime, e.getResponseTimePercentile(0.5), rps)
+	}
+	for _, e := range s.entries {
+		printEntry(e.method, e.name, e)
+	}
+	printEntry("", "Aggregated", s.total)
+
+	if s.customMetrics != nil {
+		m := newCustomMetrics()
+		if err := m.mergeReport(s.customMetrics); err != nil {
+			log.Println("Invalid custom metrics,", err)
+		} else {
+			printMetrics(w, m)
+		}
+	}
+	fmt.Fprintf(w, "Users: %d\n\n", s.userCount)
+}
+
+// printMetrics prints custom metrics of an interval, counters and trends are reset by every report.
+func printMetrics(w io.Writer, m *customMetrics) {
+	if m.isEmpty() {
+		return
+	}
+
+	const format = "%-8s %-40s %8s %8s %8s %8s %8s %8s\n"
+	fmt.Fprintf(w, "\n"+format, "Type", "Metric", "Value", "Avg", "Min", "Median", "P95", "Max")
+	rows := make([]string, 0)
+	for name, value := range m.counters {
+		rows = append(rows, fmt.Sprintf(format, "counter", name, formatMetric(float64(value)), "", "", "", "", ""))
+	}
+	for name, value := range m.gauges {
+		rows = append(rows, fmt.Sprintf(format, "gauge", name, formatMetric(value), "", "", "", "", ""))
+	}
+	for name, t := range m.trends {
+		rows = append(rows, fmt.Sprintf(format, "trend", name, formatMetric(float64(t.count)), formatMetric(t.avg()),
+			formatMetric(t.min), formatMetric(t.percentile(0.5)), formatMetric(t.percentile(0.95)), formatMetric(t.max)))
+	}
+	// sorted by type, then name
+	sort.Strings(rows)
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.TrimRight(row, " \n"))
+	}
+}
+
+// formatMetric keeps at most 2 decimals.
+func formatMetric(value float64) string {
+	return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64)
+}
+
+func init() {
+	standalone = flag.Bool("standalone", false, "Run without a master, stats are printed to the console.")
+	standaloneClients = flag.Int("clients", 1, "Number of users to hatch with --standalone.")
+	standaloneHatchRate = flag.Int("hatch-rate", 1, "Users hatched per second with --standalone.")
+	runTime = flag.Duration("run-time", 0, "Stop after the given time with --standalone, like 10m, runs until stopped by default.")
+}
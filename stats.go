@@ -1,23 +1,26 @@
 package boomer
 
 import (
+	"fmt"
+	"sort"
 	"time"
 )
 
 type requestStats struct {
-	entries   map[string]*statsEntry
+	entries   map[entryKey]*statsEntry
 	errors    map[string]*statsError
 	total     *statsEntry
 	startTime int64
 }
 
 func newRequestStats() *requestStats {
-	entries := make(map[string]*statsEntry)
+	entries := make(map[entryKey]*statsEntry)
 	errors := make(map[string]*statsError)
 
 	requestStats := &requestStats{
-		entries: entries,
-		errors:  errors,
+		entries:   entries,
+		errors:    errors,
+		startTime: time.Now().Unix(),
 	}
 
 	requestStats.total = &statsEntry{
@@ -29,18 +32,26 @@ func newRequestStats() *requestStats {
 	return requestStats
 }
 
-func (s *requestStats) logRequest(method, name string, responseTime int64, contentLength int64) {
+func (s *requestStats) logRequest(method, name string, responseTime int64, contentLength int64, tags Tags) {
 	s.total.log(responseTime, contentLength)
-	s.get(name, method).log(responseTime, contentLength)
+	s.get(name, method, tags).log(responseTime, contentLength)
 }
 
-func (s *requestStats) logError(method, name, err string) {
+func (s *requestStats) logError(method, name, err string, tags Tags) {
 	s.total.logError(err)
-	s.get(name, method).logError(err)
+	s.get(name, method, tags).logError(err)
 
-	// store error in errors map
+	// store error in errors map, errors are grouped by the name sent to the master
+	name = foldTags(name, tags)
+	err = normalizeError(err)
 	key := MD5(method, name, err)
 	entry, ok := s.errors[key]
+	if !ok && *maxErrorKeys > 0 && len(s.errors) >= *maxErrorKeys {
+		// a single bucket for all the requests, or new names would still add keys
+		method, name, err = otherErrors, otherErrors, otherErrors
+		key = MD5(method, name, err)
+		entry, ok = s.errors[key]
+	}
 	if !ok {
 		entry = &statsError{
 			name:   name,
@@ -52,17 +63,19 @@ func (s *requestStats) logError(method, name, err string) {
 	entry.occured()
 }
 
-func (s *requestStats) get(name string, method string) (entry *statsEntry) {
-	entry, ok := s.entries[name+method]
+func (s *requestStats) get(name string, method string, tags Tags) (entry *statsEntry) {
+	key := newEntryKey(method, name, tags)
+	entry, ok := s.entries[key]
 	if !ok {
 		newEntry := &statsEntry{
 			name:          name,
 			method:        method,
+			tags:          tags.copy(),
 			numReqsPerSec: make(map[int64]int64),
 			responseTimes: make(map[int64]int64),
 		}
 		newEntry.reset()
-		s.entries[name+method] = newEntry
+		s.entries[key] = newEntry
 		return newEntry
 	}
 	return entry
@@ -75,17 +88,50 @@ func (s *requestStats) clearAll() {
 	}
 	s.total.reset()
 
-	s.entries = make(map[string]*statsEntry)
+	s.entries = make(map[entryKey]*statsEntry)
 	s.errors = make(map[string]*statsError)
 	s.startTime = time.Now().Unix()
 }
 
+// snapshot returns a deep copy of s, so that it can be read outside the stats goroutine.
+func (s *requestStats) snapshot() *requestStats {
+	copied := &requestStats{
+		entries:   make(map[entryKey]*statsEntry, len(s.entries)),
+		errors:    make(map[string]*statsError, len(s.errors)),
+		total:     s.total.snapshot(),
+		startTime: s.startTime,
+	}
+	for k, v := range s.entries {
+		copied.entries[k] = v.snapshot()
+	}
+	for k, v := range s.errors {
+		e := *v
+		copied.errors[k] = &e
+	}
+	return copied
+}
+
+// serializeStats strips all the entries, entries with the same name sent to the master are merged.
 func (s *requestStats) serializeStats() []interface{} {
-	entries := make([]interface{}, 0, len(s.entries))
+	folded := make(map[entryKey]*statsEntry)
 	for _, v := range s.entries {
-		if !(v.numRequests == 0 && v.numFailures == 0) {
-			entries = append(entries, v.getStrippedReport())
+		if v.numRequests == 0 && v.numFailures == 0 {
+			continue
+		}
+		key := entryKey{method: v.method, name: foldTags(v.name, v.tags)}
+		if entry, ok := folded[key]; ok {
+			entry.merge(v)
+		} else {
+			entry = v.snapshot()
+			entry.name = key.name
+			folded[key] = entry
 		}
+		v.reset()
+	}
+
+	entries := make([]interface{}, 0, len(folded))
+	for _, v := range folded {
+		entries = append(entries, v.serialize())
 	}
 	return entries
 }
@@ -101,6 +147,7 @@ func (s *requestStats) serializeErrors() map[string]map[string]interface{} {
 type statsEntry struct {
 	name                 string
 	method               string
+	tags                 Tags
 	numRequests          int64
 	numFailures          int64
 	totalResponseTime    int64
@@ -163,21 +210,7 @@ func (s *statsEntry) logResponseTime(responseTime int64) {
 		s.maxResponseTime = responseTime
 	}
 
-	roundedResponseTime := int64(0)
-
-	// to avoid to much data that has to be transferred to the master node when
-	// running in distributed mode, we save the response time rounded in a dict
-	// so that 147 becomes 150, 3432 becomes 3400 and 58760 becomes 59000
-	// see also locust's stats.py
-	if responseTime < 100 {
-		roundedResponseTime = responseTime
-	} else if responseTime < 1000 {
-		roundedResponseTime = int64(round(float64(responseTime), .5, -1))
-	} else if responseTime < 10000 {
-		roundedResponseTime = int64(round(float64(responseTime), .5, -2))
-	} else {
-		roundedResponseTime = int64(round(float64(responseTime), .5, -3))
-	}
+	roundedResponseTime := roundResponseTime(responseTime)
 
 	_, ok := s.responseTimes[roundedResponseTime]
 	if !ok {
@@ -187,6 +220,21 @@ func (s *statsEntry) logResponseTime(responseTime int64) {
 	}
 }
 
+// to avoid to much data that has to be transferred to the master node when
+// running in distributed mode, we save the response time rounded in a dict
+// so that 147 becomes 150, 3432 becomes 3400 and 58760 becomes 59000
+// see also locust's stats.py
+func roundResponseTime(responseTime int64) int64 {
+	if responseTime < 100 {
+		return responseTime
+	} else if responseTime < 1000 {
+		return int64(round(float64(responseTime), .5, -1))
+	} else if responseTime < 10000 {
+		return int64(round(float64(responseTime), .5, -2))
+	}
+	return int64(round(float64(responseTime), .5, -3))
+}
+
 func (s *statsEntry) logError(err string) {
 	s.numFailures++
 }
@@ -208,6 +256,110 @@ func (s *statsEntry) serialize() map[string]interface{} {
 	return result
 }
 
+// newStatsEntryFromMap is the reverse of serialize, it's used to aggregate reports from workers.
+func newStatsEntryFromMap(m map[string]interface{}) (*statsEntry, error) {
+	s := &statsEntry{}
+	var ok bool
+	if s.name, ok = toString(m["name"]); !ok {
+		return nil, fmt.Errorf("invalid name in stats entry: %v", m["name"])
+	}
+	if m["method"] != nil {
+		if s.method, ok = toString(m["method"]); !ok {
+			return nil, fmt.Errorf("invalid method in stats entry: %v", m["method"])
+		}
+	}
+	for key, field := range map[string]*int64{
+		"last_request_timestamp": &s.lastRequestTimestamp,
+		"start_time":             &s.startTime,
+		"num_requests":           &s.numRequests,
+		"num_failures":           &s.numFailures,
+		"total_response_time":    &s.totalResponseTime,
+		"max_response_time":      &s.maxResponseTime,
+		"min_response_time":      &s.minResponseTime,
+		"total_content_length":   &s.totalContentLength,
+	} {
+		if m[key] == nil {
+			continue
+		}
+		if *field, ok = toInt64(m[key]); !ok {
+			return nil, fmt.Errorf("invalid %s in stats entry: %v", key, m[key])
+		}
+	}
+	if s.responseTimes, ok = toInt64Map(m["response_times"]); !ok {
+		return nil, fmt.Errorf("invalid response_times in stats entry: %v", m["response_times"])
+	}
+	if s.numReqsPerSec, ok = toInt64Map(m["num_reqs_per_sec"]); !ok {
+		return nil, fmt.Errorf("invalid num_reqs_per_sec in stats entry: %v", m["num_reqs_per_sec"])
+	}
+	return s, nil
+}
+
+func (s *statsEntry) snapshot() *statsEntry {
+	copied := *s
+	copied.numReqsPerSec = make(map[int64]int64, len(s.numReqsPerSec))
+	for k, v := range s.numReqsPerSec {
+		copied.numReqsPerSec[k] = v
+	}
+	copied.responseTimes = make(map[int64]int64, len(s.responseTimes))
+	for k, v := range s.responseTimes {
+		copied.responseTimes[k] = v
+	}
+	return &copied
+}
+
+// merge adds the requests of other to s.
+func (s *statsEntry) merge(other *statsEntry) {
+	if other.startTime < s.startTime {
+		s.startTime = other.startTime
+	}
+	if other.lastRequestTimestamp > s.lastRequestTimestamp {
+		s.lastRequestTimestamp = other.lastRequestTimestamp
+	}
+	if s.minResponseTime == 0 || (other.minResponseTime != 0 && other.minResponseTime < s.minResponseTime) {
+		s.minResponseTime = other.minResponseTime
+	}
+	if other.maxResponseTime > s.maxResponseTime {
+		s.maxResponseTime = other.maxResponseTime
+	}
+	s.numRequests += other.numRequests
+	s.numFailures += other.numFailures
+	s.totalResponseTime += other.totalResponseTime
+	s.totalContentLength += other.totalContentLength
+	for k, v := range other.numReqsPerSec {
+		s.numReqsPerSec[k] += v
+	}
+	for k, v := range other.responseTimes {
+		s.responseTimes[k] += v
+	}
+}
+
+// getResponseTimePercentile returns the response time that percent of the requests
+// finished within, it's calculated the same way as locust does.
+func (s *statsEntry) getResponseTimePercentile(percent float64) int64 {
+	return calculateResponseTimePercentile(s.responseTimes, s.numRequests, percent)
+}
+
+func calculateResponseTimePercentile(responseTimes map[int64]int64, numRequests int64, percent float64) int64 {
+	if numRequests == 0 {
+		return 0
+	}
+	times := make([]int64, 0, len(responseTimes))
+	for k := range responseTimes {
+		times = append(times, k)
+	}
+	sort.Sort(sort.Reverse(int64Slice(times)))
+
+	numOfRequests := int64(float64(numRequests) * percent)
+	processedCount := int64(0)
+	for _, t := range times {
+		processedCount += responseTimes[t]
+		if numRequests-processedCount <= numOfRequests {
+			return t
+		}
+	}
+	return 0
+}
+
 func (s *statsEntry) getStrippedReport() map[string]interface{} {
 	report := s.serialize()
 	s.reset()
@@ -236,6 +388,25 @@ func (err *statsError) toMap() map[string]interface{} {
 	return m
 }
 
+// newStatsErrorFromMap is the reverse of toMap.
+func newStatsErrorFromMap(m map[string]interface{}) (*statsError, error) {
+	err := &statsError{}
+	var ok bool
+	if err.name, ok = toString(m["name"]); !ok {
+		return nil, fmt.Errorf("invalid name in stats error: %v", m["name"])
+	}
+	if err.method, ok = toString(m["method"]); !ok && m["method"] != nil {
+		return nil, fmt.Errorf("invalid method in stats error: %v", m["method"])
+	}
+	if err.error, ok = toString(m["error"]); !ok {
+		return nil, fmt.Errorf("invalid error in stats error: %v", m["error"])
+	}
+	if err.occurences, ok = toInt64(m["occurences"]); !ok {
+		return nil, fmt.Errorf("invalid occurences in stats error: %v", m["occurences"])
+	}
+	return err, nil
+}
+
 func collectReportData() map[string]interface{} {
 	data := make(map[string]interface{})
 
@@ -245,6 +416,11 @@ func collectReportData() map[string]interface{} {
 
 	stats.errors = make(map[string]*statsError)
 
+	logBackpressure()
+	if !metrics.isEmpty() {
+		data["custom_metrics"] = metrics.getStrippedReport()
+	}
+
 	return data
 }
 
@@ -253,6 +429,7 @@ type requestSuccess struct {
 	name           string
 	responseTime   int64
 	responseLength int64
+	tags           Tags
 }
 
 type requestFailure struct {
@@ -260,31 +437,152 @@ type requestFailure struct {
 	name         string
 	responseTime int64
 	error        string
+	tags         Tags
 }
 
+// summary accumulates the stats of the whole test, it's never stripped by reports.
+var summary = newRequestStats()
 var stats = newRequestStats()
 var requestSuccessChannel = make(chan *requestSuccess, 100)
 var requestFailureChannel = make(chan *requestFailure, 100)
 var clearStatsChannel = make(chan bool)
 var messageToRunner = make(chan map[string]interface{}, 10)
+var summaryRequestChannel = make(chan chan *testSummary)
+
+// flushStatsChannel asks the stats goroutine for a final report, it's returned instead of sent to the runner.
+var flushStatsChannel = make(chan chan map[string]interface{})
+
+// reportStatsChannel asks the stats goroutine to report now, instead of waiting for the ticker.
+var reportStatsChannel = make(chan bool)
+
+// testSummary is a copy of everything accumulated during the whole test.
+type testSummary struct {
+	stats    *requestStats
+	metrics  *customMetrics
+	timeline *timelineSnapshot
+}
+
+// drainRequestChannels logs all the pending requests and metrics, it must be called in the stats goroutine.
+func drainRequestChannels() {
+	for {
+		select {
+		case m := <-requestSuccessChannel:
+			logRequestSuccess(m)
+		case n := <-requestFailureChannel:
+			logRequestFailure(n)
+		case sample := <-metricChannel:
+			logMetric(sample)
+		default:
+			return
+		}
+	}
+}
+
+func logRequestSuccess(m *requestSuccess) {
+	stats.logRequest(m.requestType, m.name, m.responseTime, m.responseLength, m.tags)
+	summary.logRequest(m.requestType, m.name, m.responseTime, m.responseLength, m.tags)
+	if t := requestTimeline; t != nil {
+		t.logRequest(m.requestType, m.name, m.responseTime, m.tags)
+	}
+}
+
+func logRequestFailure(n *requestFailure) {
+	stats.logError(n.requestType, n.name, n.error, n.tags)
+	summary.logError(n.requestType, n.name, n.error, n.tags)
+	if t := requestTimeline; t != nil {
+		t.logError(n.requestType, n.name, n.tags)
+	}
+}
+
+// requestTimeline is the per-second stats of the test, or nil if it's disabled.
+// It's only accessed in the stats goroutine.
+var requestTimeline *timeline
+
+// startTimeline replaces the timeline of the stats goroutine, the previous one is closed.
+func startTimeline(t *timeline) {
+	c := make(chan bool)
+	timelineChannel <- timelineRequest{t, c}
+	<-c
+}
+
+// closeTimeline spills the buckets still in the window and closes the time series file.
+func closeTimeline() {
+	startTimeline(nil)
+}
+
+type timelineRequest struct {
+	timeline *timeline
+	done     chan bool
+}
+
+var timelineChannel = make(chan timelineRequest)
+
+func logMetric(sample *metricSample) {
+	metrics.log(sample)
+	summaryMetrics.log(sample)
+}
+
+// getSummary returns a copy of the stats of the whole test.
+func getSummary() *testSummary {
+	c := make(chan *testSummary)
+	summaryRequestChannel <- c
+	return <-c
+}
+
+// flushStats returns what's not reported yet.
+func flushStats() map[string]interface{} {
+	c := make(chan map[string]interface{})
+	flushStatsChannel <- c
+	return <-c
+}
 
 func init() {
-	stats.entries = make(map[string]*statsEntry)
+	stats.entries = make(map[entryKey]*statsEntry)
 	stats.errors = make(map[string]*statsError)
 	go func() {
 		var ticker = time.NewTicker(slaveReportInterval)
 		for {
 			select {
 			case m := <-requestSuccessChannel:
-				stats.logRequest(m.requestType, m.name, m.responseTime, m.responseLength)
+				logRequestSuccess(m)
 			case n := <-requestFailureChannel:
-				stats.logError(n.requestType, n.name, n.error)
+				logRequestFailure(n)
+			case sample := <-metricChannel:
+				logMetric(sample)
 			case <-clearStatsChannel:
 				stats.clearAll()
+				summary.clearAll()
+				metrics = newCustomMetrics()
+				summaryMetrics = newCustomMetrics()
+				if requestTimeline != nil {
+					requestTimeline = requestTimeline.reset()
+				}
+			case c := <-summaryRequestChannel:
+				drainRequestChannels()
+				result := &testSummary{
+					stats:   summary.snapshot(),
+					metrics: summaryMetrics.snapshot(),
+				}
+				if t := requestTimeline; t != nil {
+					result.timeline = t.snapshot()
+				}
+				c <- result
+			case r := <-timelineChannel:
+				drainRequestChannels()
+				if requestTimeline != nil {
+					requestTimeline.close()
+				}
+				requestTimeline = r.timeline
+				r.done <- true
+			case <-reportStatsChannel:
+				drainRequestChannels()
+				reportStats()
+			case c := <-flushStatsChannel:
+				drainRequestChannels()
+				c <- collectReportData()
 			case <-ticker.C:
-				data := collectReportData()
 				// send data to channel, no network IO in this goroutine
-				messageToRunner <- data
+				reportStats()
 			}
 		}
 	}()
@@ -0,0 +1,116 @@
+package boomer
+
+import (
+	"flag"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Tags are arbitrary attributes of a request, like status code, region or endpoint group.
+// Requests with different tags are kept in different stats entries.
+type Tags map[string]string
+
+// encode returns a canonical and collision-free representation of tags.
+func (t Tags) encode() string {
+	if len(t) == 0 {
+		return ""
+	}
+	values := make(url.Values, len(t))
+	for k, v := range t {
+		values.Set(k, v)
+	}
+	return values.Encode()
+}
+
+// matches returns true if t has all the tags in other.
+func (t Tags) matches(other Tags) bool {
+	for k, v := range other {
+		if value, ok := t[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (t Tags) copy() Tags {
+	if t == nil {
+		return nil
+	}
+	copied := make(Tags, len(t))
+	for k, v := range t {
+		copied[k] = v
+	}
+	return copied
+}
+
+// entryKey identifies a stats entry, unlike name+method, different entries never collide.
+type entryKey struct {
+	method string
+	name   string
+	tags   string
+}
+
+func newEntryKey(method, name string, tags Tags) entryKey {
+	return entryKey{
+		method: method,
+		name:   name,
+		tags:   tags.encode(),
+	}
+}
+
+var nameTagsLock sync.RWMutex
+var nameTags []string
+
+// SetNameTags decides which tags are folded into the name of requests sent to the master,
+// because locust only knows name and method. Requests named "foo" with tags status=200 and
+// region=eu are reported as "foo (region=eu, status=200)" after SetNameTags("status", "region").
+// Requests with other tags are merged into the same entry on the master.
+func SetNameTags(keys ...string) {
+	sorted := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if k = strings.TrimSpace(k); k != "" {
+			sorted = append(sorted, k)
+		}
+	}
+	sort.Strings(sorted)
+
+	nameTagsLock.Lock()
+	nameTags = sorted
+	nameTagsLock.Unlock()
+}
+
+// foldTags returns the name sent to the master.
+func foldTags(name string, tags Tags) string {
+	nameTagsLock.RLock()
+	defer nameTagsLock.RUnlock()
+
+	folded := make([]string, 0, len(nameTags))
+	for _, k := range nameTags {
+		if v, ok := tags[k]; ok {
+			folded = append(folded, k+"="+v)
+		}
+	}
+	if len(folded) == 0 {
+		return name
+	}
+	return name + " (" + strings.Join(folded, ", ") + ")"
+}
+
+type nameTagsFlag struct{}
+
+func (f nameTagsFlag) String() string {
+	nameTagsLock.RLock()
+	defer nameTagsLock.RUnlock()
+	return strings.Join(nameTags, ",")
+}
+
+func (f nameTagsFlag) Set(value string) error {
+	SetNameTags(strings.Split(value, ",")...)
+	return nil
+}
+
+func init() {
+	flag.Var(nameTagsFlag{}, "name-tags", "Tags folded into the name of requests sent to the master, multiple tags are separated by comma, like status,region.")
+}
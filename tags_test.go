@@ -0,0 +1,66 @@
+package boomer
+
+import "testing"
+
+func TestEntryKey(t *testing.T) {
+
+	if newEntryKey("bc", "a", nil) == newEntryKey("c", "ab", nil) {
+		t.Error("entry keys of a+bc and ab+c should not collide")
+	}
+	if newEntryKey("GET", "foo", Tags{"a": "1&b=2"}) == newEntryKey("GET", "foo", Tags{"a": "1", "b": "2"}) {
+		t.Error("entry keys of different tags should not collide")
+	}
+	if newEntryKey("GET", "foo", Tags{"a": "1", "b": "2"}) != newEntryKey("GET", "foo", Tags{"b": "2", "a": "1"}) {
+		t.Error("entry keys of the same tags should be equal")
+	}
+}
+
+func TestFoldTags(t *testing.T) {
+
+	defer SetNameTags()
+
+	tags := Tags{"status": "200", "region": "eu", "group": "api"}
+	if name := foldTags("foo", tags); name != "foo" {
+		t.Error("no tags should be folded by default, got", name)
+	}
+
+	SetNameTags("status", "region")
+	if name := foldTags("foo", tags); name != "foo (region=eu, status=200)" {
+		t.Error("tags are folded incorrectly,", name)
+	}
+}
+
+func TestSerializeStatsWithTags(t *testing.T) {
+
+	defer SetNameTags()
+	SetNameTags("status")
+
+	s := newRequestStats()
+	s.logRequest("GET", "foo", 10, 0, Tags{"status": "200", "region": "eu"})
+	s.logRequest("GET", "foo", 20, 0, Tags{"status": "200", "region": "us"})
+	s.logError("GET", "foo", "error", Tags{"status": "500"})
+
+	if len(s.entries) != 3 {
+		t.Error("requests with different tags should be kept in different entries")
+	}
+
+	entries := s.serializeStats()
+	if len(entries) != 2 {
+		t.Fatal("entries with the same folded name should be merged, got", entries)
+	}
+	for _, e := range entries {
+		entry := e.(map[string]interface{})
+		switch entry["name"] {
+		case "foo (status=200)":
+			if entry["num_requests"] != int64(2) {
+				t.Error("foo (status=200) should have 2 requests")
+			}
+		case "foo (status=500)":
+			if entry["num_failures"] != int64(1) {
+				t.Error("foo (status=500) should have 1 failure")
+			}
+		default:
+			t.Error("unexpected entry", entry["name"])
+		}
+	}
+}
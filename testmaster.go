@@ -0,0 +1,272 @@
+package boomer
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// TestMaster is an in-process master for testing workers with go test, no locust is needed.
+// It speaks the same protocol as the locust master over a loopback tcp socket, and runs
+// a worker in the same process.
+//
+//	master, _ := boomer.NewTestMaster()
+//	defer master.Close()
+//	master.StartWorker(task)
+//	master.Expect("client_ready", time.Second)
+//	master.Hatch(10, 10)
+//	master.Expect("hatch_complete", time.Second)
+//	master.ReportStats()
+//	stats, _ := master.ExpectStats(time.Second)
+//
+// Boomer keeps its state in global variables, so only one worker can run at a time.
+type TestMaster struct {
+	listener  net.Listener
+	connected chan net.Conn
+	conn      net.Conn
+	messages  chan *TestMessage
+	runner    *runner
+}
+
+// TestMessage is a message received from the worker.
+type TestMessage struct {
+	Type   string
+	Data   map[string]interface{}
+	NodeID string
+}
+
+// StatsReport is the payload of a stats message from the worker.
+type StatsReport struct {
+	Entries   []*StatsEntryReport
+	Total     *StatsEntryReport
+	Errors    []*StatsErrorReport
+	UserCount int64
+}
+
+// StatsEntryReport is the stats of requests with the same name and method since the last report.
+type StatsEntryReport struct {
+	Name                 string
+	Method               string
+	NumRequests          int64
+	NumFailures          int64
+	TotalResponseTime    int64
+	MinResponseTime      int64
+	MaxResponseTime      int64
+	TotalContentLength   int64
+	StartTime            int64
+	LastRequestTimestamp int64
+	ResponseTimes        map[int64]int64
+	NumReqsPerSec        map[int64]int64
+}
+
+// StatsErrorReport is a group of failures with the same name, method and error since the last report.
+type StatsErrorReport struct {
+	Name        string
+	Method      string
+	Error       string
+	Occurrences int64
+}
+
+// ErrWorkerDisconnected is returned when the worker has disconnected from the test master.
+var ErrWorkerDisconnected = errors.New("worker disconnected")
+
+// NewTestMaster listens on a random loopback port, call StartWorker to connect a worker to it.
+func NewTestMaster() (*TestMaster, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	m := &TestMaster{
+		listener:  listener,
+		connected: make(chan net.Conn, 1),
+		messages:  make(chan *TestMessage, 100),
+	}
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			close(m.connected)
+			return
+		}
+		m.connected <- conn
+		for {
+			msg, err := readFrame(conn)
+			if err != nil {
+				close(m.messages)
+				return
+			}
+			m.messages <- &TestMessage{
+				Type:   msg.Type,
+				Data:   msg.Data,
+				NodeID: msg.NodeID,
+			}
+		}
+	}()
+	return m, nil
+}
+
+// StartWorker runs a worker with tasks in the same process, and connects it to the test master.
+func (m *TestMaster) StartWorker(tasks ...*Task) error {
+	if m.runner != nil {
+		return errors.New("worker is already started")
+	}
+
+	// drop messages left by previous workers
+	for len(fromMaster) > 0 {
+		<-fromMaster
+	}
+	for len(toMaster) > 0 {
+		<-toMaster
+	}
+
+	addr := m.listener.Addr().(*net.TCPAddr)
+	r := newRunner(tasks, newSocketClient(addr.IP.String(), addr.Port))
+	r.onQuit = r.close
+	select {
+	case conn, ok := <-m.connected:
+		if !ok {
+			return ErrWorkerDisconnected
+		}
+		m.conn = conn
+	case <-time.After(time.Second):
+		return errors.New("timeout waiting for the worker to connect")
+	}
+	m.runner = r
+	r.getReady()
+	return nil
+}
+
+// Send sends a message to the worker.
+func (m *TestMaster) Send(msgType string, data map[string]interface{}) error {
+	if m.conn == nil {
+		return errors.New("worker is not started")
+	}
+	return writeFrame(m.conn, newMessage(msgType, data, ""))
+}
+
+// Hatch tells the worker to spawn numClients users at hatchRate users per second.
+func (m *TestMaster) Hatch(numClients int, hatchRate float64) error {
+	return m.Send("hatch", map[string]interface{}{
+		"num_clients": int64(numClients),
+		"hatch_rate":  hatchRate,
+	})
+}
+
+// Stop tells the worker to stop all the users.
+func (m *TestMaster) Stop() error {
+	return m.Send("stop", nil)
+}
+
+// Quit tells the worker to quit, the worker disconnects from the test master.
+func (m *TestMaster) Quit() error {
+	return m.Send("quit", nil)
+}
+
+// ReportStats asks the worker to send a stats message now, instead of every 3 seconds.
+func (m *TestMaster) ReportStats() {
+	reportStatsChannel <- true
+}
+
+// Next returns the next message from the worker.
+func (m *TestMaster) Next(timeout time.Duration) (*TestMessage, error) {
+	select {
+	case msg, ok := <-m.messages:
+		if !ok {
+			return nil, ErrWorkerDisconnected
+		}
+		return msg, nil
+	case <-time.After(timeout):
+		return nil, errors.New("timeout waiting for messages from the worker")
+	}
+}
+
+// Expect returns the next message of msgType from the worker, messages of other types are skipped.
+// Errors wrap ErrWorkerDisconnected if the worker disconnects, check them with errors.Is.
+func (m *TestMaster) Expect(msgType string, timeout time.Duration) (*TestMessage, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		msg, err := m.Next(deadline.Sub(time.Now()))
+		if err != nil {
+			return nil, fmt.Errorf("expecting %s message: %w", msgType, err)
+		}
+		if msg.Type == msgType {
+			return msg, nil
+		}
+	}
+}
+
+// ExpectStats returns the payload of the next stats message from the worker.
+func (m *TestMaster) ExpectStats(timeout time.Duration) (*StatsReport, error) {
+	msg, err := m.Expect("stats", timeout)
+	if err != nil {
+		return nil, err
+	}
+	return msg.Stats()
+}
+
+// Stats decodes the payload of a stats message.
+func (msg *TestMessage) Stats() (*StatsReport, error) {
+	if msg.Type != "stats" {
+		return nil, fmt.Errorf("%s message has no stats", msg.Type)
+	}
+
+	s, err := decodeStats(msg.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &StatsReport{
+		Total:     newStatsEntryReport(s.total),
+		UserCount: s.userCount,
+	}
+	for _, entry := range s.entries {
+		report.Entries = append(report.Entries, newStatsEntryReport(entry))
+	}
+	for _, statsErr := range s.errors {
+		report.Errors = append(report.Errors, &StatsErrorReport{
+			Name:        statsErr.name,
+			Method:      statsErr.method,
+			Error:       statsErr.error,
+			Occurrences: statsErr.occurences,
+		})
+	}
+	return report, nil
+}
+
+func newStatsEntryReport(entry *statsEntry) *StatsEntryReport {
+	return &StatsEntryReport{
+		Name:                 entry.name,
+		Method:               entry.method,
+		NumRequests:          entry.numRequests,
+		NumFailures:          entry.numFailures,
+		TotalResponseTime:    entry.totalResponseTime,
+		MinResponseTime:      entry.minResponseTime,
+		MaxResponseTime:      entry.maxResponseTime,
+		TotalContentLength:   entry.totalContentLength,
+		StartTime:            entry.startTime,
+		LastRequestTimestamp: entry.lastRequestTimestamp,
+		ResponseTimes:        entry.responseTimes,
+		NumReqsPerSec:        entry.numReqsPerSec,
+	}
+}
+
+// Entry returns the stats of requests with name and method, or nil if there's none.
+func (r *StatsReport) Entry(method, name string) *StatsEntryReport {
+	for _, e := range r.Entries {
+		if e.Method == method && e.Name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// Close stops the worker and the test master.
+func (m *TestMaster) Close() error {
+	if m.runner != nil {
+		m.runner.close()
+	}
+	if m.conn != nil {
+		m.conn.Close()
+	}
+	return m.listener.Close()
+}
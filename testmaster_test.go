@@ -0,0 +1,71 @@
+package boomer
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWorkerWithTestMaster(t *testing.T) {
+
+	master, err := NewTestMaster()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer master.Close()
+
+	task := &Task{
+		Name:   "foo",
+		Weight: 1,
+		Fn: func() {
+			Events.Publish("request_success", "http", "foo", int64(10), int64(100))
+			time.Sleep(10 * time.Millisecond)
+		},
+	}
+	if err := master.StartWorker(task); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := master.Expect("client_ready", time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	master.Hatch(5, 100)
+	if _, err := master.Expect("hatching", time.Second); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := master.Expect("hatch_complete", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := toInt64(msg.Data["count"]); count != 5 {
+		t.Error("5 users should be hatched, got", count)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	master.ReportStats()
+	stats, err := master.ExpectStats(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.UserCount != 5 {
+		t.Error("user count should be 5, got", stats.UserCount)
+	}
+	entry := stats.Entry("http", "foo")
+	if entry == nil || entry.NumRequests == 0 || entry.MaxResponseTime != 10 {
+		t.Error("requests of foo should be reported, got", entry)
+	}
+
+	master.Stop()
+	if _, err := master.Expect("client_stopped", time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := master.Expect("client_ready", time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	master.Quit()
+	if _, err := master.Expect("client_ready", time.Second); !errors.Is(err, ErrWorkerDisconnected) {
+		t.Error("worker should disconnect after quit, got", err)
+	}
+}
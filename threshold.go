@@ -0,0 +1,231 @@
+package boomer
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// threshold is a pass/fail criterion evaluated against the stats of the whole test,
+// like "p99(http:/login) < 300ms", "fail_ratio < 1%" or "rps > 500".
+type threshold struct {
+	expr     string
+	metric   string
+	method   string
+	name     string
+	tags     Tags
+	operator string
+	value    float64
+}
+
+type thresholdResult struct {
+	Expression string  `json:"expression"`
+	Actual     float64 `json:"actual"`
+	Passed     bool    `json:"passed"`
+	Error      string  `json:"error,omitempty"`
+}
+
+var thresholdPattern = regexp.MustCompile(`^\s*([a-z0-9_.]+)\s*(?:\((.*)\))?\s*(<=|>=|==|!=|<|>)\s*([0-9.]+)\s*(ms|s|%)?\s*$`)
+
+// thresholdMethodPattern matches targets like "GET:/login", but not names which are URLs like "http://host/login".
+var thresholdMethodPattern = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9_-]*):([^/].*|/|/[^/].*)?$`)
+
+// parseThreshold parses an expression like "metric(target) operator value[unit]".
+// The target is "method:name" or just "name", optionally followed by tags like "{status=200}",
+// and defaults to the total of all requests. Names may be URLs like "http://host/login".
+// Response times are compared in milliseconds, ratios can be written as percentages.
+func parseThreshold(expr string) (*threshold, error) {
+	matches := thresholdPattern.FindStringSubmatch(expr)
+	if matches == nil {
+		return nil, fmt.Errorf("invalid threshold: %q", expr)
+	}
+
+	t := &threshold{
+		expr:     expr,
+		metric:   matches[1],
+		operator: matches[3],
+	}
+	if !isValidThresholdMetric(t.metric) {
+		return nil, fmt.Errorf("unknown metric %q in threshold: %q", t.metric, expr)
+	}
+
+	target := strings.TrimSpace(matches[2])
+	if i := strings.Index(target, "{"); i >= 0 && strings.HasSuffix(target, "}") {
+		tags, err := parseTags(target[i+1 : len(target)-1])
+		if err != nil {
+			return nil, fmt.Errorf("%v in threshold: %q", err, expr)
+		}
+		t.tags = tags
+		target = strings.TrimSpace(target[:i])
+	}
+	if m := thresholdMethodPattern.FindStringSubmatch(target); m != nil {
+		t.method = m[1]
+		t.name = m[2]
+	} else {
+		t.name = target
+	}
+
+	value, err := strconv.ParseFloat(matches[4], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value in threshold: %q", expr)
+	}
+	switch matches[5] {
+	case "s":
+		value = value * 1000
+	case "%":
+		value = value / 100
+	}
+	t.value = value
+
+	return t, nil
+}
+
+// parseTags parses tags like "status=200,region=eu".
+func parseTags(s string) (Tags, error) {
+	tags := make(Tags)
+	for _, pair := range strings.Split(s, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid tags %q", s)
+		}
+		tags[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return tags, nil
+}
+
+func isValidThresholdMetric(metric string) bool {
+	switch metric {
+	case "avg", "min", "max", "median", "rps", "fail_ratio", "requests", "failures":
+		return true
+	}
+	_, err := parsePercentile(metric)
+	return err == nil
+}
+
+// parsePercentile converts metrics like p99 or p99.9 to 0.99 and 0.999.
+func parsePercentile(metric string) (float64, error) {
+	if !strings.HasPrefix(metric, "p") {
+		return 0, fmt.Errorf("%q is not a percentile", metric)
+	}
+	p, err := strconv.ParseFloat(metric[1:], 64)
+	if err != nil || p <= 0 || p > 100 {
+		return 0, fmt.Errorf("%q is not a percentile", metric)
+	}
+	return p / 100, nil
+}
+
+// evaluate checks the threshold against the stats of the whole test, duration is in seconds.
+func (t *threshold) evaluate(s *requestStats, duration float64) *thresholdResult {
+	result := &thresholdResult{
+		Expression: t.expr,
+	}
+
+	entry := t.find(s)
+	if entry == nil {
+		result.Error = "no requests found"
+		return result
+	}
+
+	actual := entryMetric(entry, t.metric, duration)
+	result.Actual = actual
+
+	switch t.operator {
+	case "<":
+		result.Passed = actual < t.value
+	case "<=":
+		result.Passed = actual <= t.value
+	case ">":
+		result.Passed = actual > t.value
+	case ">=":
+		result.Passed = actual >= t.value
+	case "==":
+		result.Passed = actual == t.value
+	case "!=":
+		result.Passed = actual != t.value
+	}
+	return result
+}
+
+// find returns the entry that the threshold is applied to, all the entries that
+// match the name, method and tags of the threshold are merged.
+func (t *threshold) find(s *requestStats) *statsEntry {
+	if t.name == "" && len(t.tags) == 0 {
+		return s.total
+	}
+
+	var found *statsEntry
+	for _, entry := range s.entries {
+		if (t.name != "" && entry.name != t.name) || (t.method != "" && entry.method != t.method) {
+			continue
+		}
+		if !entry.tags.matches(t.tags) {
+			continue
+		}
+		if found == nil {
+			found = entry.snapshot()
+		} else {
+			found.merge(entry)
+		}
+	}
+	return found
+}
+
+func entryMetric(entry *statsEntry, metric string, duration float64) float64 {
+	switch metric {
+	case "avg":
+		if entry.numRequests == 0 {
+			return 0
+		}
+		return float64(entry.totalResponseTime) / float64(entry.numRequests)
+	case "min":
+		return float64(entry.minResponseTime)
+	case "max":
+		return float64(entry.maxResponseTime)
+	case "median":
+		return float64(entry.getResponseTimePercentile(0.5))
+	case "rps":
+		if duration <= 0 {
+			duration = 1
+		}
+		return float64(entry.numRequests) / duration
+	case "fail_ratio":
+		if entry.numRequests+entry.numFailures == 0 {
+			return 0
+		}
+		return float64(entry.numFailures) / float64(entry.numRequests+entry.numFailures)
+	case "requests":
+		return float64(entry.numRequests)
+	case "failures":
+		return float64(entry.numFailures)
+	}
+	percent, _ := parsePercentile(metric)
+	return float64(entry.getResponseTimePercentile(percent))
+}
+
+// thresholdFlag collects thresholds from repeated --threshold flags.
+type thresholdFlag []*threshold
+
+func (f *thresholdFlag) String() string {
+	exprs := make([]string, 0, len(*f))
+	for _, t := range *f {
+		exprs = append(exprs, t.expr)
+	}
+	return strings.Join(exprs, ", ")
+}
+
+func (f *thresholdFlag) Set(value string) error {
+	t, err := parseThreshold(value)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, t)
+	return nil
+}
+
+var thresholds thresholdFlag
+
+func init() {
+	flag.Var(&thresholds, "threshold", "Pass/fail criterion checked against the final stats, like 'p99(http:/login) < 300ms', 'fail_ratio < 1%' or 'rps > 500'. Can be repeated.")
+}
@@ -0,0 +1,87 @@
+package boomer
+
+import "testing"
+
+func TestParseThreshold(t *testing.T) {
+
+	th, err := parseThreshold("p99(http:/login) < 300ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if th.metric != "p99" || th.method != "http" || th.name != "/login" || th.operator != "<" || th.value != 300 {
+		t.Error("p99(http:/login) < 300ms is parsed incorrectly", th)
+	}
+
+	th, err = parseThreshold("fail_ratio < 1%")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if th.metric != "fail_ratio" || th.name != "" || th.value != 0.01 {
+		t.Error("fail_ratio < 1% is parsed incorrectly", th)
+	}
+
+	th, err = parseThreshold("avg(foo) <= 1.5s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if th.method != "" || th.name != "foo" || th.value != 1500 {
+		t.Error("avg(foo) <= 1.5s is parsed incorrectly", th)
+	}
+
+	th, err = parseThreshold("p95(http://localhost/users?page=1) < 500ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if th.method != "" || th.name != "http://localhost/users?page=1" {
+		t.Error("URL names should not be split into methods", th)
+	}
+
+	th, err = parseThreshold("p95(GET:http://localhost/users) < 500ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if th.method != "GET" || th.name != "http://localhost/users" {
+		t.Error("GET:http://localhost/users is parsed incorrectly", th)
+	}
+
+	for _, expr := range []string{"rps", "rps >", "latency < 10", "p0 < 10", "p101 < 10"} {
+		if _, err := parseThreshold(expr); err == nil {
+			t.Errorf("%q should be invalid", expr)
+		}
+	}
+}
+
+func TestEvaluateThreshold(t *testing.T) {
+
+	s := newRequestStats()
+	for i := int64(1); i <= 100; i++ {
+		s.logRequest("http", "/login", i, 0, nil)
+	}
+	s.logError("http", "/login", "timeout", nil)
+	s.logRequest("http", "/logout", 10, 0, nil)
+
+	cases := map[string]bool{
+		"p99(http:/login) <= 100ms": true,
+		"p98(/login) < 99ms":        false,
+		"max(/login) == 100":        true,
+		"fail_ratio < 1%":           true,
+		"failures(/login) >= 1":     true,
+		"rps > 50":                  true,
+		"rps(/logout) > 50":         false,
+	}
+	for expr, passed := range cases {
+		th, err := parseThreshold(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := th.evaluate(s, 2)
+		if result.Passed != passed {
+			t.Errorf("%q should be evaluated to %t, actual value is %f", expr, passed, result.Actual)
+		}
+	}
+
+	th, _ := parseThreshold("p99(/unknown) < 100ms")
+	if result := th.evaluate(s, 2); result.Passed || result.Error == "" {
+		t.Error("threshold of an unknown entry should fail")
+	}
+}
@@ -0,0 +1,277 @@
+package boomer
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"time"
+)
+
+// statsEntry is stripped by every report, so a worker keeps no history of its own.
+// If --timeseries-window is set, per-second buckets of each entry are kept in a ring,
+// buckets falling out of the window can be spilled to a file as JSON lines.
+
+type timeBucket struct {
+	Second        int64           `json:"second"`
+	Requests      int64           `json:"requests"`
+	Failures      int64           `json:"failures"`
+	ResponseTimes map[int64]int64 `json:"response_times"`
+}
+
+func newTimeBucket(second int64) *timeBucket {
+	return &timeBucket{
+		Second:        second,
+		ResponseTimes: make(map[int64]int64),
+	}
+}
+
+func (b *timeBucket) percentile(percent float64) int64 {
+	return calculateResponseTimePercentile(b.ResponseTimes, b.Requests, percent)
+}
+
+func (b *timeBucket) copy() *timeBucket {
+	copied := newTimeBucket(b.Second)
+	copied.Requests = b.Requests
+	copied.Failures = b.Failures
+	for k, v := range b.ResponseTimes {
+		copied.ResponseTimes[k] = v
+	}
+	return copied
+}
+
+// timeSeries is a ring of per-second buckets, the bucket of a second is at second % window.
+type timeSeries struct {
+	method  string
+	name    string
+	tags    Tags
+	buckets []*timeBucket
+}
+
+func newTimeSeries(method, name string, tags Tags, window int) *timeSeries {
+	return &timeSeries{
+		method:  method,
+		name:    name,
+		tags:    tags.copy(),
+		buckets: make([]*timeBucket, window),
+	}
+}
+
+// bucket returns the bucket of second, the bucket previously in its place is evicted.
+func (ts *timeSeries) bucket(second int64) (bucket *timeBucket, evicted *timeBucket) {
+	i := int(second % int64(len(ts.buckets)))
+	bucket = ts.buckets[i]
+	if bucket == nil || bucket.Second != second {
+		evicted = bucket
+		bucket = newTimeBucket(second)
+		ts.buckets[i] = bucket
+	}
+	return bucket, evicted
+}
+
+// sorted returns the buckets in the window in chronological order.
+func (ts *timeSeries) sorted() []*timeBucket {
+	buckets := make([]*timeBucket, 0, len(ts.buckets))
+	for _, b := range ts.buckets {
+		if b != nil {
+			buckets = append(buckets, b.copy())
+		}
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].Second < buckets[j].Second
+	})
+	return buckets
+}
+
+type timeline struct {
+	window int
+	total  *timeSeries
+	series map[entryKey]*timeSeries
+	spill  *json.Encoder
+	file   io.Closer
+}
+
+func newTimeline(window int, spill *json.Encoder) *timeline {
+	return &timeline{
+		window: window,
+		total:  newTimeSeries("", "Total", nil, window),
+		series: make(map[entryKey]*timeSeries),
+		spill:  spill,
+	}
+}
+
+// reset returns an empty timeline spilling to the same file.
+func (t *timeline) reset() *timeline {
+	t.flush()
+	cleared := newTimeline(t.window, t.spill)
+	cleared.file = t.file
+	return cleared
+}
+
+// flush spills the buckets still in the window.
+func (t *timeline) flush() {
+	for _, ts := range append([]*timeSeries{t.total}, t.sortedSeries()...) {
+		for _, b := range ts.sorted() {
+			t.spillBucket(ts, b)
+		}
+		ts.buckets = make([]*timeBucket, t.window)
+	}
+}
+
+// close spills the buckets still in the window and closes the file.
+func (t *timeline) close() {
+	t.flush()
+	if t.file != nil {
+		if err := t.file.Close(); err != nil {
+			log.Println("Failed to close time series file:", err)
+		}
+		t.file = nil
+	}
+	t.spill = nil
+}
+
+func (t *timeline) sortedSeries() []*timeSeries {
+	series := make([]*timeSeries, 0, len(t.series))
+	for _, ts := range t.series {
+		series = append(series, ts)
+	}
+	sort.Slice(series, func(i, j int) bool {
+		if series[i].name == series[j].name {
+			return series[i].method < series[j].method
+		}
+		return series[i].name < series[j].name
+	})
+	return series
+}
+
+func (t *timeline) get(method, name string, tags Tags) *timeSeries {
+	key := newEntryKey(method, name, tags)
+	ts, ok := t.series[key]
+	if !ok {
+		ts = newTimeSeries(method, name, tags, t.window)
+		t.series[key] = ts
+	}
+	return ts
+}
+
+func (t *timeline) logRequest(method, name string, responseTime int64, tags Tags) {
+	now := time.Now().Unix()
+	rounded := roundResponseTime(responseTime)
+	for _, ts := range []*timeSeries{t.total, t.get(method, name, tags)} {
+		bucket := t.bucket(ts, now)
+		bucket.Requests++
+		bucket.ResponseTimes[rounded]++
+	}
+}
+
+func (t *timeline) logError(method, name string, tags Tags) {
+	now := time.Now().Unix()
+	for _, ts := range []*timeSeries{t.total, t.get(method, name, tags)} {
+		t.bucket(ts, now).Failures++
+	}
+}
+
+func (t *timeline) bucket(ts *timeSeries, second int64) *timeBucket {
+	bucket, evicted := ts.bucket(second)
+	if evicted != nil {
+		t.spillBucket(ts, evicted)
+	}
+	return bucket
+}
+
+func (t *timeline) spillBucket(ts *timeSeries, bucket *timeBucket) {
+	if t.spill == nil {
+		return
+	}
+	err := t.spill.Encode(&spilledBucket{
+		Method:     ts.method,
+		Name:       ts.name,
+		Tags:       ts.tags,
+		timeBucket: bucket,
+	})
+	if err != nil {
+		log.Println("Failed to spill time series:", err)
+		t.spill = nil
+	}
+}
+
+type spilledBucket struct {
+	Method string `json:"method"`
+	Name   string `json:"name"`
+	Tags   Tags   `json:"tags,omitempty"`
+	*timeBucket
+}
+
+// timelineSnapshot is a copy of the buckets in the window, it can be read outside the stats goroutine.
+type timelineSnapshot struct {
+	total   []*timeBucket
+	entries []*timeSeriesSnapshot
+}
+
+type timeSeriesSnapshot struct {
+	method  string
+	name    string
+	tags    Tags
+	buckets []*timeBucket
+}
+
+func (t *timeline) snapshot() *timelineSnapshot {
+	s := &timelineSnapshot{
+		total:   t.total.sorted(),
+		entries: make([]*timeSeriesSnapshot, 0, len(t.series)),
+	}
+	for _, ts := range t.series {
+		s.entries = append(s.entries, &timeSeriesSnapshot{
+			method:  ts.method,
+			name:    ts.name,
+			tags:    ts.tags,
+			buckets: ts.sorted(),
+		})
+	}
+	return s
+}
+
+// worstSeconds returns the n seconds with the highest 99th percentile response time.
+func worstSeconds(buckets []*timeBucket, n int) []*timeBucket {
+	worst := make([]*timeBucket, len(buckets))
+	copy(worst, buckets)
+	sort.SliceStable(worst, func(i, j int) bool {
+		pi, pj := worst[i].percentile(0.99), worst[j].percentile(0.99)
+		if pi == pj {
+			return worst[i].Failures > worst[j].Failures
+		}
+		return pi > pj
+	})
+	if len(worst) > n {
+		worst = worst[:n]
+	}
+	return worst
+}
+
+var timeseriesWindow *int
+var timeseriesFile *string
+
+// newTimelineFromFlags returns nil if --timeseries-window is not set, it must be called after the flags are parsed.
+func newTimelineFromFlags() *timeline {
+	if *timeseriesWindow <= 0 {
+		return nil
+	}
+	t := newTimeline(*timeseriesWindow, nil)
+	if *timeseriesFile != "" {
+		f, err := os.OpenFile(*timeseriesFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			log.Println("Failed to open time series file:", err)
+		} else {
+			t.spill = json.NewEncoder(f)
+			t.file = f
+		}
+	}
+	return t
+}
+
+func init() {
+	timeseriesWindow = flag.Int("timeseries-window", 0, "Keep per-second stats of the last N seconds in memory for the final report. 0 means disabled.")
+	timeseriesFile = flag.String("timeseries-file", "", "Append per-second stats falling out of --timeseries-window to this file as JSON lines.")
+}
@@ -0,0 +1,99 @@
+package boomer
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTimeSeriesRing(t *testing.T) {
+
+	ts := newTimeSeries("GET", "foo", nil, 3)
+	for second := int64(100); second < 105; second++ {
+		bucket, evicted := ts.bucket(second)
+		bucket.Requests = second
+		if second < 103 && evicted != nil {
+			t.Error("nothing should be evicted before the ring is full")
+		}
+		if second >= 103 && (evicted == nil || evicted.Second != second-3) {
+			t.Errorf("bucket of second %d should be evicted by second %d", second-3, second)
+		}
+	}
+
+	buckets := ts.sorted()
+	if len(buckets) != 3 || buckets[0].Second != 102 || buckets[2].Second != 104 {
+		t.Error("buckets should be the last 3 seconds in chronological order")
+	}
+}
+
+func TestTimelineSpill(t *testing.T) {
+
+	buf := new(bytes.Buffer)
+	tl := newTimeline(2, json.NewEncoder(buf))
+	ts := tl.get("GET", "foo", Tags{"status": "200"})
+	for second := int64(100); second < 104; second++ {
+		tl.bucket(ts, second).Requests++
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatal("2 buckets should be spilled, got", lines)
+	}
+	spilled := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(lines[0]), &spilled); err != nil {
+		t.Fatal(err)
+	}
+	if spilled["name"] != "foo" || spilled["second"] != float64(100) || spilled["requests"] != float64(1) {
+		t.Error("spilled bucket is incorrect", lines[0])
+	}
+}
+
+type closingBuffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *closingBuffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestTimelineClose(t *testing.T) {
+
+	buf := new(closingBuffer)
+	tl := newTimeline(10, json.NewEncoder(buf))
+	tl.file = buf
+	tl.bucket(tl.get("GET", "foo", nil), 100).Requests++
+	tl.bucket(tl.total, 100).Requests++
+
+	tl = tl.reset()
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 2 {
+		t.Error("buckets in the window should be spilled when stats are cleared, got", lines)
+	}
+	if tl.file != buf || len(tl.series) != 0 {
+		t.Error("cleared timeline should be empty and spill to the same file")
+	}
+
+	tl.bucket(tl.get("GET", "bar", nil), 101).Requests++
+	tl.close()
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 3 || !buf.closed {
+		t.Error("buckets in the window should be spilled before the file is closed, got", lines)
+	}
+}
+
+func TestWorstSeconds(t *testing.T) {
+
+	buckets := make([]*timeBucket, 0)
+	for second := int64(0); second < 20; second++ {
+		b := newTimeBucket(second)
+		b.Requests = 1
+		b.ResponseTimes[second*10] = 1
+		buckets = append(buckets, b)
+	}
+
+	worst := worstSeconds(buckets, 10)
+	if len(worst) != 10 || worst[0].Second != 19 || worst[9].Second != 10 {
+		t.Error("worst seconds should be sorted by 99th percentile response time")
+	}
+}
@@ -0,0 +1,96 @@
+package boomer
+
+import (
+	"sync"
+	"time"
+)
+
+// User is a user with its own state, like a connection, created by NewUser of a task for each user hatched.
+// Like on_start and on_stop of locust, OnStart is called once before Run is called repeatedly,
+// and OnStop is called once after the user is stopped, by the master or on shutdown.
+type User interface {
+	OnStart()
+	Run()
+	OnStop()
+}
+
+// funcUser is a user of a task without NewUser, it runs Fn of the task.
+type funcUser func()
+
+func (f funcUser) OnStart() {}
+
+func (f funcUser) Run() {
+	f()
+}
+
+func (f funcUser) OnStop() {}
+
+func (task *Task) newUser() User {
+	if task.NewUser != nil {
+		return task.NewUser()
+	}
+	return funcUser(task.Fn)
+}
+
+// runOnce runs the task once as a user, for --run-tasks.
+func (task *Task) runOnce() {
+	user := task.newUser()
+	user.OnStart()
+	defer user.OnStop()
+	user.Run()
+}
+
+// runUser runs the task repeatedly as a user until quit, OnStop is called before the user goroutine exits.
+func (r *runner) runUser(task *Task, quit chan bool) {
+	var user User
+	r.safeRun(func() {
+		user = task.newUser()
+	})
+	if user == nil {
+		return
+	}
+	r.safeRun(user.OnStart)
+	defer r.safeRun(user.OnStop)
+
+	for {
+		select {
+		case <-quit:
+			return
+		default:
+			// wait until next second if max RPS is reached
+			if r.rateLimiter.acquire(quit) {
+				r.safeRun(user.Run)
+			}
+		}
+	}
+}
+
+var sleepLock sync.Mutex
+
+// sleepStop is the stop channel of the users hatched last. It stays closed after they are stopped,
+// until the next users are hatched, so users sleeping after they are stopped don't wait either.
+var sleepStop = make(chan bool)
+
+// Sleep pauses the user for d, like think time, or waiting for the time of the next request.
+// It returns false if users are stopped in the meantime, then Run should return without sending more requests.
+func Sleep(d time.Duration) bool {
+	sleepLock.Lock()
+	stop := sleepStop
+	sleepLock.Unlock()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-stop:
+		return false
+	}
+}
+
+// setSleepStop makes Sleep of the next users return when stop is closed.
+func setSleepStop(stop chan bool) {
+	sleepLock.Lock()
+	defer sleepLock.Unlock()
+	sleepStop = stop
+}
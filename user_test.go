@@ -0,0 +1,156 @@
+package boomer
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testUser struct {
+	started, runs, stopped *int32
+	// runsOfUser is only touched by the goroutine of the user
+	runsOfUser int
+}
+
+func (u *testUser) OnStart() {
+	atomic.AddInt32(u.started, 1)
+}
+
+func (u *testUser) Run() {
+	u.runsOfUser++
+	atomic.AddInt32(u.runs, 1)
+	time.Sleep(5 * time.Millisecond)
+}
+
+func (u *testUser) OnStop() {
+	if u.runsOfUser > 0 {
+		atomic.AddInt32(u.stopped, 1)
+	}
+}
+
+// The events are published along the lifecycle of users.
+func TestUserLifecycle(t *testing.T) {
+	master, err := NewTestMaster()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer master.Close()
+
+	var hatched, stoppedEvents int32
+	onHatch := func(spawnCount, hatchRate int) {
+		if spawnCount != 3 || hatchRate != 100 {
+			t.Error("unexpected hatch event", spawnCount, hatchRate)
+		}
+		atomic.AddInt32(&hatched, 1)
+	}
+	onStop := func() {
+		atomic.AddInt32(&stoppedEvents, 1)
+	}
+	Events.Subscribe("boomer:hatch", onHatch)
+	Events.Subscribe("boomer:stop", onStop)
+	defer Events.Unsubscribe("boomer:hatch", onHatch)
+	defer Events.Unsubscribe("boomer:stop", onStop)
+
+	var started, runs, stopped int32
+	task := &Task{
+		Name:   "user",
+		Weight: 1,
+		Fn: func() {
+			t.Error("Fn should be ignored with NewUser")
+		},
+		NewUser: func() User {
+			return &testUser{started: &started, runs: &runs, stopped: &stopped}
+		},
+	}
+	if err := master.StartWorker(task); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := master.Expect("client_ready", time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	master.Hatch(3, 100)
+	if _, err := master.Expect("hatch_complete", time.Second); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&started); n != 3 {
+		t.Error("OnStart should be called once by each user, got", n)
+	}
+	if atomic.LoadInt32(&runs) <= 3 {
+		t.Error("Run should be called repeatedly")
+	}
+
+	master.Stop()
+	if _, err := master.Expect("client_stopped", time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&hatched) != 1 || atomic.LoadInt32(&stoppedEvents) != 1 {
+		t.Error("boomer:hatch and boomer:stop should be published once, got", hatched, stoppedEvents)
+	}
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&stopped) != 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("OnStop should be called once by each user after Run, got", atomic.LoadInt32(&stopped))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunOnce(t *testing.T) {
+	var started, runs, stopped int32
+	task := &Task{
+		NewUser: func() User {
+			return &testUser{started: &started, runs: &runs, stopped: &stopped}
+		},
+	}
+	task.runOnce()
+	if started != 1 || runs != 1 || stopped != 1 {
+		t.Error("user should be started, run and stopped once, got", started, runs, stopped)
+	}
+
+	task = &Task{
+		Fn: func() {
+			atomic.AddInt32(&runs, 1)
+		},
+	}
+	task.runOnce()
+	if runs != 2 {
+		t.Error("Fn should be run without NewUser")
+	}
+}
+
+func TestSleep(t *testing.T) {
+	defer setSleepStop(make(chan bool))
+
+	stop := make(chan bool)
+	setSleepStop(stop)
+	if !Sleep(time.Millisecond) {
+		t.Error("Sleep should return true if users aren't stopped")
+	}
+
+	woken := make(chan bool, 1)
+	go func() {
+		woken <- Sleep(time.Minute)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(stop)
+	select {
+	case completed := <-woken:
+		if completed {
+			t.Error("Sleep should return false if users are stopped")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("sleeping users should be woken up when users are stopped")
+	}
+
+	start := time.Now()
+	if Sleep(time.Minute) || time.Since(start) > time.Second {
+		t.Error("users sleeping after they are stopped should not wait")
+	}
+
+	setSleepStop(make(chan bool))
+	if !Sleep(time.Millisecond) {
+		t.Error("users hatched later should sleep again")
+	}
+}
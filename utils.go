@@ -7,6 +7,7 @@ import (
 	"math"
 	"math/rand"
 	"os"
+	"strconv"
 	"time"
 )
 
@@ -33,6 +34,12 @@ func MD5(slice ...string) string {
 	return fmt.Sprintf("%x", h.Sum(nil))
 }
 
+type int64Slice []int64
+
+func (p int64Slice) Len() int           { return len(p) }
+func (p int64Slice) Less(i, j int) bool { return p[i] < p[j] }
+func (p int64Slice) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
 // generate a random nodeID like locust does, using the same algorithm.
 func getNodeID() (nodeID string) {
 	hostname, _ := os.Hostname()
@@ -42,6 +49,11 @@ func getNodeID() (nodeID string) {
 	return
 }
 
+// formatFloat formats f without trailing zeros, like 0.5 or 99.9.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
 // Now gets current timestamp in milliseconds.
 func Now() int64 {
 	return time.Now().UnixNano() / int64(time.Millisecond)
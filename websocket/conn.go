@@ -0,0 +1,292 @@
+// Package websocket reports WebSocket connections and messages to boomer.
+//
+//	task := &boomer.Task{
+//		Name:   "chat",
+//		Weight: 1,
+//		NewUser: func() boomer.User {
+//			conn := &websocket.Conn{
+//				URL:       "ws://localhost/chat",
+//				Correlate: websocket.JSONField("id"),
+//			}
+//			return websocket.NewUser(conn, func(c *websocket.Conn) {
+//				c.Request("send", []byte(`{"id": "1", "text": "hello"}`))
+//			})
+//		},
+//	}
+//
+// Connect time is reported as "ws_connect", the latency of a request until its response as "ws_request",
+// and connections dropped by the server or the network as "ws_dropped", all named by the name of the
+// connection. Messages sent and received are counted as websocket_messages_sent and websocket_messages_received.
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myzhan/boomer"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	typeConnect = "ws_connect"
+	typeRequest = "ws_request"
+	typeDropped = "ws_dropped"
+)
+
+var messagesSent = boomer.NewCounter("websocket_messages_sent")
+var messagesReceived = boomer.NewCounter("websocket_messages_received")
+
+var (
+	// ErrNotConnected is returned when sending without a connection.
+	ErrNotConnected = errors.New("not connected")
+	// ErrDropped is returned by Request if the connection is dropped before the response.
+	ErrDropped = errors.New("connection dropped")
+	// ErrTimeout is returned by Request if there is no response within the timeout.
+	ErrTimeout = errors.New("timeout waiting for the response")
+)
+
+// Conn is a WebSocket connection of a user, it can be connected again after it's closed or dropped.
+type Conn struct {
+	// URL is like "ws://localhost/chat".
+	URL string
+	// Origin is sent in the handshake, the URL with http or https by default.
+	Origin string
+	// Header is sent in the handshake.
+	Header http.Header
+	// Name is the name of the connection in reports, it's the URL by default.
+	Name string
+	// Timeout of connecting and requests, 10 seconds by default.
+	Timeout time.Duration
+	// Binary sends messages as binary frames instead of text frames.
+	Binary bool
+	// Correlate returns the ID of a message, responses are matched to requests by their IDs.
+	// It's required by Request, and returns "" if the message has no ID.
+	Correlate func(message []byte) string
+	// OnMessage receives the messages which aren't responses, like pushes from the server.
+	// It's called in the receiving goroutine, messages are dropped if it's nil.
+	OnMessage func(message []byte)
+
+	lock      sync.Mutex
+	ws        *websocket.Conn
+	connected time.Time
+	closing   bool
+	// dropped is closed when the receiving goroutine exits
+	dropped chan struct{}
+	pending map[string]chan []byte
+}
+
+func (c *Conn) name() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.URL
+}
+
+func (c *Conn) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return 10 * time.Second
+}
+
+func elapsedMillis(start time.Time) int64 {
+	return int64(time.Since(start) / time.Millisecond)
+}
+
+// Connected tells if the connection is open.
+func (c *Conn) Connected() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.ws != nil
+}
+
+// Connect opens the connection, the time of the handshake is reported.
+func (c *Conn) Connect() error {
+	if c.Connected() {
+		return nil
+	}
+
+	origin := c.Origin
+	if origin == "" && strings.HasPrefix(c.URL, "ws") {
+		origin = "http" + c.URL[len("ws"):]
+	}
+	config, err := websocket.NewConfig(c.URL, origin)
+	if err != nil {
+		return err
+	}
+	config.Header = c.Header
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout())
+	defer cancel()
+	start := time.Now()
+	ws, err := config.DialContext(ctx)
+	if err != nil {
+		boomer.RecordFailure(typeConnect, c.name(), elapsedMillis(start), err.Error(), nil)
+		return err
+	}
+	boomer.RecordSuccess(typeConnect, c.name(), elapsedMillis(start), 0, nil)
+
+	if c.Binary {
+		ws.PayloadType = websocket.BinaryFrame
+	}
+	dropped := make(chan struct{})
+	c.lock.Lock()
+	c.ws = ws
+	c.connected = time.Now()
+	c.closing = false
+	c.dropped = dropped
+	c.pending = make(map[string]chan []byte)
+	c.lock.Unlock()
+
+	go c.receive(ws, dropped)
+	return nil
+}
+
+func (c *Conn) receive(ws *websocket.Conn, dropped chan struct{}) {
+	defer close(dropped)
+	for {
+		var message []byte
+		if err := websocket.Message.Receive(ws, &message); err != nil {
+			c.lock.Lock()
+			closing := c.closing
+			connected := c.connected
+			c.ws = nil
+			c.lock.Unlock()
+			ws.Close()
+			if !closing {
+				boomer.RecordFailure(typeDropped, c.name(), elapsedMillis(connected), err.Error(), nil)
+			}
+			return
+		}
+		messagesReceived.Inc()
+
+		if c.Correlate != nil {
+			if id := c.Correlate(message); id != "" {
+				c.lock.Lock()
+				response, ok := c.pending[id]
+				delete(c.pending, id)
+				c.lock.Unlock()
+				if ok {
+					response <- message
+					continue
+				}
+			}
+		}
+		if c.OnMessage != nil {
+			c.OnMessage(message)
+		}
+	}
+}
+
+// Send sends a message without waiting for a response.
+func (c *Conn) Send(message []byte) error {
+	c.lock.Lock()
+	ws := c.ws
+	c.lock.Unlock()
+	return c.send(ws, message)
+}
+
+func (c *Conn) send(ws *websocket.Conn, message []byte) error {
+	if ws == nil {
+		return ErrNotConnected
+	}
+	ws.SetWriteDeadline(time.Now().Add(c.timeout()))
+	if _, err := ws.Write(message); err != nil {
+		return err
+	}
+	messagesSent.Inc()
+	return nil
+}
+
+// Request sends a message and waits for the response with the same ID returned by Correlate,
+// the latency is reported with the name. It fails if the connection is dropped before the response.
+func (c *Conn) Request(name string, message []byte) ([]byte, error) {
+	if c.Correlate == nil {
+		return nil, errors.New("a correlate function is required by Request")
+	}
+	id := c.Correlate(message)
+	if id == "" {
+		return nil, errors.New("no ID in the request")
+	}
+
+	response := make(chan []byte, 1)
+	c.lock.Lock()
+	ws, dropped := c.ws, c.dropped
+	if ws != nil {
+		c.pending[id] = response
+	}
+	c.lock.Unlock()
+
+	start := time.Now()
+	if err := c.send(ws, message); err != nil {
+		c.removePending(id)
+		boomer.RecordFailure(typeRequest, name, elapsedMillis(start), err.Error(), nil)
+		return nil, err
+	}
+
+	timer := time.NewTimer(c.timeout())
+	defer timer.Stop()
+	select {
+	case resp := <-response:
+		boomer.RecordSuccess(typeRequest, name, elapsedMillis(start), int64(len(resp)), nil)
+		return resp, nil
+	case <-dropped:
+		// the response may arrive right before the connection is dropped
+		select {
+		case resp := <-response:
+			boomer.RecordSuccess(typeRequest, name, elapsedMillis(start), int64(len(resp)), nil)
+			return resp, nil
+		default:
+		}
+		boomer.RecordFailure(typeRequest, name, elapsedMillis(start), ErrDropped.Error(), nil)
+		return nil, ErrDropped
+	case <-timer.C:
+		c.removePending(id)
+		boomer.RecordFailure(typeRequest, name, elapsedMillis(start), ErrTimeout.Error(), nil)
+		return nil, ErrTimeout
+	}
+}
+
+func (c *Conn) removePending(id string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.pending, id)
+}
+
+// Close closes the connection, it's not reported as dropped.
+func (c *Conn) Close() error {
+	c.lock.Lock()
+	ws, dropped := c.ws, c.dropped
+	c.closing = true
+	c.lock.Unlock()
+	if ws == nil {
+		return nil
+	}
+	err := ws.Close()
+	<-dropped
+	return err
+}
+
+// JSONField returns a Correlate function, which takes the field of JSON messages as the ID, like "id".
+func JSONField(field string) func(message []byte) string {
+	return func(message []byte) string {
+		var fields map[string]interface{}
+		if err := json.Unmarshal(message, &fields); err != nil {
+			return ""
+		}
+		switch id := fields[field].(type) {
+		case nil:
+			return ""
+		case string:
+			return id
+		default:
+			return fmt.Sprint(id)
+		}
+	}
+}
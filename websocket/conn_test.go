@@ -0,0 +1,186 @@
+package websocket
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/myzhan/boomer/boomertest"
+	"golang.org/x/net/websocket"
+)
+
+// newTestServer pushes "welcome" to new connections, echoes messages, ignores "ignore"
+// and drops the connection on "bye".
+func newTestServer() *httptest.Server {
+	return httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		websocket.Message.Send(ws, "welcome")
+		for {
+			var message string
+			if err := websocket.Message.Receive(ws, &message); err != nil {
+				return
+			}
+			switch {
+			case strings.Contains(message, "bye"):
+				ws.Close()
+				return
+			case strings.Contains(message, "ignore"):
+			default:
+				websocket.Message.Send(ws, message)
+			}
+		}
+	}))
+}
+
+func newTestConn(server *httptest.Server) (*Conn, chan []byte) {
+	pushed := make(chan []byte, 10)
+	return &Conn{
+		URL:       "ws" + strings.TrimPrefix(server.URL, "http"),
+		Name:      "chat",
+		Timeout:   200 * time.Millisecond,
+		Correlate: JSONField("id"),
+		OnMessage: func(message []byte) {
+			pushed <- message
+		},
+	}, pushed
+}
+
+func TestRequest(t *testing.T) {
+	recorder := boomertest.Record(t)
+	server := newTestServer()
+	defer server.Close()
+	conn, pushed := newTestConn(server)
+
+	if err := conn.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case message := <-pushed:
+		if string(message) != "welcome" {
+			t.Error("unexpected message", string(message))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("messages without IDs should be passed to OnMessage")
+	}
+
+	resp, err := conn.Request("echo", []byte(`{"id": 1, "text": "hello"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp) != `{"id": 1, "text": "hello"}` {
+		t.Error("unexpected response", string(resp))
+	}
+	if _, err := conn.Request("echo", []byte(`{"id": 2, "text": "ignore"}`)); err != ErrTimeout {
+		t.Error("request should time out, got", err)
+	}
+	if _, err := conn.Request("echo", []byte(`{"text": "hello"}`)); err == nil {
+		t.Error("requests without IDs should fail")
+	}
+	conn.Close()
+	if conn.Connected() {
+		t.Error("connection should be closed")
+	}
+	if _, err := conn.Request("echo", []byte(`{"id": 3}`)); err != ErrNotConnected {
+		t.Error("requests without connection should fail, got", err)
+	}
+
+	got := recorder.Requests()
+	want := []string{
+		"ws_connect chat",
+		"ws_request echo",
+		"ws_request echo: " + ErrTimeout.Error(),
+		"ws_request echo: " + ErrNotConnected.Error(),
+	}
+	if len(got) != len(want) {
+		t.Fatal("unexpected records, closing shouldn't be reported as dropped, got", got)
+	}
+	for i := range want {
+		if got[i].String() != want[i] {
+			t.Errorf("record %d should be %v, got %v", i, want[i], got[i])
+		}
+	}
+	if got[1].Length != int64(len(resp)) {
+		t.Error("the length of the response should be reported, got", got[1].Length)
+	}
+}
+
+func TestDropped(t *testing.T) {
+	recorder := boomertest.Record(t)
+	server := newTestServer()
+	defer server.Close()
+	conn, _ := newTestConn(server)
+
+	if err := conn.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Request("bye", []byte(`{"id": "bye"}`)); err != ErrDropped {
+		t.Error("request should fail when the connection is dropped, got", err)
+	}
+	got := recorder.Requests()
+	if len(got) != 3 || got[1].Type != "ws_dropped" || got[1].Name != "chat" || got[2].Type != "ws_request" {
+		t.Error("the connection should be reported as dropped, got", got)
+	}
+	if conn.Connected() {
+		t.Error("dropped connection should be disconnected")
+	}
+
+	// reconnect
+	if err := conn.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Request("echo", []byte(`{"id": "again"}`)); err != nil {
+		t.Error(err)
+	}
+	conn.Close()
+}
+
+func TestConnectFailure(t *testing.T) {
+	recorder := boomertest.Record(t)
+	server := newTestServer()
+	conn, _ := newTestConn(server)
+	server.Close()
+
+	if err := conn.Connect(); err == nil {
+		t.Fatal("connecting to a closed server should fail")
+	}
+	if got := recorder.Requests(); len(got) != 1 || got[0].Success || got[0].Type != "ws_connect" {
+		t.Error("the failure should be reported, got", got)
+	}
+}
+
+func TestUser(t *testing.T) {
+	boomertest.Record(t)
+	server := newTestServer()
+	defer server.Close()
+	conn, _ := newTestConn(server)
+
+	var runs int
+	user := NewUser(conn, func(c *Conn) {
+		runs++
+		c.Request("echo", []byte(`{"id": "bye"}`))
+	})
+	user.OnStart()
+	user.Run()
+	if conn.Connected() {
+		t.Fatal("connection should be dropped")
+	}
+	user.Run()
+	user.OnStop()
+	if runs != 2 || conn.Connected() {
+		t.Error("user should reconnect after the connection is dropped, and close it on stop")
+	}
+}
+
+func TestJSONField(t *testing.T) {
+	correlate := JSONField("id")
+	for message, id := range map[string]string{
+		`{"id": "a"}`: "a",
+		`{"id": 12}`:  "12",
+		`{"no": 1}`:   "",
+		`not json`:    "",
+	} {
+		if got := correlate([]byte(message)); got != id {
+			t.Errorf("ID of %s should be %q, got %q", message, id, got)
+		}
+	}
+}
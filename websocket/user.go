@@ -0,0 +1,42 @@
+package websocket
+
+import (
+	"time"
+
+	"github.com/myzhan/boomer"
+)
+
+// reconnectWait keeps users from reconnecting in a busy loop when the server is down.
+var reconnectWait = time.Second
+
+type user struct {
+	conn *Conn
+	run  func(conn *Conn)
+}
+
+// NewUser returns a user with its own connection, which connects in OnStart, reconnects before run
+// if the connection is dropped, and closes in OnStop. run is called repeatedly with the connection.
+func NewUser(conn *Conn, run func(conn *Conn)) boomer.User {
+	return &user{
+		conn: conn,
+		run:  run,
+	}
+}
+
+func (u *user) OnStart() {
+	u.conn.Connect()
+}
+
+func (u *user) Run() {
+	if !u.conn.Connected() {
+		if err := u.conn.Connect(); err != nil {
+			boomer.Sleep(reconnectWait)
+			return
+		}
+	}
+	u.run(u.conn)
+}
+
+func (u *user) OnStop() {
+	u.conn.Close()
+}